@@ -1,11 +1,21 @@
 package app
 
 import (
+	"Caw/UserService/events"
 	"Caw/UserService/infrastructure"
 	"Caw/UserService/middleware"
 	"Caw/UserService/models"
+	"Caw/UserService/search"
+	"Caw/UserService/suggestions"
+	"Caw/UserService/trends"
 	"Caw/UserService/utils"
+	"crypto/sha1"
+	"encoding/hex"
+	"expvar"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 
@@ -18,25 +28,74 @@ var (
 	supportedContentType = appJSON
 )
 
+// Maximum sizes of request bodies in bytes
+const (
+	maxAuthBodySize          = 1 << 10
+	maxUserBodySize          = 4 << 10
+	maxFollowBodySize        = 1 << 10
+	maxCawBodySize           = 16 << 10
+	maxNotificationsBodySize = 64 << 10
+)
+
 type App struct {
 	router                *mux.Router
 	dataStoreFactory      infrastructure.DataStoreFactory
 	logger                *logrus.Logger
+	trends                *trends.Aggregator
+	events                *events.Broker
+	search                *search.Index
+	names                 *search.NameIndex
+	suggestions           *suggestions.Recommender
 	TokenExpiresInMinutes int
+	// tenant is empty in single tenant mode
+	tenant string
+
+	socketMessagesPerSecond int
+	cawEditWindow           time.Duration
+	cawMaxEdits             int
+	limits                  models.Limits
+	// operators are IDs of users allowed to read metrics
+	operators []string
 }
 
 func New(appConfig *utils.AppConfig, dataStoreFactory infrastructure.DataStoreFactory, logger *logrus.Logger) *App {
-	app := App{dataStoreFactory: dataStoreFactory, logger: logger, TokenExpiresInMinutes: appConfig.TokenExpiresInMinutes}
+	app := App{
+		dataStoreFactory:      dataStoreFactory,
+		logger:                logger,
+		trends:                trends.NewAggregator(appConfig.TrendWindows, logger),
+		events:                events.NewBroker(events.Policy(appConfig.StreamBackpressure), logger),
+		search:                search.NewIndex(),
+		names:                 search.NewNameIndex(),
+		TokenExpiresInMinutes: appConfig.TokenExpiresInMinutes,
+		tenant:                appConfig.Tenant,
+
+		socketMessagesPerSecond: appConfig.SocketMessagesPerSecond,
+		cawEditWindow:           appConfig.CawEditWindow,
+		cawMaxEdits:             appConfig.CawMaxEdits,
+		limits:                  validationLimits(appConfig),
+		operators:               appConfig.Operators,
+	}
+	app.suggestions = suggestions.NewRecommender(app.computeSuggestions, appConfig.SuggestionsRefresh, logger)
+	go app.trends.Run()
+	go app.suggestions.Run()
 	app.createRoute()
 	return &app
 }
 
+// Close stops background workers started by App and closes open event streams
+func (app *App) Close() {
+	app.trends.Stop()
+	app.suggestions.Stop()
+	app.events.Close()
+}
+
 func (app App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	app.router.ServeHTTP(w, r)
 }
 
 func (app *App) UpdateConfig(appConfig *utils.AppConfig) {
 	app.TokenExpiresInMinutes = appConfig.TokenExpiresInMinutes
+	app.limits = validationLimits(appConfig)
 	logrus.Infof("TokenExpiresInMinutes updated to %v", app.TokenExpiresInMinutes)
 }
 
@@ -48,15 +107,67 @@ func (app App) newCawDataStore() infrastructure.CawDataStore {
 	return app.dataStoreFactory.CreateCawDataStore()
 }
 
-func writeErrMsg(statusCode int, msg string, w http.ResponseWriter) {
-	errMsg := models.Error{Message: msg}
-	js, err := errMsg.ToJSON()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+func (app App) newNotificationDataStore() infrastructure.NotificationDataStore {
+	return app.dataStoreFactory.CreateNotificationDataStore()
+}
+
+// validationLimits returns payload limits configured in appConfig
+func validationLimits(appConfig *utils.AppConfig) models.Limits {
+	return models.Limits{
+		NameMinLength:     appConfig.UserNameMinLength,
+		NameMaxLength:     appConfig.UserNameMaxLength,
+		PasswordMinLength: appConfig.PasswordMinLength,
+		CawMaxLength:      appConfig.CawMaxLength,
+	}.WithDefaults()
+}
+
+// userClaims returns claims of authenticated user put into request context by middleware.MustAuth
+func userClaims(r *http.Request) (*utils.UserClaims, bool) {
+	claims, ok := r.Context().Value("userClaims").(*utils.UserClaims)
+	return claims, ok
+}
+
+// isRequestor returns true if authenticated user has userID. Otherwise it writes error status and returns false.
+func (app App) isRequestor(w http.ResponseWriter, r *http.Request, userID string) bool {
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Error("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return false
+	}
+
+	if claims.UserId != userID {
+		app.logger.Errorf("User %v cannot access resources of user %v", claims.UserId, userID)
+		writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "Resources of other users cannot be accessed")
+		return false
 	}
-	w.WriteHeader(statusCode)
-	w.Write(js)
+	return true
+}
+
+// queryPage returns page number from page query parameter or 0 if it is missing or invalid
+func queryPage(r *http.Request) int {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		return 0
+	}
+	return page
+}
+
+// etag returns strong entity tag of response body
+func etag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// isNotModified returns true if If-None-Match header of request matches entity tag
+func isNotModified(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
 }
 
 // createRoute creates route
@@ -65,6 +176,13 @@ func (app *App) createRoute() {
 	router := mux.NewRouter()
 	app.addUserEndpoint(router)
 	app.addCawEndpoint(router)
+	app.addHashtagEndpoint(router)
+	app.addTrendEndpoint(router)
+	app.addStreamEndpoint(router)
+	app.addSocketEndpoint(router)
+	app.addMetricsEndpoint(router)
+	app.addOpenAPIEndpoint(router)
+	app.addSearchEndpoint(router)
 	app.addAuthEndpoint(router)
 	app.router = router
 }
@@ -75,11 +193,18 @@ func (app App) addUserEndpoint(router *mux.Router) {
 	router.HandleFunc(
 		uriBuilder.User().Done(),
 		middleware.Chain(app.postUserHandler,
+			middleware.MaxBodySize(maxUserBodySize),
 			middleware.CQRS(),
 			middleware.Consume(supportedContentType),
 			middleware.Produce(supportedAccept),
 			middleware.Logging(app.logger))).
 		Methods("POST")
+	// has to be registered before {userID} routes
+	router.HandleFunc(
+		uriBuilder.User().Autocomplete().Done(),
+		middleware.Chain(app.commonMiddleware(app.getUsersAutocompleteHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Done(),
 		middleware.Chain(app.commonMiddleware(app.getUserHandler),
@@ -93,13 +218,29 @@ func (app App) addUserEndpoint(router *mux.Router) {
 		uriBuilder.User().WithUser("{userID}").Followers().Done(),
 		app.commonMiddleware(app.getUserFollowersHandler)).
 		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Followers().WithFollowers("{otherID}").Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserFollowerHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Mutuals().Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserMutualsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Relationship().WithRelationship("{otherID}").Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserRelationshipHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Following().Done(),
 		app.commonMiddleware(app.getUserFollowingHandler)).
 		Methods("GET")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Following().Done(),
-		app.commonMiddleware(app.postUserFollowingHandler)).
+		middleware.Chain(app.commonMiddleware(app.postUserFollowingHandler),
+			middleware.MaxBodySize(maxFollowBodySize))).
 		Methods("POST")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Following().WithFollowing("{fid}").Done(),
@@ -107,17 +248,59 @@ func (app App) addUserEndpoint(router *mux.Router) {
 		Methods("DELETE")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Caws().Done(),
-		app.commonMiddleware(app.postCawHandler)).
+		middleware.Chain(app.commonMiddleware(app.postCawHandler),
+			middleware.MaxBodySize(maxCawBodySize))).
 		Methods("POST")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Caws().WithCaw("{cawId}").Done(),
 		app.commonMiddleware(app.deleteCawHandler)).
 		Methods("DELETE")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Caws().WithCaw("{cawId}").Done(),
+		middleware.Chain(app.commonMiddleware(app.patchCawHandler),
+			middleware.MaxBodySize(maxCawBodySize),
+			middleware.Consume(supportedContentType),
+			middleware.Produce(supportedAccept))).
+		Methods("PATCH")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Caws().WithCaw("{cawId}").Done(),
+		middleware.Chain(app.commonMiddleware(app.getCawHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Caws().WithCaw("{cawId}").History().Done(),
+		middleware.Chain(app.commonMiddleware(app.getCawHistoryHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Caws().Done(),
 		app.commonMiddleware(app.getUserCawsHandler)).
 		Queries("page", "{[0-9]+}").
 		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Notifications().Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserNotificationsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Notifications().Read().Done(),
+		middleware.Chain(app.commonMiddleware(app.postReadNotificationsHandler),
+			middleware.MaxBodySize(maxNotificationsBodySize))).
+		Methods("POST")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Notifications().WithNotification("{notificationID}").Read().Done(),
+		app.commonMiddleware(app.postReadNotificationHandler)).
+		Methods("POST")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Suggestions().Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserSuggestionsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.User().WithUser("{userID}").Mentions().Done(),
+		middleware.Chain(app.commonMiddleware(app.getUserMentionsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
 
 	router.HandleFunc(
 		uriBuilder.User().WithUser("{userID}").Done(),
@@ -133,9 +316,107 @@ func (app App) addUserEndpoint(router *mux.Router) {
 }
 
 func (app *App) addCawEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
 	//router.HandleFunc("/v1/caws/{cawID}/replies?page={page}",
 	//	app.commonMiddleware(app.PostCawHandler)).
 	//	Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Caw().WithCaw("{cawId}").Done(),
+		middleware.Chain(app.commonMiddleware(app.getCawHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Caw().WithCaw("{cawId}").Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
+}
+
+func (app *App) addHashtagEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	router.HandleFunc(
+		uriBuilder.Hashtags().WithHashtag("{tag}").Caws().Done(),
+		middleware.Chain(app.commonMiddleware(app.getHashtagCawsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Hashtags().WithHashtag("{tag}").Caws().Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
+}
+
+func (app *App) addTrendEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	router.HandleFunc(
+		uriBuilder.Trends().Done(),
+		middleware.Chain(app.commonMiddleware(app.getTrendsHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Trends().Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
+}
+
+func (app *App) addStreamEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	router.HandleFunc(
+		uriBuilder.Stream().Done(),
+		app.commonMiddleware(app.getStreamHandler)).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Stream().Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
+}
+
+func (app *App) addSearchEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	router.HandleFunc(
+		uriBuilder.Search().Done(),
+		middleware.Chain(app.commonMiddleware(app.getSearchHandler),
+			middleware.Produce(supportedAccept))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.Search().Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
+}
+
+func (app *App) addSocketEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	// client authenticates with first message, so only logging is applied
+	router.HandleFunc(
+		uriBuilder.Socket().Done(),
+		middleware.Chain(app.getSocketHandler, middleware.Logging(app.logger))).
+		Methods("GET")
+}
+
+func (app *App) addMetricsEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	// metrics expose internals of the service, so they are served only to operators
+	router.HandleFunc(
+		uriBuilder.Metrics().Done(),
+		middleware.Chain(expvar.Handler().ServeHTTP,
+			app.mustBeOperator,
+			app.mustBeActive,
+			middleware.MustAuth(app.logger, app.tenant),
+			middleware.Logging(app.logger))).
+		Methods("GET")
+}
+
+func (app *App) addOpenAPIEndpoint(router *mux.Router) {
+	uriBuilder := utils.NewUriBuilder()
+
+	// document is public, so clients can discover the API before authentication
+	router.HandleFunc(
+		uriBuilder.OpenAPI().Done(),
+		middleware.Chain(app.getOpenAPIHandler, middleware.CQRS(), middleware.Logging(app.logger))).
+		Methods("GET")
+	router.HandleFunc(
+		uriBuilder.OpenAPI().Done(),
+		middleware.Chain(CQRS, middleware.Logging(app.logger))).Methods("OPTIONS")
 }
 
 func (app *App) addAuthEndpoint(router *mux.Router) {
@@ -144,6 +425,7 @@ func (app *App) addAuthEndpoint(router *mux.Router) {
 	router.HandleFunc(
 		uriBuilder.Auth().Done(),
 		middleware.Chain(app.authenticateHandler,
+			middleware.MaxBodySize(maxAuthBodySize),
 			middleware.Consume(supportedContentType),
 			middleware.Produce(supportedAccept),
 			middleware.Logging(app.logger))).
@@ -155,7 +437,8 @@ func (app *App) addAuthEndpoint(router *mux.Router) {
 
 func (app App) commonMiddleware(f http.HandlerFunc) http.HandlerFunc {
 	return middleware.Chain(f,
-		middleware.MustAuth(app.logger),
+		app.mustBeActive,
+		middleware.MustAuth(app.logger, app.tenant),
 		middleware.CQRS(),
 		middleware.Logging(app.logger))
 }
@@ -164,5 +447,5 @@ func CQRS(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
 }
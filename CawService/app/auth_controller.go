@@ -5,25 +5,92 @@ import (
 	"Caw/UserService/models"
 	"Caw/UserService/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 )
 
+// errAccountSuspended is returned by activeAccount for users suspended after their token was issued
+var errAccountSuspended = errors.New("Account is suspended")
+
+// activeAccount returns ErrNotFound if user with userID was deleted and errAccountSuspended
+// if account of user was suspended, tokens issued before remain valid otherwise
+func (app App) activeAccount(userID string) error {
+	ds := app.newUserDataStore()
+	defer ds.Close()
+	suspended, err := ds.IsSuspended(userID)
+	if err != nil {
+		return err
+	}
+	if suspended {
+		return errAccountSuspended
+	}
+	return nil
+}
+
+// mustBeActive rejects requests authenticated by token of deleted or suspended user,
+// it has to follow middleware.MustAuth
+func (app App) mustBeActive(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := userClaims(r)
+		if !ok {
+			app.logger.Error("Cannot retrieve user claims")
+			writeMissingClaims(w, r)
+			return
+		}
+
+		switch err := app.activeAccount(claims.UserId); err {
+		case nil:
+			f(w, r)
+		case infrastructure.ErrNotFound:
+			app.logger.Errorf("User of token does not exist. userID: %s", claims.UserId)
+			writeProblem(w, r, http.StatusUnauthorized, models.CodeUnauthenticated, "User of token does not exist")
+		case errAccountSuspended:
+			app.logger.Errorf("Account is suspended. userID: %s", claims.UserId)
+			writeProblem(w, r, http.StatusForbidden, models.CodeAccountSuspended, err.Error())
+		default:
+			app.logger.Errorf("Cannot check account of user %s. err: %s", claims.UserId, err)
+			writeInternalError(w, r)
+		}
+	}
+}
+
+// mustBeOperator rejects requests of users which are not configured as operators,
+// it has to follow middleware.MustAuth
+func (app App) mustBeOperator(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := userClaims(r)
+		if !ok {
+			app.logger.Error("Cannot retrieve user claims")
+			writeMissingClaims(w, r)
+			return
+		}
+
+		for _, operator := range app.operators {
+			if operator == claims.UserId {
+				f(w, r)
+				return
+			}
+		}
+		app.logger.Errorf("User %s is not operator", claims.UserId)
+		writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "Resource is available only to operators")
+	}
+}
+
 // POST /v1/authentication
 // authenticateHandler authenticate user by password
 func (app *App) authenticateHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	var auth models.Auth
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&auth); err != nil {
+	auth, err := models.AuthFromJSON(r.Body)
+	defer r.Body.Close()
+	if err != nil {
 		errMsg := "Illformated payload"
 		app.logger.Error(errMsg + fmt.Sprintf(". err: %s", err))
-		writeErrMsg(http.StatusBadRequest, errMsg, w)
+		writePayloadError(w, r, err, errMsg)
 		return
 	}
-	defer r.Body.Close()
 
 	ds := app.newUserDataStore()
 	defer ds.Close()
@@ -33,29 +100,32 @@ func (app *App) authenticateHandler(w http.ResponseWriter, r *http.Request) {
 		if err == infrastructure.ErrNotFound {
 			errMsg := "Incorrect user name or password"
 			app.logger.Error(errMsg)
-			writeErrMsg(http.StatusUnauthorized, errMsg, w)
+			writeProblem(w, r, http.StatusUnauthorized, models.CodeInvalidCredentials, errMsg)
 			return
 		}
-		errMsg := http.StatusText(http.StatusInternalServerError)
-		app.logger.Error(errMsg)
-		writeErrMsg(http.StatusInternalServerError, errMsg, w)
+		writeInternalError(w, r)
 		return
-
 	}
 
 	if !utils.CheckPasswordHash(auth.Password, user.Password) {
 		errMsg := "Incorrect user name or password"
 		app.logger.Error(errMsg, err)
-		writeErrMsg(http.StatusUnauthorized, errMsg, w)
+		writeProblem(w, r, http.StatusUnauthorized, models.CodeInvalidCredentials, errMsg)
+		return
+	}
+
+	if user.Suspended {
+		errMsg := "Account is suspended"
+		app.logger.Errorf("%s. userID: %s", errMsg, user.ID.Hex())
+		writeProblem(w, r, http.StatusForbidden, models.CodeAccountSuspended, errMsg)
 		return
 	}
 
 	expiresAt := time.Now().Add(time.Duration(time.Duration(app.TokenExpiresInMinutes) * time.Minute)).Unix()
-	tokenString, err := utils.NewUserToken(user.ID.Hex(), expiresAt)
+	tokenString, err := utils.NewTenantUserToken(user.ID.Hex(), app.tenant, expiresAt)
 	if err != nil {
-		errMsg := "Token is invalid"
-		app.logger.Errorf(errMsg + fmt.Sprintf(". err: %s", err))
-		writeErrMsg(http.StatusUnauthorized, errMsg, w)
+		app.logger.Errorf("Cannot create token. err: %s", err)
+		writeInternalError(w, r)
 		return
 	}
 
@@ -68,7 +138,7 @@ func (app *App) authenticateHandler(w http.ResponseWriter, r *http.Request) {
 	js, err := json.Marshal(token)
 	if err != nil {
 		app.logger.Errorf("Cannot marshal to json. err: %s", err)
-		w.WriteHeader(http.StatusUnauthorized)
+		writeInternalError(w, r)
 		return
 	}
 
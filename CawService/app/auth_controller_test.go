@@ -47,6 +47,18 @@ func TestAuthenticationHandler(t *testing.T) {
 			UserDataStoreError: nil,
 			ExpectedStatusCode: http.StatusUnauthorized,
 		},
+		{
+			Name:     "SuspendedUserTest",
+			AuthData: models.Auth{Name: "anycmon", Password: "foobar"},
+			User: models.User{
+				ID:        bson.ObjectIdHex("597bd1d34ac00c75e9280ae4"),
+				Name:      "anycmon",
+				Email:     "anycmon@gmail.com",
+				Password:  "$2a$14$KIRkInExkvkQdy71k1hkcOh9WtOqqQYNCFsKsr7hmTLPJko2LTXU6",
+				Suspended: true},
+			UserDataStoreError: nil,
+			ExpectedStatusCode: http.StatusForbidden,
+		},
 		{
 			Name:     "UserDoesNotExistsTest",
 			AuthData: models.Auth{Name: "anycmon", Password: "foobar"},
@@ -149,3 +161,76 @@ func TestAuthenticationOptionsHandler(t *testing.T) {
 	assert.Equal(t, expectedAllowOrigin, allowOrigin, "different allow origin %v %v", expectedAllowOrigin, allowOrigin)
 
 }
+
+func TestMetricsRequireOperator(t *testing.T) {
+	t.Parallel()
+
+	operatorID := bson.NewObjectId().Hex()
+	app := New(&utils.AppConfig{Operators: []string{operatorID}}, DataStoreFactoryMock{
+		OnCreateUserDataStore: func() infrastructure.UserDataStore { return UserDataStoreMock{} },
+	}, Logger)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, NewTestRequest(t, "GET", uriBuilder.Metrics().Done(), nil).Request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request := NewTestRequest(t, "GET", uriBuilder.Metrics().Done(), nil).WithAuthorization(bson.NewObjectId().Hex())
+	app.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	problem := models.Problem{}
+	json.NewDecoder(recorder.Body).Decode(&problem)
+	assert.Equal(t, models.CodeForbidden, problem.Code)
+
+	recorder = httptest.NewRecorder()
+	request = NewTestRequest(t, "GET", uriBuilder.Metrics().Done(), nil).WithAuthorization(operatorID)
+	app.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestTokenOfInactiveAccount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name               string
+		OnIsSuspended      func(userID string) (bool, error)
+		ExpectedStatusCode int
+		ExpectedCode       string
+	}{
+		{
+			Name:               "TokenOfSuspendedUserTest",
+			OnIsSuspended:      func(userID string) (bool, error) { return true, nil },
+			ExpectedStatusCode: http.StatusForbidden,
+			ExpectedCode:       models.CodeAccountSuspended,
+		},
+		{
+			Name:               "TokenOfDeletedUserTest",
+			OnIsSuspended:      func(userID string) (bool, error) { return false, infrastructure.ErrNotFound },
+			ExpectedStatusCode: http.StatusUnauthorized,
+			ExpectedCode:       models.CodeUnauthenticated,
+		},
+		{
+			Name:               "TokenCheckFailedTest",
+			OnIsSuspended:      func(userID string) (bool, error) { return false, errors.New("connection lost") },
+			ExpectedStatusCode: http.StatusInternalServerError,
+			ExpectedCode:       models.CodeInternalError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		userID := bson.NewObjectId().Hex()
+		app := createApp(UserDataStoreMock{OnIsSuspended: testCase.OnIsSuspended}, CawDataStoreMock{})
+
+		recorder := httptest.NewRecorder()
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID).Following().Done(), nil).
+			WithAuthorization(userID)
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		var problem models.Problem
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+		assert.Equal(t, testCase.ExpectedCode, problem.Code)
+	}
+}
@@ -2,79 +2,160 @@ package app
 
 import (
 	"Caw/UserService/infrastructure"
+	"Caw/UserService/middleware"
 	"Caw/UserService/models"
 	"Caw/UserService/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	// maxMentions limits number of user lookups done for one caw
+	maxMentions = 10
+	// defaultCawEditWindow is used when edit window is not configured
+	defaultCawEditWindow = 15 * time.Minute
+	// defaultCawMaxEdits is used when edit limit is not configured
+	defaultCawMaxEdits = 5
+)
+
 // POST /v1/users/{id}/caws
 func (app *App) postCawHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userClaims, ok := r.Context().Value("userClaims").(*utils.UserClaims)
 	if !ok {
 		app.logger.Errorf("Cannot retrieve user claims")
-		w.WriteHeader(http.StatusForbidden)
+		writeMissingClaims(w, r)
 		return
 	}
 	userID := vars["userID"]
 	if userClaims.UserId != userID {
-		app.logger.Errorf("Cannot to post caw by not authorized user: %v", userID, userClaims.UserId)
-		w.WriteHeader(http.StatusUnauthorized)
+		app.logger.Errorf("Cannot to post caw by not authorized user: %v, %v", userID, userClaims.UserId)
+		writeProblem(w, r, http.StatusUnauthorized, models.CodeNotOwner, "Caws can be posted only by their author")
+		return
 	}
 
 	caw, err := models.CawFromJSON(r.Body)
 	defer r.Body.Close()
 	if err != nil {
 		app.logger.Errorf("Cannot decode payload. err: %s", err)
-		w.WriteHeader(http.StatusBadRequest)
+		writePayloadError(w, r, err, "Payload has to be a caw in JSON format")
 		return
 	}
 
 	if caw.UserID.Hex() != userID {
-		errMsg := "User id in URI and JSON have to be the same"
-		app.logger.Error(errMsg + fmt.Sprintf("%s %s", caw.UserID.Hex(), userID))
-		writeErrMsg(http.StatusBadRequest, errMsg, w)
+		app.logger.Errorf("User id in URI and JSON are different %s %s", caw.UserID.Hex(), userID)
+		problem := models.NewProblem(http.StatusBadRequest, models.CodeInvalidPayload, "User id in URI and JSON have to be the same")
+		problem.Errors = []models.FieldError{{Field: "user_id", Code: models.FieldMismatch, Message: problem.Detail}}
+		middleware.WriteProblem(w, r, problem)
+		return
+	}
+
+	storedCaw, err := app.storeCaw(userID, *caw)
+	if err != nil {
+		writeError(w, r, err, models.CodeNotFound)
 		return
 	}
 
+	jsCaw, err := storedCaw.ToJSON()
+	if err != nil {
+		app.logger.Errorf("Cannot convert caw into js. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	uriBuilder := utils.NewUriBuilder()
+	w.Header().Add("Location", uriBuilder.User().WithUser(userID).Caws().WithCaw(storedCaw.ID.Hex()).Done())
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsCaw)
+}
+
+// storeCaw stores caw posted by user with userID and informs interested parties about it.
+// Rejected caws are reported with models.Problem.
+func (app *App) storeCaw(userID string, caw models.Caw) (*models.Caw, error) {
+	if fieldErrors := caw.Validate(app.limits); len(fieldErrors) > 0 {
+		app.logger.Errorf("Invalid caw. err: %v", fieldErrors)
+		return nil, models.NewValidationProblem(fieldErrors)
+	}
+	if caw.Visibility == "" {
+		caw.Visibility = models.VisibilityPublic
+	}
+	// counters and edit history are maintained by the service, values sent by client are ignored
+	caw.LikeCount, caw.RecawCount, caw.RepliesCount = 0, 0, 0
+	caw.EditCount = 0
+	caw.EditedAt = nil
+
 	userDataStore := app.newUserDataStore()
 	defer userDataStore.Close()
 	user, err := userDataStore.GetUser(userID)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
+		app.logger.Errorf("Cannot get caw author. userID: %s, err: %s", userID, err)
+		if err == infrastructure.ErrNotFound {
+			return nil, problemFromError(err, models.CodeUserNotFound)
+		}
+		return nil, err
 	}
 	caw.UserName = user.Name
+	caw.Mentions, err = app.resolveMentions(userDataStore, caw.Message)
+	if err != nil {
+		app.logger.Errorf("Cannot resolve mentions. err: %s", err)
+		return nil, err
+	}
 	cawDataStore := app.newCawDataStore()
 	defer cawDataStore.Close()
 
-	storedCaw, err := cawDataStore.Store(*caw)
+	var parent *models.Caw
+	if caw.ParentID != "" {
+		parent, err = cawDataStore.GetByID(caw.ParentID.Hex(), userID)
+		if err != nil {
+			app.logger.Errorf("Cannot get parent caw. parentID: %s, err: %s", caw.ParentID.Hex(), err)
+			if err == infrastructure.ErrNotFound {
+				return nil, models.NewProblem(http.StatusUnprocessableEntity, models.CodeParentCawNotFound,
+					notFoundDetails[models.CodeParentCawNotFound])
+			}
+			return nil, err
+		}
+	}
+
+	storedCaw, err := cawDataStore.Store(caw)
 	if err != nil {
 		app.logger.Errorf("Cannot store caw. err: %s", err)
-		writeErrMsg(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
-		return
+		return nil, err
 	}
 	if storedCaw == nil {
 		app.logger.Errorf("Stored caw is nil. err: %s", err)
-		writeErrMsg(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
-		return
+		return nil, errors.New("Stored caw is nil")
 	}
-	jsCaw, err := storedCaw.ToJSON()
-	if err != nil {
-		app.logger.Errorf("Cannot convert caw into js. err: %s", err)
-		writeErrMsg(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
-		return
+	app.search.IndexCaw(*storedCaw)
+	if storedCaw.Visibility == models.VisibilityPublic {
+		app.trends.Observe(storedCaw.Hashtags, storedCaw.CreatedAt)
 	}
+	app.notifyCaw(*storedCaw, parent)
+	app.publishCaw(userDataStore, *storedCaw)
+	return storedCaw, nil
+}
 
-	uriBuilder := utils.NewUriBuilder()
-	w.Header().Add("Location", uriBuilder.User().WithUser(userID).Caws().WithCaw(storedCaw.ID.Hex()).Done())
-	w.WriteHeader(http.StatusCreated)
-	w.Write(jsCaw)
+// resolveMentions returns mentioned users which exist. Mentions of unknown users are ignored.
+func (app *App) resolveMentions(userDataStore infrastructure.UserDataStore, message string) ([]models.Mention, error) {
+	var mentions []models.Mention
+	names := models.ExtractMentionNames(message)
+	if len(names) > maxMentions {
+		names = names[:maxMentions]
+	}
+	for _, name := range names {
+		user, err := userDataStore.GetUserByName(name)
+		if err == infrastructure.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, models.Mention{UserID: user.ID, Name: user.Name})
+	}
+	return mentions, nil
 }
 
 // DELETE /v1/users/{userID}/caws/{cawId}
@@ -82,52 +163,281 @@ func (app *App) deleteCawHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["userID"]
 	cawID := vars["cawId"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
 
 	cawDataStore := app.newCawDataStore()
 	defer cawDataStore.Close()
 
-	caw, err := cawDataStore.GetByID(cawID)
+	caw, err := cawDataStore.GetByID(cawID, claims.UserId)
 	if err != nil {
 		app.logger.Errorf("Cannot get caw. cawID %s, err: %s", cawID, err)
-		writeErrMsg(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		writeError(w, r, err, models.CodeCawNotFound)
 		return
 	}
 
-	if caw.UserID.Hex() != userID {
-		writeErrMsg(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), w)
+	if caw.UserID.Hex() != userID || claims.UserId != userID {
+		writeProblem(w, r, http.StatusUnauthorized, models.CodeNotOwner, "Caws can be deleted only by their author")
 		return
 	}
 
 	if err = cawDataStore.Delete(cawID); err != nil {
-		if err == infrastructure.ErrNotFound {
-			writeErrMsg(http.StatusNotFound, http.StatusText(http.StatusNotFound), w)
+		writeError(w, r, err, models.CodeCawNotFound)
+		return
+	}
+	app.search.RemoveCaw(cawID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /v1/users/{userID}/caws/{cawId}?embed=author
+// GET /v1/caws/{cawId}?embed=author
+// getCawHandler returns caw visible to requestor. Response has ETag so it can be requested conditionally.
+func (app *App) getCawHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, byUser := vars["userID"]
+	cawID := vars["cawId"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	caw, err := cawDataStore.GetByID(cawID, claims.UserId)
+	if err != nil {
+		app.logger.Errorf("Cannot get caw. cawID %s, err: %s", cawID, err)
+		writeError(w, r, err, models.CodeCawNotFound)
+		return
+	}
+	if byUser && caw.UserID.Hex() != userID {
+		writeProblem(w, r, http.StatusNotFound, models.CodeCawNotFound, notFoundDetails[models.CodeCawNotFound])
+		return
+	}
+
+	response := models.CawWithAuthor{Caw: *caw}
+	if r.URL.Query().Get("embed") == "author" {
+		userDataStore := app.newUserDataStore()
+		defer userDataStore.Close()
+		author, err := userDataStore.GetUser(caw.UserID.Hex())
+		if err != nil && err != infrastructure.ErrNotFound {
+			app.logger.Errorf("Cannot get caw author. userID: %s, err: %s", caw.UserID.Hex(), err)
+			writeInternalError(w, r)
 			return
 		}
-		writeErrMsg(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), w)
+		if author != nil {
+			public := author.ToPublic()
+			response.Author = &public
+		}
+	}
+
+	js, err := json.Marshal(response)
+	if err != nil {
+		app.logger.Errorf("Cannot convert caw into js. err: %s", err)
+		writeInternalError(w, r)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+
+	tag := etag(js)
+	w.Header().Set("ETag", tag)
+	if isNotModified(r, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(js)
 }
 
-// GET /v1/users/{userID}/caws?page=$
-func (app *App) getUserCawsHandler(w http.ResponseWriter, r *http.Request) {
+// PATCH /v1/users/{userID}/caws/{cawId}
+// patchCawHandler replaces message of caw within edit window and keeps previous revision in history
+func (app *App) patchCawHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["userID"]
-	page := 0
-	vals := r.URL.Query()
-	qPage, ok := vals["page"]
-	if ok && len(qPage) >= 1 {
-		if v, err := strconv.Atoi(qPage[0]); err != nil {
-			page = v
+	cawID := vars["cawId"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+	if claims.UserId != userID {
+		app.logger.Errorf("Cannot to edit caw by not authorized user: %s, %s", userID, claims.UserId)
+		writeProblem(w, r, http.StatusUnauthorized, models.CodeNotOwner, "Caws can be edited only by their author")
+		return
+	}
+
+	edit, err := models.CawFromJSON(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		app.logger.Errorf("Cannot decode payload. err: %s", err)
+		writePayloadError(w, r, err, "Payload has to be a caw in JSON format")
+		return
+	}
+	if fieldErrors := (models.Caw{Message: edit.Message}).Validate(app.limits); len(fieldErrors) > 0 {
+		app.logger.Errorf("Invalid caw edit. err: %v", fieldErrors)
+		writeValidationProblem(w, r, fieldErrors)
+		return
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	caw, err := cawDataStore.GetByID(cawID, claims.UserId)
+	if err != nil {
+		app.logger.Errorf("Cannot get caw. cawID %s, err: %s", cawID, err)
+		writeError(w, r, err, models.CodeCawNotFound)
+		return
+	}
+	if caw.UserID.Hex() != userID {
+		writeProblem(w, r, http.StatusNotFound, models.CodeCawNotFound, notFoundDetails[models.CodeCawNotFound])
+		return
+	}
+
+	editWindow := app.cawEditWindow
+	if editWindow <= 0 {
+		editWindow = defaultCawEditWindow
+	}
+	if !caw.IsEditableAt(time.Now(), editWindow) {
+		writeProblem(w, r, http.StatusForbidden, models.CodeEditWindowExpired, "Edit window has expired")
+		return
+	}
+
+	userDataStore := app.newUserDataStore()
+	defer userDataStore.Close()
+	mentions, err := app.resolveMentions(userDataStore, edit.Message)
+	if err != nil {
+		app.logger.Errorf("Cannot resolve mentions. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	maxEdits := app.cawMaxEdits
+	if maxEdits <= 0 {
+		maxEdits = defaultCawMaxEdits
+	}
+	editedCaw, err := cawDataStore.Edit(cawID, edit.Message, mentions, maxEdits)
+	if err != nil {
+		app.logger.Errorf("Cannot edit caw. cawID %s, err: %s", cawID, err)
+		problem := problemFromError(err, models.CodeCawNotFound)
+		if err == infrastructure.ErrEditLimit {
+			problem.Detail = fmt.Sprintf("Caw cannot be edited more than %d times", maxEdits)
 		}
+		middleware.WriteProblem(w, r, problem)
+		return
+	}
+	app.search.IndexCaw(*editedCaw)
+
+	jsCaw, err := editedCaw.ToJSON()
+	if err != nil {
+		app.logger.Errorf("Cannot convert caw into js. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(jsCaw)
+}
+
+// GET /v1/users/{userID}/caws/{cawId}/history
+// getCawHistoryHandler returns revisions replaced by edits of caw, the oldest first
+func (app *App) getCawHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	cawID := vars["cawId"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	caw, err := cawDataStore.GetByID(cawID, claims.UserId)
+	if err != nil {
+		app.logger.Errorf("Cannot get caw. cawID %s, err: %s", cawID, err)
+		writeError(w, r, err, models.CodeCawNotFound)
+		return
+	}
+	if caw.UserID.Hex() != userID {
+		writeProblem(w, r, http.StatusNotFound, models.CodeCawNotFound, notFoundDetails[models.CodeCawNotFound])
+		return
+	}
+
+	revisions, err := cawDataStore.GetRevisions(cawID)
+	if err != nil {
+		app.logger.Errorf("Cannot get caw revisions. cawID %s, err: %s", cawID, err)
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(revisions)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal caw revisions. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
+
+// GET /v1/users/{userID}/caws?page=$&order=$
+// getUserCawsHandler returns caws of requested user, the oldest first unless order is newest
+func (app *App) getUserCawsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+	order := r.URL.Query().Get("order")
+	if order != "" && order != models.OrderOldest && order != models.OrderNewest {
+		app.logger.Errorf("Unknown caws order %s", order)
+		writeParameterProblem(w, r, "order", "Order has to be oldest or newest")
+		return
 	}
 	cawDataStore := app.newCawDataStore()
 	defer cawDataStore.Close()
-	caws, err := cawDataStore.GetByUserID(userID, page)
+	caws, err := cawDataStore.GetByUserID(userID, claims.UserId, queryPage(r), order == models.OrderNewest)
 	if err != nil {
+		app.logger.Errorf("Cannot get user caws. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
 	jsCaws, err := json.Marshal(caws)
 
 	w.Write(jsCaws)
 }
+
+// GET /v1/users/{userID}/mentions?page=$
+// getUserMentionsHandler handle HTTP GET method and returns newest caws mentioning requested user
+func (app *App) getUserMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	caws, err := cawDataStore.GetByMention(userID, claims.UserId, queryPage(r))
+	if err != nil {
+		app.logger.Errorf("Cannot get user mentions. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
+		return
+	}
+
+	js, err := json.Marshal(caws)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal caws. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Write(js)
+}
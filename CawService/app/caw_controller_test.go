@@ -1,12 +1,17 @@
 package app
 
 import (
+	"Caw/UserService/infrastructure"
 	"Caw/UserService/models"
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/mgo.v2/bson"
@@ -33,6 +38,15 @@ func TestPostCawHandler(t *testing.T) {
 		UserID:  userID,
 		Message: "test message",
 	}
+	cawInvalidVisibility := &models.Caw{
+		UserID:     userID,
+		Message:    "test message",
+		Visibility: "friends",
+	}
+	cawTooLong := &models.Caw{
+		UserID:  userID,
+		Message: strings.Repeat("a", models.DefaultLimits.CawMaxLength+1),
+	}
 	cawDiffUserId := &models.Caw{
 		UserID:  bson.NewObjectId(),
 		Message: "test message",
@@ -64,6 +78,24 @@ func TestPostCawHandler(t *testing.T) {
 			ExpectedLocation:   "",
 			ExpectedStatusCode: http.StatusBadRequest,
 		},
+		{
+			Name:               "PostCawInvalidVisibilityTest",
+			UserID:             userID.Hex(),
+			Caw:                cawInvalidVisibility,
+			CawDataStoreErr:    nil,
+			ExpectedStoredCaw:  cawInvalidVisibility,
+			ExpectedLocation:   "",
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:               "PostCawTooLongMessageTest",
+			UserID:             userID.Hex(),
+			Caw:                cawTooLong,
+			CawDataStoreErr:    nil,
+			ExpectedStoredCaw:  cawTooLong,
+			ExpectedLocation:   "",
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+		},
 		{
 			Name:               "PostCawDataStoreErrorTest",
 			UserID:             userID.Hex(),
@@ -88,7 +120,11 @@ func TestPostCawHandler(t *testing.T) {
 
 		assert.Nil(t, err, "cannot to prepare request %v", err)
 
-		userDataStoreMock := UserDataStoreMock{}
+		userDataStoreMock := UserDataStoreMock{
+			OnGetUser: func(userID string) (*models.User, error) {
+				return &models.User{ID: bson.ObjectIdHex(userID), Name: "user"}, nil
+			},
+		}
 		cawDataStoreMock := CawDataStoreMock{
 			OnStore: func(caw models.Caw) (*models.Caw, error) {
 				if testCase.ExpectedStoredCaw != nil {
@@ -129,10 +165,26 @@ func TestGetUserCaws(t *testing.T) {
 
 	userID := bson.NewObjectId()
 	testCases := []struct {
-		Name string
+		Name                string
+		Query               string
+		ExpectedNewestFirst bool
+		ExpectedStatusCode  int
 	}{
 		{
-			Name: "IfExists",
+			Name:               "IfExists",
+			Query:              "?page",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:                "NewestFirst",
+			Query:               "?page=0&order=newest",
+			ExpectedNewestFirst: true,
+			ExpectedStatusCode:  http.StatusOK,
+		},
+		{
+			Name:               "UnknownOrder",
+			Query:              "?page=0&order=random",
+			ExpectedStatusCode: http.StatusBadRequest,
 		},
 	}
 
@@ -141,17 +193,380 @@ func TestGetUserCaws(t *testing.T) {
 
 		request := NewTestRequest(
 			t, "GET",
-			"/v1/users/"+userID.Hex()+"/caws?page",
+			"/v1/users/"+userID.Hex()+"/caws"+testCase.Query,
 			nil).WithAuthorization(userID.Hex())
 
 		userDataStoreMock := UserDataStoreMock{}
-		cawDataStoreMock := CawDataStoreMock{}
+		var newestFirst bool
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByUserID: func(userID, viewerID string, page int, newest bool) ([]models.Caw, error) {
+				newestFirst = newest
+				return []models.Caw{}, nil
+			},
+		}
 
 		app := createApp(userDataStoreMock, cawDataStoreMock)
 
 		recorder := httptest.NewRecorder()
 		app.ServeHTTP(recorder, request.Request)
 
-		assert.Equal(t, recorder.Code, http.StatusOK, "status code are different %v %v", recorder.Code)
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code, "status code are different")
+		assert.Equal(t, testCase.ExpectedNewestFirst, newestFirst, "caws order is different")
+	}
+}
+
+func TestPostCawHandlerResolvesMentions(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	bobID := bson.NewObjectId()
+	caw := &models.Caw{
+		UserID:  userID,
+		Message: "hello @bob and @ghost",
+	}
+	jsCaw, err := caw.ToJSON()
+	assert.Nil(t, err, "cannot to encode caw to JSON %v", err)
+
+	request := NewTestRequest(
+		t, "POST", uriBuilder.User().WithUser(userID.Hex()).Caws().Done(),
+		bytes.NewBuffer(jsCaw)).WithAuthorization(userID.Hex())
+
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUser: func(userID string) (*models.User, error) {
+			return &models.User{ID: bson.ObjectIdHex(userID), Name: "user"}, nil
+		},
+		OnGetUserByName: func(name string) (*models.User, error) {
+			if name == "bob" {
+				return &models.User{ID: bobID, Name: "bob"}, nil
+			}
+			return nil, infrastructure.ErrNotFound
+		},
+	}
+	cawDataStoreMock := CawDataStoreMock{
+		OnStore: func(caw models.Caw) (*models.Caw, error) {
+			caw.ID = bson.NewObjectId()
+			return &caw, nil
+		},
+	}
+
+	app := createApp(userDataStoreMock, cawDataStoreMock)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	storedCaw, err := models.CawFromJSON(recorder.Body)
+	assert.Nil(t, err, "cannot to convert JSON to caw")
+	assert.Equal(t, []models.Mention{{UserID: bobID, Name: "bob"}}, storedCaw.Mentions)
+}
+
+func TestPostCawHandlerIgnoresForgedCounters(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	payload := fmt.Sprintf(`{"user_id":%q,"message":"x","edit_count":-1000,"edited_at":"2017-01-01T00:00:00Z",`+
+		`"like_count":7,"recaw_count":7,"replies_count":7}`, userID.Hex())
+	request := NewTestRequest(
+		t, "POST", uriBuilder.User().WithUser(userID.Hex()).Caws().Done(),
+		bytes.NewBufferString(payload)).WithAuthorization(userID.Hex())
+
+	var stored models.Caw
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUser: func(userID string) (*models.User, error) {
+			return &models.User{ID: bson.ObjectIdHex(userID), Name: "user"}, nil
+		},
+	}
+	cawDataStoreMock := CawDataStoreMock{
+		OnStore: func(caw models.Caw) (*models.Caw, error) {
+			stored = caw
+			caw.ID = bson.NewObjectId()
+			return &caw, nil
+		},
+	}
+
+	app := createApp(userDataStoreMock, cawDataStoreMock)
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Equal(t, 0, stored.EditCount)
+	assert.Nil(t, stored.EditedAt)
+	assert.Equal(t, 0, stored.LikeCount+stored.RecawCount+stored.RepliesCount)
+}
+
+func TestGetUserMentions(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	testCases := []struct {
+		Name               string
+		CawDataStoreErr    error
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "GetUserMentionsSuccessfullyTest",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetUserMentionsNotFoundTest",
+			CawDataStoreErr:    infrastructure.ErrNotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "GetUserMentionsDataStoreErrorTest",
+			CawDataStoreErr:    errors.New("Unknow error"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(
+			t, "GET", uriBuilder.User().WithUser(userID.Hex()).Mentions().Done()+"?page=2",
+			nil).WithAuthorization(userID.Hex())
+
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByMention: func(mentionedID, viewerID string, page int) ([]models.Caw, error) {
+				assert.Equal(t, userID.Hex(), mentionedID)
+				assert.Equal(t, 2, page)
+				return []models.Caw{}, testCase.CawDataStoreErr
+			},
+		}
+
+		app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+	}
+}
+
+func TestPatchCawHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	cawID := bson.NewObjectId()
+	testCases := []struct {
+		Name               string
+		RequestorID        bson.ObjectId
+		Message            string
+		CreatedAt          time.Time
+		EditErr            error
+		ExpectedStatusCode int
+	}{
+		{"EditCawSuccessfullyTest", userID, "edited", time.Now(), nil, http.StatusOK},
+		{"EditCawByOtherUserTest", bson.NewObjectId(), "edited", time.Now(), nil, http.StatusUnauthorized},
+		{"EditCawEmptyMessageTest", userID, " ", time.Now(), nil, http.StatusUnprocessableEntity},
+		{"EditCawAfterWindowTest", userID, "edited", time.Now().Add(-time.Hour), nil, http.StatusForbidden},
+		{"EditCawLimitTest", userID, "edited", time.Now(), infrastructure.ErrEditLimit, http.StatusForbidden},
+		{"EditCawConflictTest", userID, "edited", time.Now(), infrastructure.ErrEditConflict, http.StatusConflict},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		jsCaw, err := models.Caw{Message: testCase.Message}.ToJSON()
+		assert.Nil(t, err, "cannot to encode caw to JSON %v", err)
+		request := NewTestRequest(
+			t, "PATCH", uriBuilder.User().WithUser(userID.Hex()).Caws().WithCaw(cawID.Hex()).Done(),
+			bytes.NewBuffer(jsCaw)).WithAuthorization(testCase.RequestorID.Hex())
+
+		stored := models.Caw{ID: cawID, UserID: userID, Message: "original", CreatedAt: testCase.CreatedAt}
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+				return &stored, nil
+			},
+			OnEdit: func(cawID, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error) {
+				if testCase.EditErr != nil {
+					return nil, testCase.EditErr
+				}
+				editedAt := time.Now()
+				edited := stored
+				edited.Message = message
+				edited.EditedAt = &editedAt
+				edited.EditCount++
+				return &edited, nil
+			},
+		}
+
+		app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if recorder.Code == http.StatusOK {
+			edited, err := models.CawFromJSON(recorder.Body)
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.Message, edited.Message)
+			assert.Equal(t, 1, edited.EditCount)
+			assert.NotNil(t, edited.EditedAt)
+		}
 	}
 }
+
+func TestGetCawHistoryHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	cawID := bson.NewObjectId()
+	testCases := []struct {
+		Name               string
+		AuthorID           bson.ObjectId
+		GetByIDErr         error
+		ExpectedStatusCode int
+	}{
+		{"GetCawHistorySuccessfullyTest", userID, nil, http.StatusOK},
+		{"GetCawHistoryOfOtherUserCawTest", bson.NewObjectId(), nil, http.StatusNotFound},
+		{"GetCawHistoryNotVisibleCawTest", userID, infrastructure.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(
+			t, "GET", uriBuilder.User().WithUser(userID.Hex()).Caws().WithCaw(cawID.Hex()).History().Done(),
+			nil).WithAuthorization(bson.NewObjectId().Hex())
+		revisions := []models.CawRevision{{CawID: cawID, Revision: 0, Message: "original"}}
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+				if testCase.GetByIDErr != nil {
+					return nil, testCase.GetByIDErr
+				}
+				return &models.Caw{ID: bson.ObjectIdHex(cawID), UserID: testCase.AuthorID}, nil
+			},
+			OnGetRevisions: func(cawID string) ([]models.CawRevision, error) {
+				return revisions, nil
+			},
+		}
+
+		app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if recorder.Code == http.StatusOK {
+			var given []models.CawRevision
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&given))
+			assert.Equal(t, revisions, given)
+		}
+	}
+}
+
+func TestGetCawHandler(t *testing.T) {
+	t.Parallel()
+
+	authorID := bson.NewObjectId()
+	cawID := bson.NewObjectId()
+	testCases := []struct {
+		Name               string
+		URI                string
+		GetByIDErr         error
+		ExpectedStatusCode int
+		ExpectedAuthor     bool
+	}{
+		{
+			Name:               "GetCawByUserURITest",
+			URI:                uriBuilder.User().WithUser(authorID.Hex()).Caws().WithCaw(cawID.Hex()).Done(),
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetCawByCanonicalURITest",
+			URI:                uriBuilder.Caw().WithCaw(cawID.Hex()).Done(),
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetCawWithAuthorTest",
+			URI:                uriBuilder.Caw().WithCaw(cawID.Hex()).Done() + "?embed=author",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedAuthor:     true,
+		},
+		{
+			Name:               "GetCawOfOtherUserTest",
+			URI:                uriBuilder.User().WithUser(bson.NewObjectId().Hex()).Caws().WithCaw(cawID.Hex()).Done(),
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "GetNotVisibleCawTest",
+			URI:                uriBuilder.Caw().WithCaw(cawID.Hex()).Done(),
+			GetByIDErr:         infrastructure.ErrNotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(t, "GET", testCase.URI, nil).WithAuthorization(bson.NewObjectId().Hex())
+		userDataStoreMock := UserDataStoreMock{
+			OnGetUser: func(userID string) (*models.User, error) {
+				return &models.User{ID: bson.ObjectIdHex(userID), Name: "author", Password: "secret"}, nil
+			},
+		}
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+				if testCase.GetByIDErr != nil {
+					return nil, testCase.GetByIDErr
+				}
+				return &models.Caw{ID: bson.ObjectIdHex(cawID), UserID: authorID, Message: "message"}, nil
+			},
+		}
+
+		app := createApp(userDataStoreMock, cawDataStoreMock)
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if recorder.Code != http.StatusOK {
+			continue
+		}
+		assert.NotEmpty(t, recorder.Header().Get("ETag"))
+		var caw models.CawWithAuthor
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&caw))
+		assert.Equal(t, cawID, caw.ID)
+		if testCase.ExpectedAuthor {
+			assert.Equal(t, "author", caw.Author.Name)
+			assert.Empty(t, caw.Author.Password)
+		} else {
+			assert.Nil(t, caw.Author)
+		}
+	}
+}
+
+func TestGetCawHandlerConditional(t *testing.T) {
+	t.Parallel()
+
+	caw := &models.Caw{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "message"}
+	cawDataStoreMock := CawDataStoreMock{
+		OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+			return caw, nil
+		},
+	}
+	app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+	uri := uriBuilder.Caw().WithCaw(caw.ID.Hex()).Done()
+	viewerID := bson.NewObjectId().Hex()
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, NewTestRequest(t, "GET", uri, nil).WithAuthorization(viewerID).Request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	tag := recorder.Header().Get("ETag")
+
+	request := NewTestRequest(t, "GET", uri, nil).WithAuthorization(viewerID)
+	request.Header.Set("If-None-Match", `"other", `+tag)
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+
+	caw.Message = "edited"
+	request = NewTestRequest(t, "GET", uri, nil).WithAuthorization(viewerID)
+	request.Header.Set("If-None-Match", tag)
+	recorder = httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusOK, recorder.Code, "changed caw has to be returned")
+	assert.NotEqual(t, tag, recorder.Header().Get("ETag"))
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/middleware"
+	"Caw/UserService/models"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// notFoundDetails describes missing resources by problem code
+var notFoundDetails = map[string]string{
+	models.CodeNotFound:             "Resource does not exist",
+	models.CodeUserNotFound:         "User does not exist",
+	models.CodeCawNotFound:          "Caw does not exist",
+	models.CodeParentCawNotFound:    "Parent caw does not exist",
+	models.CodeNotificationNotFound: "Notification does not exist",
+	models.CodeFollowNotFound:       "Follow relation does not exist",
+}
+
+// writeProblem writes problem details with provided status, code and detail
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	middleware.WriteProblem(w, r, models.NewProblem(status, code, detail))
+}
+
+// writeValidationProblem writes problem details listing rejected fields
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, errors []models.FieldError) {
+	middleware.WriteProblem(w, r, models.NewValidationProblem(errors))
+}
+
+// writeParameterProblem writes problem details of invalid query parameter or header
+func writeParameterProblem(w http.ResponseWriter, r *http.Request, parameter string, message string) {
+	writeParametersProblem(w, r, []models.FieldError{{Field: parameter, Code: models.FieldInvalid, Message: message}})
+}
+
+// writeParametersProblem writes problem details listing invalid query parameters or headers
+func writeParametersProblem(w http.ResponseWriter, r *http.Request, errors []models.FieldError) {
+	problem := models.NewProblem(http.StatusBadRequest, models.CodeInvalidParameter, errors[0].Message)
+	problem.Errors = errors
+	middleware.WriteProblem(w, r, problem)
+}
+
+// writePayloadError writes problem details of request payload which cannot be decoded.
+// Too large payloads are reported with 413 and unknown fields are listed in field errors.
+func writePayloadError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	if err == middleware.ErrBodyTooLarge {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, models.CodePayloadTooLarge, err.Error())
+		return
+	}
+	problem := models.NewProblem(http.StatusBadRequest, models.CodeInvalidPayload, detail)
+	if field := unknownField(err); field != "" {
+		problem.Errors = []models.FieldError{{
+			Field:   field,
+			Code:    models.FieldUnknown,
+			Message: fmt.Sprintf("Field %s is not supported", field),
+		}}
+	} else if err != nil {
+		problem.Detail = fmt.Sprintf("%s. %s", detail, err)
+	}
+	middleware.WriteProblem(w, r, problem)
+}
+
+// unknownField returns name of field rejected by strict decoding or empty string for other errors
+func unknownField(err error) string {
+	const prefix = `json: unknown field "`
+	if err == nil || !strings.HasPrefix(err.Error(), prefix) {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(err.Error(), prefix), `"`)
+}
+
+// writeError writes problem details mapped from err.
+// ErrNotFound is reported with notFoundCode so clients know which resource is missing.
+func writeError(w http.ResponseWriter, r *http.Request, err error, notFoundCode string) {
+	middleware.WriteProblem(w, r, problemFromError(err, notFoundCode))
+}
+
+// problemFromError maps errors of datastores and validation to problem details
+func problemFromError(err error, notFoundCode string) models.Problem {
+	if problem, ok := err.(models.Problem); ok {
+		return problem
+	}
+	switch err {
+	case infrastructure.ErrNotFound:
+		return models.NewProblem(http.StatusNotFound, notFoundCode, notFoundDetails[notFoundCode])
+	case infrastructure.ErrUserExists:
+		return models.NewProblem(http.StatusConflict, models.CodeUserExists, "User with this name or email already exists")
+	case infrastructure.ErrFollowExists:
+		return models.NewProblem(http.StatusConflict, models.CodeFollowExists, "User is already followed")
+	case infrastructure.ErrSelfFollow:
+		return models.NewProblem(http.StatusUnprocessableEntity, models.CodeSelfFollow, "User cannot follow itself")
+	case infrastructure.ErrEditLimit:
+		return models.NewProblem(http.StatusForbidden, models.CodeEditLimitReached, "Caw cannot be edited anymore")
+	case infrastructure.ErrEditConflict:
+		return models.NewProblem(http.StatusConflict, models.CodeEditConflict, "Caw was edited concurrently")
+	}
+	return models.NewProblem(http.StatusInternalServerError, models.CodeInternalError,
+		http.StatusText(http.StatusInternalServerError))
+}
+
+// writeInternalError writes problem details of unexpected failure
+func writeInternalError(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusInternalServerError, models.CodeInternalError,
+		http.StatusText(http.StatusInternalServerError))
+}
+
+// writeMissingClaims writes problem details of request without user claims
+func writeMissingClaims(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "User claims are missing")
+}
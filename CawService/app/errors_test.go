@@ -0,0 +1,150 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestProblemFromError(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Err            error
+		NotFoundCode   string
+		ExpectedStatus int
+		ExpectedCode   string
+	}{
+		{"UserNotFoundTest", infrastructure.ErrNotFound, models.CodeUserNotFound, http.StatusNotFound, models.CodeUserNotFound},
+		{"CawNotFoundTest", infrastructure.ErrNotFound, models.CodeCawNotFound, http.StatusNotFound, models.CodeCawNotFound},
+		{"UserExistsTest", infrastructure.ErrUserExists, models.CodeUserNotFound, http.StatusConflict, models.CodeUserExists},
+		{"SelfFollowTest", infrastructure.ErrSelfFollow, models.CodeUserNotFound, http.StatusUnprocessableEntity, models.CodeSelfFollow},
+		{
+			"ProblemTest",
+			models.NewValidationProblem([]models.FieldError{{Field: "name"}}),
+			models.CodeUserNotFound,
+			http.StatusUnprocessableEntity,
+			models.CodeValidationFailed,
+		},
+		{"UnknownErrorTest", errors.New("db error"), models.CodeUserNotFound, http.StatusInternalServerError, models.CodeInternalError},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		problem := problemFromError(testCase.Err, testCase.NotFoundCode)
+		assert.Equal(t, testCase.ExpectedStatus, problem.Status)
+		assert.Equal(t, testCase.ExpectedCode, problem.Code)
+		assert.Equal(t, "urn:caw:problem:"+testCase.ExpectedCode, problem.Type)
+		assert.Equal(t, http.StatusText(testCase.ExpectedStatus), problem.Title)
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUser: func(userID string) (*models.User, error) {
+			return nil, infrastructure.ErrNotFound
+		},
+	}
+	cawDataStoreMock := CawDataStoreMock{
+		OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+			return nil, infrastructure.ErrNotFound
+		},
+	}
+	testCases := []struct {
+		Name           string
+		Request        *TestRequest
+		ExpectedStatus int
+		ExpectedCode   string
+		ExpectedFields []string
+	}{
+		{
+			"UserNotFoundTest",
+			NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID.Hex()).Done(), nil).WithAuthorization(userID.Hex()),
+			http.StatusNotFound,
+			models.CodeUserNotFound,
+			nil,
+		},
+		{
+			"CawNotFoundTest",
+			NewTestRequest(t, "GET", uriBuilder.Caw().WithCaw(bson.NewObjectId().Hex()).Done(), nil).WithAuthorization(userID.Hex()),
+			http.StatusNotFound,
+			models.CodeCawNotFound,
+			nil,
+		},
+		{
+			"InvalidUserTest",
+			NewTestRequest(t, "POST", uriBuilder.User().Done(), marshalUser(models.User{Name: "user"}, t)),
+			http.StatusUnprocessableEntity,
+			models.CodeValidationFailed,
+			[]string{"email", "password"},
+		},
+		{
+			"InvalidParameterTest",
+			NewTestRequest(t, "GET", uriBuilder.User().Autocomplete().Done()+"?prefix=a&limit=abc", nil).WithAuthorization(userID.Hex()),
+			http.StatusBadRequest,
+			models.CodeInvalidParameter,
+			[]string{"limit"},
+		},
+		{
+			"UnknownFieldTest",
+			NewTestRequest(t, "POST", uriBuilder.User().WithUser(userID.Hex()).Caws().Done(),
+				strings.NewReader(`{"userId": "`+userID.Hex()+`", "message": "hi"}`)).WithAuthorization(userID.Hex()),
+			http.StatusBadRequest,
+			models.CodeInvalidPayload,
+			[]string{"userId"},
+		},
+		{
+			"TrailingDataTest",
+			NewTestRequest(t, "POST", uriBuilder.Auth().Done(), strings.NewReader(`{"name": "user", "password": "foobar12"} {}`)),
+			http.StatusBadRequest,
+			models.CodeInvalidPayload,
+			nil,
+		},
+		{
+			"PayloadTooLargeTest",
+			NewTestRequest(t, "POST", uriBuilder.User().WithUser(userID.Hex()).Caws().Done(),
+				strings.NewReader(`{"message": "`+strings.Repeat("a", maxCawBodySize)+`"}`)).WithAuthorization(userID.Hex()),
+			http.StatusRequestEntityTooLarge,
+			models.CodePayloadTooLarge,
+			nil,
+		},
+		{
+			"UnauthenticatedTest",
+			NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID.Hex()).Done(), nil),
+			http.StatusUnauthorized,
+			models.CodeUnauthenticated,
+			nil,
+		},
+	}
+
+	app := createApp(userDataStoreMock, cawDataStoreMock)
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, testCase.Request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatus, recorder.Code)
+		assert.Equal(t, models.ProblemContentType, recorder.Header().Get("Content-Type"))
+		var problem models.Problem
+		assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&problem))
+		assert.Equal(t, testCase.ExpectedStatus, problem.Status)
+		assert.Equal(t, testCase.ExpectedCode, problem.Code)
+		assert.Equal(t, testCase.Request.URL.Path, problem.Instance)
+		var fields []string
+		for _, fieldError := range problem.Errors {
+			fields = append(fields, fieldError.Field)
+		}
+		assert.Equal(t, testCase.ExpectedFields, fields)
+	}
+}
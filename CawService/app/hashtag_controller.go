@@ -0,0 +1,39 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /v1/hashtags/{tag}/caws?page=$
+// getHashtagCawsHandler handle HTTP GET method and returns newest caws tagged with requested hashtag
+func (app *App) getHashtagCawsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tag := vars["tag"]
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	caws, err := cawDataStore.GetByHashtag(tag, claims.UserId, queryPage(r))
+	if err != nil {
+		app.logger.Errorf("Cannot get hashtag caws. tag: %s, err: %s", tag, err)
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(caws)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal caws. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Write(js)
+}
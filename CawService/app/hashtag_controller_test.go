@@ -0,0 +1,85 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGetHashtagCawsHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	caws := []models.Caw{
+		{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "#go", Hashtags: []string{"go"}},
+	}
+	testCases := []struct {
+		Name               string
+		URI                string
+		Caws               []models.Caw
+		CawDataStoreErr    error
+		ExpectedTag        string
+		ExpectedPage       int
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "GetHashtagCawsSuccessfullyTest",
+			URI:                uriBuilder.Hashtags().WithHashtag("go").Caws().Done(),
+			Caws:               caws,
+			ExpectedTag:        "go",
+			ExpectedPage:       0,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetHashtagCawsSecondPageTest",
+			URI:                uriBuilder.Hashtags().WithHashtag("Go").Caws().Done() + "?page=1",
+			Caws:               caws,
+			ExpectedTag:        "Go",
+			ExpectedPage:       1,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetHashtagCawsDataStoreErrorTest",
+			URI:                uriBuilder.Hashtags().WithHashtag("go").Caws().Done(),
+			CawDataStoreErr:    errors.New("Unknow error"),
+			ExpectedTag:        "go",
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(t, "GET", testCase.URI, nil).WithAuthorization(userID.Hex())
+
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByHashtag: func(hashtag, viewerID string, page int) ([]models.Caw, error) {
+				assert.Equal(t, testCase.ExpectedTag, hashtag)
+				assert.Equal(t, userID.Hex(), viewerID)
+				assert.Equal(t, testCase.ExpectedPage, page)
+				return testCase.Caws, testCase.CawDataStoreErr
+			},
+		}
+
+		app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+
+		if recorder.Code == http.StatusOK {
+			var returnedCaws []models.Caw
+			err := json.NewDecoder(recorder.Body).Decode(&returnedCaws)
+			assert.Nil(t, err, "cannot to decode caws %v", err)
+			assert.Equal(t, len(testCase.Caws), len(returnedCaws))
+		}
+	}
+}
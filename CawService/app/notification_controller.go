@@ -0,0 +1,194 @@
+package app
+
+import (
+	"Caw/UserService/events"
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// notify stores notification for user. Notifications are best effort,
+// so failures are only logged and never fail the request which produced them.
+func (app *App) notify(notification models.Notification) {
+	if notification.UserID == notification.Actor.UserID {
+		return
+	}
+
+	ds := app.newNotificationDataStore()
+	defer ds.Close()
+	stored, err := ds.Store(notification)
+	if err != nil {
+		app.logger.Errorf("Cannot store notification. userID: %s, type: %s, err: %s",
+			notification.UserID.Hex(), notification.Type, err)
+		return
+	}
+
+	if err = app.events.Publish(events.EventNotification, stored, stored.UserID.Hex()); err != nil {
+		app.logger.Errorf("Cannot publish notification. err: %s", err)
+	}
+}
+
+// notifyCaw notifies author of replied caw and users mentioned in stored caw,
+// users who cannot read the caw are not notified
+func (app *App) notifyCaw(caw models.Caw, parent *models.Caw) {
+	actor := models.Follow{UserID: caw.UserID, Name: caw.UserName}
+	if parent != nil && app.canSeeCaw(parent.UserID.Hex(), caw) {
+		app.notify(models.Notification{UserID: parent.UserID, Type: models.NotificationReply, Actor: actor, CawID: caw.ID})
+	}
+
+	for _, mention := range caw.Mentions {
+		if !app.canSeeCaw(mention.UserID.Hex(), caw) {
+			continue
+		}
+		app.notify(models.Notification{UserID: mention.UserID, Type: models.NotificationMention, Actor: actor, CawID: caw.ID})
+	}
+}
+
+// canSeeCaw returns true if user with userID is allowed to read caw. Followers only caws
+// can be read only by followers of the author and mentioned only caws by mentioned users.
+func (app *App) canSeeCaw(userID string, caw models.Caw) bool {
+	if userID == caw.UserID.Hex() {
+		return true
+	}
+
+	switch caw.Visibility {
+	case models.VisibilityMentioned:
+		for _, mention := range caw.Mentions {
+			if mention.UserID.Hex() == userID {
+				return true
+			}
+		}
+		return false
+	case models.VisibilityFollowers:
+		ds := app.newUserDataStore()
+		defer ds.Close()
+		_, err := ds.GetFollowRelation(userID, caw.UserID.Hex())
+		if err != nil && err != infrastructure.ErrNotFound {
+			app.logger.Errorf("Cannot check follow relation of notified user. userID: %s, err: %s", userID, err)
+		}
+		return err == nil
+	}
+	return true
+}
+
+// GET /v1/users/{userID}/notifications?page=$&unread=true&grouped=true
+// getUserNotificationsHandler handle HTTP GET method and returns notifications of requestor
+func (app *App) getUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	if !app.isRequestor(w, r, userID) {
+		return
+	}
+
+	vals := r.URL.Query()
+	grouped := vals.Get("grouped") == "true"
+	ds := app.newNotificationDataStore()
+	defer ds.Close()
+	var notifications []models.Notification
+	var err error
+	if grouped {
+		notifications, err = ds.GetGroupedByUserID(userID, vals.Get("unread") == "true", queryPage(r))
+	} else {
+		notifications, err = ds.GetByUserID(userID, vals.Get("unread") == "true", queryPage(r))
+	}
+	if err != nil {
+		app.logger.Errorf("Cannot get user notifications. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeNotificationNotFound)
+		return
+	}
+
+	unread, err := ds.CountUnread(userID)
+	if err != nil {
+		app.logger.Errorf("Cannot count unread notifications. userID: %s, err: %s", userID, err)
+		writeInternalError(w, r)
+		return
+	}
+
+	result := models.Notifications{UnreadCount: unread}
+	if grouped {
+		result.Groups = models.GroupNotifications(notifications)
+	} else {
+		result.Notifications = notifications
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal notifications. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Write(js)
+}
+
+// POST /v1/users/{userID}/notifications/read
+// postReadNotificationsHandler marks notifications listed in payload as read. All notifications are
+// marked when payload sets all or is missing, empty list of notifications is rejected.
+func (app *App) postReadNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	if !app.isRequestor(w, r, userID) {
+		return
+	}
+
+	var read models.ReadNotifications
+	err := models.DecodeJSON(r.Body, &read)
+	defer r.Body.Close()
+	if err == io.EOF {
+		read.All = true
+	} else if err != nil {
+		app.logger.Errorf("Cannot to convert payload to ReadNotifications. err: %s", err)
+		writePayloadError(w, r, err, "Payload has to be a list of notification ids in JSON format")
+		return
+	}
+	if read.All == (len(read.IDs) > 0) {
+		app.logger.Errorf("Notifications to mark as read are not selected. payload: %+v", read)
+		writeProblem(w, r, http.StatusBadRequest, models.CodeInvalidPayload,
+			"Either ids of notifications have to be listed or all has to be true")
+		return
+	}
+
+	ds := app.newNotificationDataStore()
+	defer ds.Close()
+	if read.All {
+		_, err = ds.MarkAllAsRead(userID)
+	} else {
+		_, err = ds.MarkAsRead(userID, read.IDs)
+	}
+	if err != nil {
+		app.logger.Errorf("Cannot mark notifications as read. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeNotificationNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /v1/users/{userID}/notifications/{notificationID}/read
+// postReadNotificationHandler marks single notification as read, marking already read notification succeeds
+func (app *App) postReadNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	notificationID := vars["notificationID"]
+	if !app.isRequestor(w, r, userID) {
+		return
+	}
+
+	ds := app.newNotificationDataStore()
+	defer ds.Close()
+	found, err := ds.MarkAsRead(userID, []string{notificationID})
+	if err != nil {
+		app.logger.Errorf("Cannot mark notification as read. userID: %s, notificationID: %s, err: %s", userID, notificationID, err)
+		writeError(w, r, err, models.CodeNotificationNotFound)
+		return
+	}
+	if found == 0 {
+		app.logger.Errorf("Notification does not exist. notificationID: %s", notificationID)
+		writeError(w, r, infrastructure.ErrNotFound, models.CodeNotificationNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
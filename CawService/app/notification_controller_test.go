@@ -0,0 +1,312 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGetUserNotificationsHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	notifications := []models.Notification{
+		{ID: bson.NewObjectId(), UserID: userID, Type: models.NotificationFollow, Actor: models.Follow{UserID: bson.NewObjectId(), Name: "bob"}},
+		{ID: bson.NewObjectId(), UserID: userID, Type: models.NotificationFollow, Actor: models.Follow{UserID: bson.NewObjectId(), Name: "alice"}},
+	}
+	testCases := []struct {
+		Name               string
+		RequestorID        string
+		Query              string
+		ExpectedUnreadOnly bool
+		ExpectedGroups     int
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "GetNotificationsTest",
+			RequestorID:        userID.Hex(),
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetUnreadGroupedNotificationsTest",
+			RequestorID:        userID.Hex(),
+			Query:              "?unread=true&grouped=true",
+			ExpectedUnreadOnly: true,
+			ExpectedGroups:     1,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetNotificationsOfOtherUserTest",
+			RequestorID:        bson.NewObjectId().Hex(),
+			ExpectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID.Hex()).Notifications().Done()+testCase.Query, nil).
+			WithAuthorization(testCase.RequestorID)
+
+		notificationDataStoreMock := NotificationDataStoreMock{
+			OnGetByUserID: func(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+				assert.Equal(t, testCase.ExpectedUnreadOnly, unreadOnly)
+				assert.Equal(t, 0, testCase.ExpectedGroups, "grouped notifications have to be paginated by groups")
+				return notifications, nil
+			},
+			OnGetGroupedByUserID: func(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+				assert.Equal(t, testCase.ExpectedUnreadOnly, unreadOnly)
+				assert.NotEqual(t, 0, testCase.ExpectedGroups, "ungrouped notifications have to be paginated by notifications")
+				return notifications, nil
+			},
+			OnCountUnread: func(userID string) (int, error) {
+				return len(notifications), nil
+			},
+		}
+
+		app := createAppWithNotifications(UserDataStoreMock{}, CawDataStoreMock{}, notificationDataStoreMock)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+
+		if recorder.Code == http.StatusOK {
+			var result models.Notifications
+			err := json.NewDecoder(recorder.Body).Decode(&result)
+			assert.Nil(t, err, "cannot to decode notifications %v", err)
+			assert.Equal(t, len(notifications), result.UnreadCount)
+			assert.Equal(t, testCase.ExpectedGroups, len(result.Groups))
+			if testCase.ExpectedGroups == 0 {
+				assert.Equal(t, len(notifications), len(result.Notifications))
+			}
+		}
+	}
+}
+
+func TestPostReadNotificationsHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	notificationID := bson.NewObjectId().Hex()
+	testCases := []struct {
+		Name               string
+		URI                string
+		Payload            string
+		Found              int
+		ExpectedIDs        []string
+		ExpectedAll        bool
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "MarkAllAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			ExpectedAll:        true,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "MarkListedAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			Payload:            `{"ids": ["` + notificationID + `"]}`,
+			Found:              1,
+			ExpectedIDs:        []string{notificationID},
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "MarkSingleAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().WithNotification(notificationID).Read().Done(),
+			Found:              1,
+			ExpectedIDs:        []string{notificationID},
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "MarkAllExplicitlyAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			Payload:            `{"all": true}`,
+			ExpectedAll:        true,
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "MarkEmptyListAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			Payload:            `{"ids": []}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "MarkListedAndAllAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			Payload:            `{"ids": ["` + notificationID + `"], "all": true}`,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "MarkAlreadyReadAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().WithNotification(notificationID).Read().Done(),
+			Found:              1,
+			ExpectedIDs:        []string{notificationID},
+			ExpectedStatusCode: http.StatusNoContent,
+		},
+		{
+			Name:               "MarkNotExistingAsReadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().WithNotification(notificationID).Read().Done(),
+			Found:              0,
+			ExpectedIDs:        []string{notificationID},
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "MarkIllformatedPayloadTest",
+			URI:                uriBuilder.User().WithUser(userID.Hex()).Notifications().Read().Done(),
+			Payload:            `{"ids": `,
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(t, "POST", testCase.URI, bytes.NewBufferString(testCase.Payload)).
+			WithAuthorization(userID.Hex())
+
+		markedAll := false
+		notificationDataStoreMock := NotificationDataStoreMock{
+			OnMarkAsRead: func(userID string, notificationIDs []string) (int, error) {
+				assert.Equal(t, testCase.ExpectedIDs, notificationIDs)
+				return testCase.Found, nil
+			},
+			OnMarkAllAsRead: func(userID string) (int, error) {
+				markedAll = true
+				return testCase.Found, nil
+			},
+		}
+
+		app := createAppWithNotifications(UserDataStoreMock{}, CawDataStoreMock{}, notificationDataStoreMock)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+		assert.Equal(t, testCase.ExpectedAll, markedAll)
+	}
+}
+
+func TestPostReplyCawNotifications(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	parentAuthorID := bson.NewObjectId()
+	mentionedID := bson.NewObjectId()
+	parentID := bson.NewObjectId()
+	testCases := []struct {
+		Name                  string
+		ParentErr             error
+		Visibility            string
+		AuthorFollowers       []bson.ObjectId
+		ExpectedNotifications []string
+		ExpectedStatusCode    int
+	}{
+		{
+			Name:                  "ReplyNotifiesParentAuthorAndMentionedTest",
+			Visibility:            models.VisibilityPublic,
+			ExpectedNotifications: []string{models.NotificationReply, models.NotificationMention},
+			ExpectedStatusCode:    http.StatusCreated,
+		},
+		{
+			Name:                  "FollowersOnlyReplyNotifiesFollowersTest",
+			Visibility:            models.VisibilityFollowers,
+			AuthorFollowers:       []bson.ObjectId{parentAuthorID, mentionedID},
+			ExpectedNotifications: []string{models.NotificationReply, models.NotificationMention},
+			ExpectedStatusCode:    http.StatusCreated,
+		},
+		{
+			Name:                  "FollowersOnlyReplySkipsMentionedNotFollowerTest",
+			Visibility:            models.VisibilityFollowers,
+			AuthorFollowers:       []bson.ObjectId{parentAuthorID},
+			ExpectedNotifications: []string{models.NotificationReply},
+			ExpectedStatusCode:    http.StatusCreated,
+		},
+		{
+			Name:                  "FollowersOnlyReplySkipsParentAuthorNotFollowerTest",
+			Visibility:            models.VisibilityFollowers,
+			AuthorFollowers:       []bson.ObjectId{mentionedID},
+			ExpectedNotifications: []string{models.NotificationMention},
+			ExpectedStatusCode:    http.StatusCreated,
+		},
+		{
+			Name:                  "MentionedOnlyReplySkipsParentAuthorNotMentionedTest",
+			Visibility:            models.VisibilityMentioned,
+			AuthorFollowers:       []bson.ObjectId{parentAuthorID, mentionedID},
+			ExpectedNotifications: []string{models.NotificationMention},
+			ExpectedStatusCode:    http.StatusCreated,
+		},
+		{
+			Name:               "ReplyToNotExistingCawTest",
+			ParentErr:          infrastructure.ErrNotFound,
+			ExpectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		jsCaw, err := models.Caw{UserID: userID, ParentID: parentID, Message: "@bob look"}.ToJSON()
+		assert.Nil(t, err, "cannot to encode caw to JSON %v", err)
+		request := NewTestRequest(t, "POST", uriBuilder.User().WithUser(userID.Hex()).Caws().Done(), bytes.NewBuffer(jsCaw)).
+			WithAuthorization(userID.Hex())
+
+		userDataStoreMock := UserDataStoreMock{
+			OnGetUser: func(userID string) (*models.User, error) {
+				return &models.User{ID: bson.ObjectIdHex(userID), Name: "user"}, nil
+			},
+			OnGetUserByName: func(name string) (*models.User, error) {
+				return &models.User{ID: mentionedID, Name: name}, nil
+			},
+			OnGetFollowRelation: func(followerID, followingID string) (*models.FollowRelation, error) {
+				assert.Equal(t, userID.Hex(), followingID)
+				for _, follower := range testCase.AuthorFollowers {
+					if follower.Hex() == followerID {
+						return &models.FollowRelation{}, nil
+					}
+				}
+				return nil, infrastructure.ErrNotFound
+			},
+		}
+		cawDataStoreMock := CawDataStoreMock{
+			OnGetByID: func(cawID, viewerID string) (*models.Caw, error) {
+				assert.Equal(t, parentID.Hex(), cawID)
+				if testCase.ParentErr != nil {
+					return nil, testCase.ParentErr
+				}
+				return &models.Caw{ID: parentID, UserID: parentAuthorID}, nil
+			},
+			OnStore: func(caw models.Caw) (*models.Caw, error) {
+				caw.ID = bson.NewObjectId()
+				caw.Visibility = testCase.Visibility
+				return &caw, nil
+			},
+		}
+		var notifications []string
+		notificationDataStoreMock := NotificationDataStoreMock{
+			OnStore: func(notification models.Notification) (*models.Notification, error) {
+				notifications = append(notifications, notification.Type)
+				return &notification, nil
+			},
+		}
+
+		app := createAppWithNotifications(userDataStoreMock, cawDataStoreMock, notificationDataStoreMock)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+		assert.Equal(t, testCase.ExpectedNotifications, notifications)
+	}
+}
@@ -0,0 +1,798 @@
+package app
+
+import "net/http"
+
+// GET /v1/openapi.json
+// getOpenAPIHandler handle HTTP GET method and returns OpenAPI 3 document describing the API
+func (app *App) getOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", appJSON)
+	w.Write([]byte(openAPISpec))
+}
+
+// openAPISpec describes every route registered in createRoute. Keep it in sync with
+// routes, TestOpenAPISpecCoversRoutes fails when a registered route is missing.
+const openAPISpec = `{
+  "openapi": "3.0.0",
+  "info": {
+    "title": "Caw API",
+    "version": "1.0.0",
+    "description": "Users, follows and caws of Caw service. Errors are reported as RFC 7807 problem details with stable code field. Every route answers OPTIONS with CORS headers. Authenticated routes answer 403 with code account_suspended once account of token owner is suspended and 401 once it is deleted."
+  },
+  "servers": [{"url": "/"}],
+  "security": [{"bearerAuth": []}],
+  "tags": [
+    {"name": "auth"},
+    {"name": "users"},
+    {"name": "follows"},
+    {"name": "caws"},
+    {"name": "notifications"},
+    {"name": "discovery"},
+    {"name": "realtime"},
+    {"name": "operations"}
+  ],
+  "paths": {
+    "/v1/authentication": {
+      "post": {
+        "tags": ["auth"],
+        "summary": "Authenticate user by name and password",
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Auth"}}}
+        },
+        "responses": {
+          "200": {"description": "Access token", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Token"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "415": {"$ref": "#/components/responses/UnsupportedMediaType"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users": {
+      "post": {
+        "tags": ["users"],
+        "summary": "Register user",
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewUser"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "Registered user",
+            "headers": {"Location": {"$ref": "#/components/headers/Location"}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "415": {"$ref": "#/components/responses/UnsupportedMediaType"},
+          "422": {"$ref": "#/components/responses/ValidationFailed"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/autocomplete": {
+      "get": {
+        "tags": ["discovery"],
+        "summary": "Complete user names by prefix",
+        "description": "Users followed by requestor and users with more followers are returned first.",
+        "parameters": [
+          {"name": "prefix", "in": "query", "required": true, "schema": {"type": "string"}, "description": "Name prefix, leading @ is ignored"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {"description": "Matching users", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/User"}}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["users"],
+        "summary": "Get user",
+        "responses": {
+          "200": {"description": "User", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      },
+      "delete": {
+        "tags": ["users"],
+        "summary": "Delete own account",
+        "description": "Follow relations, caws and notifications of the user are deleted with the account",
+        "responses": {
+          "200": {"description": "User deleted"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/followers": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["follows"],
+        "summary": "List followers of user",
+        "responses": {
+          "200": {"description": "Followers", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Follow"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/followers/{otherID}": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}, {"$ref": "#/components/parameters/otherID"}],
+      "get": {
+        "tags": ["follows"],
+        "summary": "Check whether other user follows user",
+        "responses": {
+          "200": {"description": "Follower", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Follow"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/mutuals": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["follows"],
+        "summary": "List users following user and followed back",
+        "parameters": [{"$ref": "#/components/parameters/page"}],
+        "responses": {
+          "200": {"description": "Mutual follows", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Follow"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/relationship/{otherID}": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}, {"$ref": "#/components/parameters/otherID"}],
+      "get": {
+        "tags": ["follows"],
+        "summary": "Get relationship of user to other user",
+        "responses": {
+          "200": {"description": "Relationship", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Relationship"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/following": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["follows"],
+        "summary": "List users followed by user",
+        "responses": {
+          "200": {"description": "Followed users", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Follow"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      },
+      "post": {
+        "tags": ["follows"],
+        "summary": "Follow user",
+        "description": "Users can follow only on their own behalf. Repeated follow returns existing relation with 200.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Follow"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "User is already followed",
+            "headers": {"Location": {"$ref": "#/components/headers/Location"}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FollowRelation"}}}
+          },
+          "201": {
+            "description": "User followed",
+            "headers": {"Location": {"$ref": "#/components/headers/Location"}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/FollowRelation"}}}
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "422": {"$ref": "#/components/responses/ValidationFailed"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/following/{fid}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/userID"},
+        {"name": "fid", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ObjectID"}, "description": "Followed user id"}
+      ],
+      "delete": {
+        "tags": ["follows"],
+        "summary": "Unfollow user",
+        "responses": {
+          "200": {"description": "User unfollowed"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/caws": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["caws"],
+        "summary": "List caws of user visible to requestor",
+        "parameters": [
+          {"name": "page", "in": "query", "required": true, "schema": {"type": "integer", "minimum": 0}},
+          {"name": "order", "in": "query", "schema": {"type": "string", "enum": ["oldest", "newest"], "default": "oldest"}}
+        ],
+        "responses": {
+          "200": {"description": "Caws", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Caw"}}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      },
+      "post": {
+        "tags": ["caws"],
+        "summary": "Post caw",
+        "description": "Hashtags and mentions are extracted from message.",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewCaw"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "Posted caw",
+            "headers": {"Location": {"$ref": "#/components/headers/Location"}},
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Caw"}}}
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "422": {"$ref": "#/components/responses/ValidationFailed"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/caws/{cawId}": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}, {"$ref": "#/components/parameters/cawId"}],
+      "get": {
+        "tags": ["caws"],
+        "summary": "Get caw",
+        "parameters": [{"$ref": "#/components/parameters/embed"}, {"$ref": "#/components/parameters/ifNoneMatch"}],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Caw"},
+          "304": {"description": "Caw is not modified"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      },
+      "patch": {
+        "tags": ["caws"],
+        "summary": "Edit caw message within edit window",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CawEdit"}}}
+        },
+        "responses": {
+          "200": {"description": "Edited caw", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Caw"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "409": {"$ref": "#/components/responses/Conflict"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "415": {"$ref": "#/components/responses/UnsupportedMediaType"},
+          "422": {"$ref": "#/components/responses/ValidationFailed"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      },
+      "delete": {
+        "tags": ["caws"],
+        "summary": "Delete caw",
+        "responses": {
+          "200": {"description": "Caw deleted"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/caws/{cawId}/history": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}, {"$ref": "#/components/parameters/cawId"}],
+      "get": {
+        "tags": ["caws"],
+        "summary": "List replaced revisions of caw, the oldest first",
+        "responses": {
+          "200": {"description": "Revisions", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/CawRevision"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/mentions": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["caws"],
+        "summary": "List caws mentioning user",
+        "parameters": [{"$ref": "#/components/parameters/page"}],
+        "responses": {
+          "200": {"description": "Caws", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Caw"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/notifications": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["notifications"],
+        "summary": "List notifications of requestor",
+        "parameters": [
+          {"$ref": "#/components/parameters/page"},
+          {"name": "unread", "in": "query", "schema": {"type": "boolean"}, "description": "Return only unread notifications"},
+          {"name": "grouped", "in": "query", "schema": {"type": "boolean"}, "description": "Group similar notifications, page then contains whole groups"}
+        ],
+        "responses": {
+          "200": {"description": "Notifications", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Notifications"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/notifications/read": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "post": {
+        "tags": ["notifications"],
+        "summary": "Mark listed notifications, or all if all is true or body is missing, as read",
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ReadNotifications"}}}
+        },
+        "responses": {
+          "204": {"description": "Notifications marked as read"},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "413": {"$ref": "#/components/responses/PayloadTooLarge"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/notifications/{notificationID}/read": {
+      "parameters": [
+        {"$ref": "#/components/parameters/userID"},
+        {"name": "notificationID", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ObjectID"}}
+      ],
+      "post": {
+        "tags": ["notifications"],
+        "summary": "Mark notification as read",
+        "responses": {
+          "204": {"description": "Notification marked as read, also when it was read before"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/users/{userID}/suggestions": {
+      "parameters": [{"$ref": "#/components/parameters/userID"}],
+      "get": {
+        "tags": ["discovery"],
+        "summary": "List accounts recommended to requestor",
+        "responses": {
+          "200": {"description": "Suggestions", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Suggestion"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/caws/{cawId}": {
+      "parameters": [{"$ref": "#/components/parameters/cawId"}],
+      "get": {
+        "tags": ["caws"],
+        "summary": "Get caw",
+        "parameters": [{"$ref": "#/components/parameters/embed"}, {"$ref": "#/components/parameters/ifNoneMatch"}],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Caw"},
+          "304": {"description": "Caw is not modified"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/hashtags/{tag}/caws": {
+      "parameters": [{"name": "tag", "in": "path", "required": true, "schema": {"type": "string"}, "description": "Hashtag with or without #, matched case insensitively"}],
+      "get": {
+        "tags": ["discovery"],
+        "summary": "List public caws with hashtag",
+        "parameters": [{"$ref": "#/components/parameters/page"}],
+        "responses": {
+          "200": {"description": "Caws", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Caw"}}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/trends": {
+      "get": {
+        "tags": ["discovery"],
+        "summary": "List hashtags trending in time window",
+        "parameters": [
+          {"name": "window", "in": "query", "schema": {"type": "string", "example": "1h"}, "description": "Configured window, the first one by default"},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+        ],
+        "responses": {
+          "200": {"description": "Trends", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Trends"}}}},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/NotFound"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/search": {
+      "get": {
+        "tags": ["discovery"],
+        "summary": "Search public caws or users",
+        "parameters": [
+          {"name": "q", "in": "query", "required": true, "schema": {"type": "string"}, "description": "Words to search, quoted words are searched as phrase"},
+          {"name": "type", "in": "query", "schema": {"type": "string", "enum": ["caws", "users"], "default": "caws"}},
+          {"name": "author", "in": "query", "schema": {"$ref": "#/components/schemas/ObjectID"}, "description": "Author of caws"},
+          {"name": "from", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+          {"name": "to", "in": "query", "schema": {"type": "string", "format": "date-time"}},
+          {"$ref": "#/components/parameters/page"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Caws or users depending on type",
+            "content": {"application/json": {"schema": {"oneOf": [
+              {"type": "array", "items": {"$ref": "#/components/schemas/Caw"}},
+              {"type": "array", "items": {"$ref": "#/components/schemas/User"}}
+            ]}}}
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "406": {"$ref": "#/components/responses/NotAcceptable"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/stream": {
+      "get": {
+        "tags": ["realtime"],
+        "summary": "Stream timeline caws, notifications and follow events as Server-Sent Events",
+        "parameters": [{"name": "Last-Event-ID", "in": "header", "schema": {"type": "string"}, "description": "Resume stream after event, reset event is sent first if the event is no longer kept"}],
+        "responses": {
+          "200": {"description": "Event stream", "content": {"text/event-stream": {"schema": {"type": "string"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/v1/ws": {
+      "get": {
+        "tags": ["realtime"],
+        "summary": "Open WebSocket connection",
+        "description": "Client authenticates with auth message carrying access token, then subscribes to topics and posts caws. Messages are SocketMessage objects.",
+        "security": [],
+        "responses": {
+          "101": {"description": "Switching to WebSocket protocol"},
+          "400": {"description": "Request is not a WebSocket handshake"}
+        }
+      }
+    },
+    "/v1/openapi.json": {
+      "get": {
+        "tags": ["operations"],
+        "summary": "Get this OpenAPI document",
+        "security": [],
+        "responses": {
+          "200": {"description": "OpenAPI document", "content": {"application/json": {"schema": {"type": "object"}}}}
+        }
+      }
+    },
+    "/debug/vars": {
+      "get": {
+        "tags": ["operations"],
+        "summary": "Get runtime and reconciliation metrics",
+        "description": "Metrics are served only to users configured as operators",
+        "responses": {
+          "200": {"description": "Published expvar variables", "content": {"application/json": {"schema": {"type": "object"}}}},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT", "description": "Access token returned by /v1/authentication"}
+    },
+    "headers": {
+      "Location": {"description": "URI of the resource", "schema": {"type": "string"}}
+    },
+    "parameters": {
+      "userID": {"name": "userID", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ObjectID"}},
+      "otherID": {"name": "otherID", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ObjectID"}},
+      "cawId": {"name": "cawId", "in": "path", "required": true, "schema": {"$ref": "#/components/schemas/ObjectID"}},
+      "page": {"name": "page", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}},
+      "embed": {"name": "embed", "in": "query", "schema": {"type": "string", "enum": ["author"]}, "description": "Embed public profile of author"},
+      "ifNoneMatch": {"name": "If-None-Match", "in": "header", "schema": {"type": "string"}, "description": "Entity tags of cached caw"}
+    },
+    "responses": {
+      "Caw": {
+        "description": "Caw",
+        "headers": {"ETag": {"description": "Entity tag of caw", "schema": {"type": "string"}}},
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CawWithAuthor"}}}
+      },
+      "BadRequest": {"description": "Payload or parameter is invalid", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "Unauthorized": {"description": "Request is not authenticated or caller does not own the resource", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "Forbidden": {"description": "Resource cannot be accessed or modified", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "NotFound": {"description": "Resource does not exist", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "NotAcceptable": {"description": "Accept header does not allow application/json", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "Conflict": {"description": "Resource already exists or was modified concurrently", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "PayloadTooLarge": {"description": "Request body is too large", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "UnsupportedMediaType": {"description": "Content-Type is not application/json", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "ValidationFailed": {"description": "Payload violates validation rules", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}},
+      "InternalError": {"description": "Unexpected server error", "content": {"application/problem+json": {"schema": {"$ref": "#/components/schemas/Problem"}}}}
+    },
+    "schemas": {
+      "ObjectID": {"type": "string", "pattern": "^[0-9a-f]{24}$"},
+      "Auth": {
+        "type": "object",
+        "required": ["name", "password"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string"},
+          "password": {"type": "string", "format": "password"}
+        }
+      },
+      "Token": {
+        "type": "object",
+        "properties": {
+          "access_token": {"type": "string"},
+          "type": {"type": "string", "example": "Bearer"},
+          "expires_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "NewUser": {
+        "type": "object",
+        "required": ["name", "email", "password"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 3, "maxLength": 30},
+          "email": {"type": "string", "format": "email"},
+          "password": {"type": "string", "format": "password", "minLength": 8, "description": "Has to contain a letter and a digit"}
+        }
+      },
+      "User": {
+        "type": "object",
+        "properties": {
+          "id": {"$ref": "#/components/schemas/ObjectID"},
+          "name": {"type": "string"},
+          "email": {"type": "string", "format": "email"},
+          "followers_count": {"type": "integer", "minimum": 0},
+          "following_count": {"type": "integer", "minimum": 0},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Follow": {
+        "type": "object",
+        "required": ["user_id"],
+        "additionalProperties": false,
+        "properties": {
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "name": {"type": "string"}
+        }
+      },
+      "FollowRelation": {
+        "type": "object",
+        "properties": {
+          "follower": {"$ref": "#/components/schemas/Follow"},
+          "following": {"$ref": "#/components/schemas/Follow"}
+        }
+      },
+      "Relationship": {
+        "type": "object",
+        "properties": {
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "other_id": {"$ref": "#/components/schemas/ObjectID"},
+          "following": {"type": "boolean"},
+          "followed_by": {"type": "boolean"},
+          "blocked": {"type": "boolean"},
+          "muted": {"type": "boolean"},
+          "pending": {"type": "boolean"}
+        }
+      },
+      "Mention": {
+        "type": "object",
+        "properties": {
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "name": {"type": "string"}
+        }
+      },
+      "Visibility": {"type": "string", "enum": ["public", "followers", "mentioned"], "default": "public"},
+      "NewCaw": {
+        "type": "object",
+        "required": ["user_id", "message"],
+        "properties": {
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "parent_id": {"$ref": "#/components/schemas/ObjectID"},
+          "message": {"type": "string", "maxLength": 280},
+          "visibility": {"$ref": "#/components/schemas/Visibility"}
+        }
+      },
+      "CawEdit": {
+        "type": "object",
+        "required": ["message"],
+        "properties": {
+          "message": {"type": "string", "maxLength": 280}
+        }
+      },
+      "Caw": {
+        "type": "object",
+        "properties": {
+          "id": {"$ref": "#/components/schemas/ObjectID"},
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "user_name": {"type": "string"},
+          "parent_id": {"$ref": "#/components/schemas/ObjectID"},
+          "message": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "like_count": {"type": "integer"},
+          "recaw_count": {"type": "integer"},
+          "replies_count": {"type": "integer"},
+          "visibility": {"$ref": "#/components/schemas/Visibility"},
+          "mentions": {"type": "array", "items": {"$ref": "#/components/schemas/Mention"}},
+          "hashtags": {"type": "array", "items": {"type": "string"}},
+          "edited_at": {"type": "string", "format": "date-time"},
+          "edit_count": {"type": "integer"}
+        }
+      },
+      "CawWithAuthor": {
+        "allOf": [
+          {"$ref": "#/components/schemas/Caw"},
+          {"type": "object", "properties": {"author": {"$ref": "#/components/schemas/User"}}}
+        ]
+      },
+      "CawRevision": {
+        "type": "object",
+        "properties": {
+          "id": {"$ref": "#/components/schemas/ObjectID"},
+          "caw_id": {"$ref": "#/components/schemas/ObjectID"},
+          "revision": {"type": "integer", "description": "0 for original content"},
+          "message": {"type": "string"},
+          "mentions": {"type": "array", "items": {"$ref": "#/components/schemas/Mention"}},
+          "hashtags": {"type": "array", "items": {"type": "string"}},
+          "created_at": {"type": "string", "format": "date-time"},
+          "replaced_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "Notification": {
+        "type": "object",
+        "properties": {
+          "id": {"$ref": "#/components/schemas/ObjectID"},
+          "user_id": {"$ref": "#/components/schemas/ObjectID"},
+          "type": {"type": "string", "enum": ["follow", "reply", "mention", "like"]},
+          "actor": {"$ref": "#/components/schemas/Follow"},
+          "caw_id": {"$ref": "#/components/schemas/ObjectID"},
+          "read": {"type": "boolean"},
+          "created_at": {"type": "string", "format": "date-time"}
+        }
+      },
+      "NotificationGroup": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string"},
+          "caw_id": {"$ref": "#/components/schemas/ObjectID"},
+          "summary": {"type": "string", "example": "bob and 4 others followed you"},
+          "count": {"type": "integer"},
+          "actors": {"type": "array", "items": {"$ref": "#/components/schemas/Follow"}},
+          "read": {"type": "boolean"},
+          "latest_at": {"type": "string", "format": "date-time"},
+          "notification_ids": {"type": "array", "items": {"$ref": "#/components/schemas/ObjectID"}}
+        }
+      },
+      "Notifications": {
+        "type": "object",
+        "properties": {
+          "unread_count": {"type": "integer"},
+          "notifications": {"type": "array", "items": {"$ref": "#/components/schemas/Notification"}},
+          "groups": {"type": "array", "items": {"$ref": "#/components/schemas/NotificationGroup"}}
+        }
+      },
+      "ReadNotifications": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "ids": {"type": "array", "items": {"$ref": "#/components/schemas/ObjectID"}},
+          "all": {"type": "boolean", "description": "Mark all notifications, cannot be combined with ids"}
+        }
+      },
+      "Suggestion": {
+        "type": "object",
+        "properties": {
+          "user": {"$ref": "#/components/schemas/Follow"},
+          "score": {"type": "integer"},
+          "followed_by": {"type": "array", "items": {"$ref": "#/components/schemas/Follow"}},
+          "reason": {"type": "string", "example": "Followed by bob and 3 others"}
+        }
+      },
+      "Trend": {
+        "type": "object",
+        "properties": {
+          "hashtag": {"type": "string"},
+          "count": {"type": "integer"},
+          "baseline": {"type": "number"},
+          "velocity": {"type": "number"}
+        }
+      },
+      "Trends": {
+        "type": "object",
+        "properties": {
+          "window": {"type": "string"},
+          "computed_at": {"type": "string", "format": "date-time"},
+          "trends": {"type": "array", "items": {"$ref": "#/components/schemas/Trend"}}
+        }
+      },
+      "FieldError": {
+        "type": "object",
+        "properties": {
+          "field": {"type": "string"},
+          "code": {"type": "string", "enum": ["required", "unknown", "invalid", "mismatch", "too_short", "too_long", "weak", "invalid_characters"]},
+          "message": {"type": "string"}
+        }
+      },
+      "Problem": {
+        "type": "object",
+        "required": ["type", "title", "status", "code"],
+        "properties": {
+          "type": {"type": "string", "example": "urn:caw:problem:user_not_found"},
+          "title": {"type": "string"},
+          "status": {"type": "integer"},
+          "detail": {"type": "string"},
+          "instance": {"type": "string"},
+          "code": {"type": "string", "description": "Stable machine readable code of problem"},
+          "errors": {"type": "array", "items": {"$ref": "#/components/schemas/FieldError"}}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func TestGetOpenAPIHandler(t *testing.T) {
+	t.Parallel()
+
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	request := NewTestRequest(t, "GET", uriBuilder.OpenAPI().Done(), nil)
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, appJSON, recorder.Header().Get("Content-Type"))
+	var document openAPIDocument
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&document), "OpenAPI document is not valid JSON")
+	assert.Equal(t, "3.0.0", document.OpenAPI)
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	t.Parallel()
+
+	var document openAPIDocument
+	if err := json.Unmarshal([]byte(openAPISpec), &document); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON. err: %s", err)
+	}
+
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	registered := map[string]bool{}
+	err := app.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			// CORS preflight is answered by every route and described in document info
+			if method == "OPTIONS" {
+				continue
+			}
+			operation := path + " " + strings.ToLower(method)
+			registered[operation] = true
+			if _, ok := document.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("Route %s %s is missing in OpenAPI document", method, path)
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	for path, item := range document.Paths {
+		for method := range item {
+			if method == "parameters" {
+				continue
+			}
+			if !registered[path+" "+method] {
+				t.Errorf("OpenAPI document describes %s %s which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// GET /v1/users/{userID}/followers/{otherID}
+// getUserFollowerHandler handle HTTP GET method and returns follower if user with otherID follows requested user
+func (app *App) getUserFollowerHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	otherID := vars["otherID"]
+
+	ds := app.newUserDataStore()
+	defer ds.Close()
+	relation, err := ds.GetFollowRelation(otherID, userID)
+	if err != nil {
+		if err == infrastructure.ErrNotFound {
+			writeProblem(w, r, http.StatusNotFound, models.CodeFollowNotFound, "User is not a follower")
+			return
+		}
+		app.logger.Errorf("Cannot to get follow relation. userID: %s, otherID: %s, err: %s", userID, otherID, err)
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(relation.Follower)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal follower. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
+
+// GET /v1/users/{userID}/mutuals?page=$
+// getUserMutualsHandler handle HTTP GET method and returns users who follow requested user and are followed back
+func (app *App) getUserMutualsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+
+	ds := app.newUserDataStore()
+	defer ds.Close()
+	mutuals, err := ds.GetMutuals(userID, queryPage(r))
+	if err != nil {
+		app.logger.Errorf("Cannot to get user mutuals. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
+		return
+	}
+
+	js, err := json.Marshal(mutuals)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal mutuals. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
+
+// GET /v1/users/{userID}/relationship/{otherID}
+// getUserRelationshipHandler handle HTTP GET method and returns relationship of requestor to other user
+func (app *App) getUserRelationshipHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID := vars["userID"]
+	otherID := vars["otherID"]
+	if !app.isRequestor(w, r, userID) {
+		return
+	}
+
+	ds := app.newUserDataStore()
+	defer ds.Close()
+	other, err := ds.GetUser(otherID)
+	if err != nil {
+		app.logger.Errorf("Cannot to get user. userID: %s, err: %s", otherID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
+		return
+	}
+
+	if !bson.IsObjectIdHex(userID) {
+		writeProblem(w, r, http.StatusNotFound, models.CodeUserNotFound, notFoundDetails[models.CodeUserNotFound])
+		return
+	}
+	relationship := models.Relationship{UserID: bson.ObjectIdHex(userID), OtherID: other.ID}
+	if relationship.Following, err = app.isFollowing(ds, userID, otherID); err != nil {
+		writeInternalError(w, r)
+		return
+	}
+	if relationship.FollowedBy, err = app.isFollowing(ds, otherID, userID); err != nil {
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(relationship)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal relationship. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
+
+// isFollowing returns true if user with followerID follows user with followingID
+func (app *App) isFollowing(ds infrastructure.UserDataStore, followerID, followingID string) (bool, error) {
+	_, err := ds.GetFollowRelation(followerID, followingID)
+	if err == infrastructure.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		app.logger.Errorf("Cannot to get follow relation. followerID: %s, followingID: %s, err: %s", followerID, followingID, err)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// followGraphMock returns UserDataStoreMock answering follow relation queries from follows
+func followGraphMock(follows map[string]bool, err error) UserDataStoreMock {
+	return UserDataStoreMock{
+		OnGetUser: func(userID string) (*models.User, error) {
+			if !bson.IsObjectIdHex(userID) {
+				return nil, infrastructure.ErrNotFound
+			}
+			return &models.User{ID: bson.ObjectIdHex(userID), Name: "user"}, nil
+		},
+		OnGetFollowRelation: func(followerID, followingID string) (*models.FollowRelation, error) {
+			if err != nil {
+				return nil, err
+			}
+			if !follows[followerID+"->"+followingID] {
+				return nil, infrastructure.ErrNotFound
+			}
+			return &models.FollowRelation{
+				Follower:  models.Follow{UserID: bson.ObjectIdHex(followerID), Name: "follower"},
+				Following: models.Follow{UserID: bson.ObjectIdHex(followingID), Name: "following"},
+			}, nil
+		},
+	}
+}
+
+func TestGetUserRelationshipHandler(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	fanID := bson.NewObjectId().Hex()
+	mutualID := bson.NewObjectId().Hex()
+	follows := map[string]bool{
+		fanID + "->" + userID:    true,
+		userID + "->" + mutualID: true,
+		mutualID + "->" + userID: true,
+	}
+
+	var testCases = []struct {
+		Name                 string
+		OtherID              string
+		RequestorID          string
+		DataStoreError       error
+		ExpectedStatusCode   int
+		ExpectedRelationship models.Relationship
+	}{
+		{
+			Name:               "FollowedByTest",
+			OtherID:            fanID,
+			RequestorID:        userID,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedRelationship: models.Relationship{
+				UserID: bson.ObjectIdHex(userID), OtherID: bson.ObjectIdHex(fanID), FollowedBy: true},
+		},
+		{
+			Name:               "MutualTest",
+			OtherID:            mutualID,
+			RequestorID:        userID,
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedRelationship: models.Relationship{
+				UserID: bson.ObjectIdHex(userID), OtherID: bson.ObjectIdHex(mutualID), Following: true, FollowedBy: true},
+		},
+		{
+			Name:               "OtherUserDoesNotExistTest",
+			OtherID:            "1",
+			RequestorID:        userID,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "RelationshipOfOtherUserTest",
+			OtherID:            fanID,
+			RequestorID:        fanID,
+			ExpectedStatusCode: http.StatusForbidden,
+		},
+		{
+			Name:               "StorageInternalErrorTest",
+			OtherID:            fanID,
+			RequestorID:        userID,
+			DataStoreError:     errors.New("StorageInternalError"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		app := createApp(followGraphMock(follows, testCase.DataStoreError), CawDataStoreMock{})
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID).Relationship().WithRelationship(testCase.OtherID).Done(), nil).
+			WithAuthorization(testCase.RequestorID)
+		request.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var relationship models.Relationship
+			err := json.NewDecoder(recorder.Body).Decode(&relationship)
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.ExpectedRelationship, relationship)
+		}
+		app.Close()
+	}
+}
+
+func TestGetUserFollowerHandler(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	fanID := bson.NewObjectId().Hex()
+	follows := map[string]bool{fanID + "->" + userID: true}
+
+	var testCases = []struct {
+		Name               string
+		OtherID            string
+		DataStoreError     error
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "FollowerTest",
+			OtherID:            fanID,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "NotFollowerTest",
+			OtherID:            bson.NewObjectId().Hex(),
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "StorageInternalErrorTest",
+			OtherID:            fanID,
+			DataStoreError:     errors.New("StorageInternalError"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		app := createApp(followGraphMock(follows, testCase.DataStoreError), CawDataStoreMock{})
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID).Followers().WithFollowers(testCase.OtherID).Done(), nil).
+			WithAuthorization(userID)
+		request.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var follower models.Follow
+			err := json.NewDecoder(recorder.Body).Decode(&follower)
+			assert.Nil(t, err)
+			assert.Equal(t, fanID, follower.UserID.Hex())
+		}
+		app.Close()
+	}
+}
+
+func TestGetUserMutualsHandler(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	mutual := models.Follow{UserID: bson.NewObjectId(), Name: "mutual"}
+
+	var testCases = []struct {
+		Name               string
+		Page               string
+		DataStoreError     error
+		ExpectedStatusCode int
+		ExpectedPage       int
+	}{
+		{
+			Name:               "MutualsTest",
+			Page:               "?page=2",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedPage:       2,
+		},
+		{
+			Name:               "UserDoesNotExistTest",
+			DataStoreError:     infrastructure.ErrNotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		userDataStoreMock := UserDataStoreMock{
+			OnGetMutuals: func(id string, page int) ([]models.Follow, error) {
+				assert.Equal(t, testCase.ExpectedPage, page)
+				return []models.Follow{mutual}, testCase.DataStoreError
+			},
+		}
+		app := createApp(userDataStoreMock, CawDataStoreMock{})
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(userID).Mutuals().Done()+testCase.Page, nil).
+			WithAuthorization(userID)
+		request.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var mutuals []models.Follow
+			err := json.NewDecoder(recorder.Body).Decode(&mutuals)
+			assert.Nil(t, err)
+			assert.Equal(t, []models.Follow{mutual}, mutuals)
+		}
+		app.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/search"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	searchTypeCaws  = "caws"
+	searchTypeUsers = "users"
+)
+
+// GET /v1/search?q=$&type=caws|users&author=$&from=$&to=$&page=$
+// getSearchHandler handle HTTP GET method and returns public caws or users matching query.
+// Quoted words are searched as phrase, author, from and to narrow caw results.
+func (app *App) getSearchHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+	q := vals.Get("q")
+	page := queryPage(r)
+
+	var result interface{}
+	var err error
+	switch vals.Get("type") {
+	case "", searchTypeCaws:
+		filter, filterErrors := cawFilter(vals)
+		if len(filterErrors) > 0 {
+			app.logger.Errorf("Invalid search filter. err: %v", filterErrors)
+			writeParametersProblem(w, r, filterErrors)
+			return
+		}
+		result, err = app.search.SearchCaws(q, filter, page)
+	case searchTypeUsers:
+		var users []models.User
+		users, err = app.search.SearchUsers(q, page)
+		publicUsers := []models.PublicUser{}
+		for _, user := range users {
+			publicUsers = append(publicUsers, user.ToPublic())
+		}
+		result = publicUsers
+	default:
+		writeParameterProblem(w, r, "type", "Type has to be one of: caws, users")
+		return
+	}
+
+	if err != nil {
+		app.logger.Errorf("Cannot search. q: %s, err: %s", q, err)
+		if err == search.ErrEmptyQuery {
+			writeParameterProblem(w, r, "q", err.Error())
+			return
+		}
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal search result. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
+
+func cawFilter(vals url.Values) (search.CawFilter, []models.FieldError) {
+	filter := search.CawFilter{AuthorID: vals.Get("author")}
+	var errors []models.FieldError
+	var err error
+	if from := vals.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			errors = append(errors, models.FieldError{Field: "from", Code: models.FieldInvalid, Message: "From has to be RFC3339 date"})
+		}
+	}
+	if to := vals.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			errors = append(errors, models.FieldError{Field: "to", Code: models.FieldInvalid, Message: "To has to be RFC3339 date"})
+		}
+	}
+	return filter, errors
+}
+
+// LoadSearchIndex fills search index with users and public caws kept in data stores
+func (app *App) LoadSearchIndex() {
+	userDataStore := app.newUserDataStore()
+	defer userDataStore.Close()
+	err := userDataStore.ForEachUser(func(user models.User) error {
+		app.search.IndexUser(user)
+		app.names.Put(user)
+		return nil
+	})
+	if err != nil {
+		app.logger.Errorf("Cannot index users. err: %s", err)
+	}
+
+	cawDataStore := app.newCawDataStore()
+	defer cawDataStore.Close()
+	err = cawDataStore.ForEachPublic(func(caw models.Caw) error {
+		app.search.IndexCaw(caw)
+		return nil
+	})
+	if err != nil {
+		app.logger.Errorf("Cannot index caws. err: %s", err)
+	}
+}
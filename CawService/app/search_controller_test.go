@@ -0,0 +1,106 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGetSearchHandler(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId()
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	defer app.Close()
+	app.search.IndexUser(models.User{ID: userID, Name: "anycmon", Password: "secret"})
+	app.search.IndexCaw(models.Caw{ID: bson.NewObjectId(), UserID: userID, Message: "hello world", CreatedAt: time.Now()})
+
+	var testCases = []struct {
+		Name               string
+		Query              string
+		ExpectedStatusCode int
+		ExpectedCount      int
+	}{
+		{
+			Name:               "SearchCawsTest",
+			Query:              "?q=hello",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedCount:      1,
+		},
+		{
+			Name:               "SearchCawsByOtherAuthorTest",
+			Query:              "?q=hello&author=" + bson.NewObjectId().Hex(),
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedCount:      0,
+		},
+		{
+			Name:               "SearchUsersTest",
+			Query:              "?q=any&type=users",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedCount:      1,
+		},
+		{
+			Name:               "EmptyQueryTest",
+			Query:              "?q=",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "UnsupportedTypeTest",
+			Query:              "?q=hello&type=hashtags",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "InvalidDateTest",
+			Query:              "?q=hello&from=yesterday",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		request := NewTestRequest(t, "GET", uriBuilder.Search().Done()+testCase.Query, nil).
+			WithAuthorization(userID.Hex())
+		request.Header.Set("Accept", "application/json")
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var result []map[string]interface{}
+			err := json.NewDecoder(recorder.Body).Decode(&result)
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.ExpectedCount, len(result))
+			for _, item := range result {
+				assert.Nil(t, item["password"], "password cannot be returned")
+			}
+		}
+	}
+}
+
+func TestPostUserIndexesUser(t *testing.T) {
+	t.Parallel()
+	userDataStoreMock := UserDataStoreMock{
+		OnStoreUser: func(user models.User) (*models.User, error) {
+			user.ID = bson.NewObjectId()
+			return &user, nil
+		},
+	}
+	app := createApp(userDataStoreMock, CawDataStoreMock{})
+	defer app.Close()
+
+	user := models.User{Name: "anycmon", Email: "anycmon@gmail.com", Password: "foobar12"}
+	request := NewTestRequest(t, "POST", uriBuilder.User().Done(), marshalUser(user, t))
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+
+	users, err := app.search.SearchUsers("anyc", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(users))
+}
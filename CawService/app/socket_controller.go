@@ -0,0 +1,436 @@
+package app
+
+import (
+	"Caw/UserService/events"
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/middleware"
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anycmon/throttle"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	socketAuthTimeout    = 10 * time.Second
+	socketPongWait       = 60 * time.Second
+	socketPingPeriod     = 30 * time.Second
+	socketWriteWait      = 10 * time.Second
+	socketMaxMessageSize = 16 * 1024
+	socketBufferSize     = 64
+	// socketCloseTokenExpired is close code sent when access token of connection expires
+	socketCloseTokenExpired = 4001
+	// defaultSocketMessagesPerSecond is used when rate limit is not configured
+	defaultSocketMessagesPerSecond = 5
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// socketConn is the part of websocket.Conn used by socketSession
+type socketConn interface {
+	ReadJSON(v interface{}) error
+	WriteJSON(v interface{}) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetReadLimit(limit int64)
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// GET /v1/ws
+// getSocketHandler upgrades connection to WebSocket. Client has to send auth message
+// with access token first, then it can subscribe to topics and post caws.
+func (app *App) getSocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		app.logger.Errorf("Cannot upgrade connection to WebSocket. err: %s", err)
+		return
+	}
+	app.serveSocket(conn)
+}
+
+// socketSession represents authenticated WebSocket connection. Messages are read
+// by serving goroutine and written by writeLoop, subscriptions are forwarded
+// to writeLoop by their own goroutines.
+type socketSession struct {
+	app           *App
+	conn          socketConn
+	throttle      throttle.Throttle
+	userID        string
+	claims        *utils.UserClaims
+	subscriptions map[string]*events.Subscription
+	out           chan models.SocketMessage
+	expiry        chan int64
+	done          chan struct{}
+}
+
+func (app *App) serveSocket(conn socketConn) {
+	defer conn.Close()
+
+	messagesPerSecond := app.socketMessagesPerSecond
+	if messagesPerSecond <= 0 {
+		messagesPerSecond = defaultSocketMessagesPerSecond
+	}
+	session := &socketSession{
+		app:           app,
+		conn:          conn,
+		throttle:      rate.NewLimiter(rate.Limit(middleware.Per(messagesPerSecond, time.Second)), messagesPerSecond),
+		subscriptions: map[string]*events.Subscription{},
+		out:           make(chan models.SocketMessage, socketBufferSize),
+		expiry:        make(chan int64, 1),
+		done:          make(chan struct{}),
+	}
+
+	conn.SetReadLimit(socketMaxMessageSize)
+	claims, err := session.authenticate()
+	if err != nil {
+		app.logger.Errorf("Cannot authenticate WebSocket client. err: %s", err)
+		conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		conn.WriteJSON(models.SocketMessage{Type: models.SocketError, Message: err.Error()})
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()),
+			time.Now().Add(socketWriteWait))
+		return
+	}
+	session.userID = claims.UserId
+	session.claims = claims
+
+	conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+
+	writerDone := make(chan struct{})
+	go func() {
+		session.writeLoop()
+		close(writerDone)
+	}()
+
+	session.expiry <- claims.ExpiresAt
+	session.sendAuthenticated()
+	session.readLoop()
+
+	close(session.done)
+	for _, subscription := range session.subscriptions {
+		subscription.Close()
+	}
+	<-writerDone
+}
+
+// authenticate reads auth message which has to be sent first
+func (s *socketSession) authenticate() (*utils.UserClaims, error) {
+	s.conn.SetReadDeadline(time.Now().Add(socketAuthTimeout))
+	var msg models.SocketMessage
+	if err := s.conn.ReadJSON(&msg); err != nil {
+		return nil, err
+	}
+	if msg.Type != models.SocketAuth {
+		return nil, errors.New("First message has to be auth")
+	}
+	claims, err := decodeSocketToken(msg.Token, s.app.tenant)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.app.activeAccount(claims.UserId); err == infrastructure.ErrNotFound {
+		return nil, errors.New("Invalid token")
+	} else if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeSocketToken(token string, tenant string) (*utils.UserClaims, error) {
+	claims, err := utils.DecodeUserToken(token)
+	if err != nil {
+		return nil, errors.New("Invalid token")
+	}
+	if err = claims.Valid(); err != nil || claims.Tenant() != tenant {
+		return nil, errors.New("Invalid token")
+	}
+	return claims, nil
+}
+
+func (s *socketSession) readLoop() {
+	for {
+		var msg models.SocketMessage
+		if err := s.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				s.app.logger.Errorf("Cannot read WebSocket message. userID: %s, err: %s", s.userID, err)
+			}
+			return
+		}
+
+		if !s.throttle.Allow() {
+			s.sendError(msg, "Rate limit exceeded")
+			continue
+		}
+
+		switch msg.Type {
+		case models.SocketAuth:
+			s.reauthenticate(msg)
+		case models.SocketSubscribe:
+			s.subscribe(msg)
+		case models.SocketUnsubscribe:
+			s.unsubscribe(msg)
+		case models.SocketPost:
+			s.post(msg)
+		default:
+			s.sendError(msg, "Unknown message type")
+		}
+	}
+}
+
+// writeLoop writes queued messages and pings, and closes connection when token expires
+func (s *socketSession) writeLoop() {
+	defer s.conn.Close()
+
+	ping := time.NewTicker(socketPingPeriod)
+	defer ping.Stop()
+
+	var expiryTimer *time.Timer
+	var expired <-chan time.Time
+	defer func() {
+		if expiryTimer != nil {
+			expiryTimer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-s.out:
+			s.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			if err := s.conn.WriteJSON(msg); err != nil {
+				s.app.logger.Errorf("Cannot write WebSocket message. userID: %s, err: %s", s.userID, err)
+				return
+			}
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait)); err != nil {
+				s.app.logger.Errorf("Cannot ping WebSocket client. userID: %s, err: %s", s.userID, err)
+				return
+			}
+		case expiresAt := <-s.expiry:
+			if expiryTimer != nil {
+				expiryTimer.Stop()
+				expired = nil
+			}
+			if expiresAt > 0 {
+				expiryTimer = time.NewTimer(time.Unix(expiresAt, 0).Sub(time.Now()))
+				expired = expiryTimer.C
+			}
+		case <-expired:
+			s.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(socketCloseTokenExpired, "Token expired"),
+				time.Now().Add(socketWriteWait))
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// send queues msg for writeLoop, it returns false when session is closed
+func (s *socketSession) send(msg models.SocketMessage) bool {
+	select {
+	case s.out <- msg:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *socketSession) sendError(request models.SocketMessage, message string) {
+	s.send(models.SocketMessage{
+		Type:    models.SocketError,
+		Topic:   request.Topic,
+		ID:      request.ID,
+		Message: message,
+	})
+}
+
+func (s *socketSession) sendAuthenticated() {
+	msg := models.SocketMessage{Type: models.SocketAuthenticated, ID: s.userID}
+	if s.claims.ExpiresAt > 0 {
+		expiresAt := time.Unix(s.claims.ExpiresAt, 0)
+		msg.ExpiresAt = &expiresAt
+	}
+	s.send(msg)
+}
+
+// reauthenticate extends session with new token of the same user
+func (s *socketSession) reauthenticate(msg models.SocketMessage) {
+	claims, err := decodeSocketToken(msg.Token, s.app.tenant)
+	if err != nil {
+		s.sendError(msg, err.Error())
+		return
+	}
+	if claims.UserId != s.userID {
+		s.sendError(msg, "Token has to belong to authenticated user")
+		return
+	}
+	if err = s.app.activeAccount(claims.UserId); err == errAccountSuspended {
+		s.sendError(msg, err.Error())
+		return
+	} else if err != nil {
+		s.sendError(msg, "Invalid token")
+		return
+	}
+
+	s.claims = claims
+	select {
+	case s.expiry <- claims.ExpiresAt:
+	case <-s.done:
+		return
+	}
+	s.sendAuthenticated()
+}
+
+// topicKey returns Broker recipient key of topic requested by msg
+func (s *socketSession) topicKey(msg models.SocketMessage) (string, error) {
+	switch msg.Topic {
+	case models.TopicTimeline:
+		return s.userID, nil
+	case models.TopicHashtag:
+		hashtag := models.NormalizeHashtag(msg.ID)
+		if hashtag == "" {
+			return "", errors.New("Hashtag cannot be empty")
+		}
+		return events.HashtagTopic(hashtag), nil
+	case models.TopicReplies:
+		if !bson.IsObjectIdHex(msg.ID) {
+			return "", errors.New("Caw does not exist")
+		}
+		cawDataStore := s.app.newCawDataStore()
+		defer cawDataStore.Close()
+		if _, err := cawDataStore.GetByID(msg.ID, s.userID); err != nil {
+			if err == infrastructure.ErrNotFound {
+				return "", errors.New("Caw does not exist")
+			}
+			s.app.logger.Errorf("Cannot get caw. cawID: %s, err: %s", msg.ID, err)
+			return "", errors.New(http.StatusText(http.StatusInternalServerError))
+		}
+		return events.RepliesTopic(msg.ID), nil
+	}
+	return "", errors.New("Topic has to be one of: timeline, hashtag, replies")
+}
+
+// subscribe forwards events of requested topic to client. Events published after
+// EventID of msg are replayed if it is set.
+func (s *socketSession) subscribe(msg models.SocketMessage) {
+	key, err := s.topicKey(msg)
+	if err != nil {
+		s.sendError(msg, err.Error())
+		return
+	}
+
+	acknowledgement := models.SocketMessage{Type: models.SocketSubscribed, Topic: msg.Topic, ID: msg.ID}
+	if subscription, ok := s.subscriptions[key]; ok {
+		select {
+		case <-subscription.Done():
+			// closed by Broker, subscribe again
+		default:
+			s.send(acknowledgement)
+			return
+		}
+	}
+
+	subscription, missed := s.app.events.Subscribe(key, msg.EventID)
+	if msg.EventID == 0 {
+		missed = nil
+	}
+	s.subscriptions[key] = subscription
+	s.send(acknowledgement)
+	go s.forward(msg.Topic, msg.ID, subscription, missed)
+}
+
+func (s *socketSession) unsubscribe(msg models.SocketMessage) {
+	key, err := s.topicKey(msg)
+	if err != nil {
+		s.sendError(msg, err.Error())
+		return
+	}
+
+	subscription, ok := s.subscriptions[key]
+	if !ok {
+		s.sendError(msg, "Topic is not subscribed")
+		return
+	}
+	delete(s.subscriptions, key)
+	// unsubscribed message is sent by forward when subscription is done
+	subscription.Close()
+}
+
+func (s *socketSession) forward(topic, id string, subscription *events.Subscription, missed []events.Event) {
+	eventMessage := func(event events.Event) models.SocketMessage {
+		return models.SocketMessage{
+			Type:    models.SocketEvent,
+			Topic:   topic,
+			ID:      id,
+			Event:   event.Type,
+			EventID: event.ID,
+			Data:    event.Data,
+		}
+	}
+
+	for _, event := range missed {
+		if !s.send(eventMessage(event)) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			if !s.send(eventMessage(event)) {
+				return
+			}
+		case <-subscription.Done():
+			s.send(models.SocketMessage{Type: models.SocketUnsubscribed, Topic: topic, ID: id})
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// post stores caw sent by client on behalf of authenticated user
+func (s *socketSession) post(msg models.SocketMessage) {
+	if msg.Caw == nil {
+		s.sendError(msg, "Caw is required")
+		return
+	}
+
+	caw := *msg.Caw
+	userID := s.userID
+	if caw.UserID != "" && caw.UserID.Hex() != userID {
+		s.sendError(msg, "Caw has to be posted by authenticated user")
+		return
+	}
+	caw.UserID = bson.ObjectIdHex(userID)
+
+	storedCaw, err := s.app.storeCaw(userID, caw)
+	if err != nil {
+		if problem, ok := err.(models.Problem); ok {
+			if len(problem.Errors) > 0 {
+				s.sendError(msg, problem.Errors[0].Message)
+				return
+			}
+			s.sendError(msg, problem.Detail)
+			return
+		}
+		s.sendError(msg, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	s.send(models.SocketMessage{Type: models.SocketPosted, ID: storedCaw.ID.Hex(), Caw: storedCaw})
+}
@@ -0,0 +1,367 @@
+package app
+
+import (
+	"Caw/UserService/events"
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type SocketConnMock struct {
+	in        chan models.SocketMessage
+	out       chan models.SocketMessage
+	closeCode chan int
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewSocketConnMock() *SocketConnMock {
+	return &SocketConnMock{
+		in:        make(chan models.SocketMessage),
+		out:       make(chan models.SocketMessage, socketBufferSize),
+		closeCode: make(chan int, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+func (m *SocketConnMock) ReadJSON(v interface{}) error {
+	select {
+	case msg := <-m.in:
+		*v.(*models.SocketMessage) = msg
+		return nil
+	case <-m.closed:
+		return errors.New("connection closed")
+	}
+}
+
+func (m *SocketConnMock) WriteJSON(v interface{}) error {
+	select {
+	case m.out <- v.(models.SocketMessage):
+		return nil
+	case <-m.closed:
+		return errors.New("connection closed")
+	}
+}
+
+func (m *SocketConnMock) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType == websocket.CloseMessage && len(data) >= 2 {
+		m.closeCode <- int(data[0])<<8 | int(data[1])
+	}
+	return nil
+}
+
+func (m *SocketConnMock) SetReadDeadline(t time.Time) error           { return nil }
+func (m *SocketConnMock) SetWriteDeadline(t time.Time) error          { return nil }
+func (m *SocketConnMock) SetReadLimit(limit int64)                    {}
+func (m *SocketConnMock) SetPongHandler(h func(appData string) error) {}
+
+func (m *SocketConnMock) Close() error {
+	m.closeOnce.Do(func() { close(m.closed) })
+	return nil
+}
+
+func (m *SocketConnMock) Send(t *testing.T, msg models.SocketMessage) {
+	select {
+	case m.in <- msg:
+	case <-time.After(time.Second):
+		t.Fatalf("Cannot send message %v", msg)
+	}
+}
+
+func (m *SocketConnMock) Receive(t *testing.T) models.SocketMessage {
+	select {
+	case msg := <-m.out:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Message was not received")
+	}
+	return models.SocketMessage{}
+}
+
+func newSocketToken(t *testing.T, userID string, expiresIn time.Duration) string {
+	token, err := utils.NewUserToken(userID, time.Now().Add(expiresIn).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serveSocketMock serves conn by app and returns channel closed when serving is finished
+func serveSocketMock(app *App, conn *SocketConnMock) <-chan struct{} {
+	finished := make(chan struct{})
+	go func() {
+		app.serveSocket(conn)
+		close(finished)
+	}()
+	return finished
+}
+
+func connectSocketMock(t *testing.T, app *App, userID string) (*SocketConnMock, <-chan struct{}) {
+	conn := NewSocketConnMock()
+	finished := serveSocketMock(app, conn)
+	conn.Send(t, models.SocketMessage{Type: models.SocketAuth, Token: newSocketToken(t, userID, time.Minute)})
+	msg := conn.Receive(t)
+	assert.Equal(t, models.SocketAuthenticated, msg.Type)
+	assert.Equal(t, userID, msg.ID)
+	return conn, finished
+}
+
+func TestSocketAuthentication(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	var testCases = []struct {
+		Name            string
+		Message         models.SocketMessage
+		ExpectedMessage models.SocketMessage
+	}{
+		{
+			Name:            "AuthenticatedTest",
+			Message:         models.SocketMessage{Type: models.SocketAuth, Token: newSocketToken(t, userID, time.Minute)},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketAuthenticated, ID: userID},
+		},
+		{
+			Name:            "InvalidTokenTest",
+			Message:         models.SocketMessage{Type: models.SocketAuth, Token: "invalid"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Message: "Invalid token"},
+		},
+		{
+			Name:            "ExpiredTokenTest",
+			Message:         models.SocketMessage{Type: models.SocketAuth, Token: newSocketToken(t, userID, -time.Minute)},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Message: "Invalid token"},
+		},
+		{
+			Name:            "FirstMessageIsNotAuthTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: models.TopicTimeline},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Message: "First message has to be auth"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+		conn := NewSocketConnMock()
+		finished := serveSocketMock(app, conn)
+
+		conn.Send(t, testCase.Message)
+		msg := conn.Receive(t)
+		msg.ExpiresAt = nil
+		assert.Equal(t, testCase.ExpectedMessage, msg)
+
+		if testCase.ExpectedMessage.Type == models.SocketError {
+			assert.Equal(t, websocket.ClosePolicyViolation, <-conn.closeCode)
+			<-finished
+		} else {
+			conn.Close()
+			<-finished
+		}
+		app.Close()
+	}
+}
+
+func TestSocketSubscriptions(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	cawID := bson.NewObjectId().Hex()
+	cawDataStoreMock := CawDataStoreMock{
+		OnGetByID: func(id, viewerID string) (*models.Caw, error) {
+			if id != cawID {
+				return nil, infrastructure.ErrNotFound
+			}
+			return &models.Caw{ID: bson.ObjectIdHex(id)}, nil
+		},
+	}
+	app := createApp(UserDataStoreMock{}, cawDataStoreMock)
+	app.socketMessagesPerSecond = 100
+	defer app.Close()
+	conn, finished := connectSocketMock(t, app, userID)
+
+	var testCases = []struct {
+		Name            string
+		Message         models.SocketMessage
+		ExpectedMessage models.SocketMessage
+		PublishTo       string
+	}{
+		{
+			Name:            "SubscribeTimelineTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: models.TopicTimeline},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketSubscribed, Topic: models.TopicTimeline},
+			PublishTo:       userID,
+		},
+		{
+			Name:            "SubscribeHashtagTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: models.TopicHashtag, ID: "#GoLang"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketSubscribed, Topic: models.TopicHashtag, ID: "#GoLang"},
+			PublishTo:       events.HashtagTopic("golang"),
+		},
+		{
+			Name:            "SubscribeRepliesTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: models.TopicReplies, ID: cawID},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketSubscribed, Topic: models.TopicReplies, ID: cawID},
+			PublishTo:       events.RepliesTopic(cawID),
+		},
+		{
+			Name:            "SubscribeRepliesOfNotExistingCawTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: models.TopicReplies, ID: bson.NewObjectId().Hex()},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Topic: models.TopicReplies, Message: "Caw does not exist"},
+		},
+		{
+			Name:            "SubscribeUnknownTopicTest",
+			Message:         models.SocketMessage{Type: models.SocketSubscribe, Topic: "unknown"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Topic: "unknown", Message: "Topic has to be one of: timeline, hashtag, replies"},
+		},
+		{
+			Name:            "UnsubscribeHashtagTest",
+			Message:         models.SocketMessage{Type: models.SocketUnsubscribe, Topic: models.TopicHashtag, ID: "#GoLang"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketUnsubscribed, Topic: models.TopicHashtag, ID: "#GoLang"},
+		},
+		{
+			Name:            "UnsubscribeNotSubscribedTopicTest",
+			Message:         models.SocketMessage{Type: models.SocketUnsubscribe, Topic: models.TopicHashtag, ID: "other"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Topic: models.TopicHashtag, ID: "other", Message: "Topic is not subscribed"},
+		},
+		{
+			Name:            "UnknownMessageTypeTest",
+			Message:         models.SocketMessage{Type: "unknown"},
+			ExpectedMessage: models.SocketMessage{Type: models.SocketError, Message: "Unknown message type"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		conn.Send(t, testCase.Message)
+		msg := conn.Receive(t)
+		if testCase.ExpectedMessage.Type == models.SocketError && testCase.ExpectedMessage.ID == "" {
+			msg.ID = ""
+		}
+		assert.Equal(t, testCase.ExpectedMessage, msg)
+
+		if testCase.PublishTo != "" {
+			app.events.Publish(events.EventCaw, "caw", testCase.PublishTo)
+			msg = conn.Receive(t)
+			assert.Equal(t, models.SocketEvent, msg.Type)
+			assert.Equal(t, testCase.Message.Topic, msg.Topic)
+			assert.Equal(t, testCase.Message.ID, msg.ID)
+			assert.Equal(t, events.EventCaw, msg.Event)
+			assert.Equal(t, `"caw"`, string(msg.Data))
+		}
+	}
+
+	conn.Close()
+	<-finished
+	assert.False(t, app.events.HasSubscriptions(), "subscriptions have to be closed with connection")
+}
+
+func TestSocketPost(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId()
+	cawID := bson.NewObjectId()
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUser: func(id string) (*models.User, error) {
+			return &models.User{ID: bson.ObjectIdHex(id), Name: "anycmon"}, nil
+		},
+	}
+	cawDataStoreMock := CawDataStoreMock{
+		OnStore: func(caw models.Caw) (*models.Caw, error) {
+			caw.ID = cawID
+			return &caw, nil
+		},
+	}
+
+	var testCases = []struct {
+		Name            string
+		Caw             *models.Caw
+		ExpectedType    string
+		ExpectedMessage string
+	}{
+		{
+			Name:         "PostCawTest",
+			Caw:          &models.Caw{Message: "hello"},
+			ExpectedType: models.SocketPosted,
+		},
+		{
+			Name:            "PostCawOfOtherUserTest",
+			Caw:             &models.Caw{UserID: bson.NewObjectId(), Message: "hello"},
+			ExpectedType:    models.SocketError,
+			ExpectedMessage: "Caw has to be posted by authenticated user",
+		},
+		{
+			Name:            "PostCawWithInvalidVisibilityTest",
+			Caw:             &models.Caw{Message: "hello", Visibility: "nobody"},
+			ExpectedType:    models.SocketError,
+			ExpectedMessage: "Visibility has to be one of: public, followers, mentioned",
+		},
+		{
+			Name:            "PostWithoutCawTest",
+			ExpectedType:    models.SocketError,
+			ExpectedMessage: "Caw is required",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		app := createApp(userDataStoreMock, cawDataStoreMock)
+		conn, finished := connectSocketMock(t, app, userID.Hex())
+
+		conn.Send(t, models.SocketMessage{Type: models.SocketPost, Caw: testCase.Caw})
+		msg := conn.Receive(t)
+		assert.Equal(t, testCase.ExpectedType, msg.Type)
+		assert.Equal(t, testCase.ExpectedMessage, msg.Message)
+		if testCase.ExpectedType == models.SocketPosted {
+			assert.Equal(t, cawID.Hex(), msg.ID)
+			assert.Equal(t, userID, msg.Caw.UserID)
+			assert.Equal(t, "anycmon", msg.Caw.UserName)
+		}
+
+		conn.Close()
+		<-finished
+		app.Close()
+	}
+}
+
+func TestSocketRateLimit(t *testing.T) {
+	t.Parallel()
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	app.socketMessagesPerSecond = 1
+	defer app.Close()
+	conn, finished := connectSocketMock(t, app, bson.NewObjectId().Hex())
+
+	conn.Send(t, models.SocketMessage{Type: "unknown"})
+	assert.Equal(t, "Unknown message type", conn.Receive(t).Message)
+	conn.Send(t, models.SocketMessage{Type: "unknown"})
+	assert.Equal(t, "Rate limit exceeded", conn.Receive(t).Message)
+
+	conn.Close()
+	<-finished
+}
+
+func TestSocketTokenExpiry(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	defer app.Close()
+	conn := NewSocketConnMock()
+	finished := serveSocketMock(app, conn)
+
+	conn.Send(t, models.SocketMessage{Type: models.SocketAuth, Token: newSocketToken(t, userID, time.Second)})
+	assert.Equal(t, models.SocketAuthenticated, conn.Receive(t).Type)
+
+	select {
+	case code := <-conn.closeCode:
+		assert.Equal(t, socketCloseTokenExpired, code)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Connection was not closed after token expiry")
+	}
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Connection was not served to the end")
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"Caw/UserService/events"
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+// publishCaw publishes stored caw to timelines of users allowed to read it
+// and, if caw is public, to its hashtag and replies topics. Caw is published also
+// when nobody listens, so clients resuming with Last-Event-ID receive it.
+func (app *App) publishCaw(userDataStore infrastructure.UserDataStore, caw models.Caw) {
+	recipients := []string{caw.UserID.Hex()}
+	if caw.Visibility == models.VisibilityMentioned {
+		for _, mention := range caw.Mentions {
+			recipients = append(recipients, mention.UserID.Hex())
+		}
+	} else {
+		followers, err := userDataStore.GetUserFollowers(caw.UserID.Hex())
+		if err != nil {
+			app.logger.Errorf("Cannot get followers to publish caw. userID: %s, err: %s", caw.UserID.Hex(), err)
+		}
+		for _, follower := range followers {
+			recipients = append(recipients, follower.UserID.Hex())
+		}
+	}
+
+	if caw.Visibility == models.VisibilityPublic {
+		for _, hashtag := range caw.Hashtags {
+			recipients = append(recipients, events.HashtagTopic(hashtag))
+		}
+		if caw.ParentID != "" {
+			recipients = append(recipients, events.RepliesTopic(caw.ParentID.Hex()))
+		}
+	}
+
+	if err := app.events.Publish(events.EventCaw, caw, recipients...); err != nil {
+		app.logger.Errorf("Cannot publish caw. cawID: %s, err: %s", caw.ID.Hex(), err)
+	}
+}
+
+// GET /v1/stream
+// getStreamHandler streams timeline caws, notifications and follow events of requestor
+// as Server-Sent Events. Stream is resumed after ID from Last-Event-ID header, reset
+// event is sent first when events after the ID are no longer kept.
+func (app *App) getStreamHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Error("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		app.logger.Error("Streaming is not supported by ResponseWriter")
+		writeInternalError(w, r)
+		return
+	}
+
+	var lastEventID uint64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		var err error
+		if lastEventID, err = strconv.ParseUint(header, 10, 64); err != nil {
+			app.logger.Errorf("Invalid Last-Event-ID %s. err: %s", header, err)
+			writeParameterProblem(w, r, "Last-Event-ID", "Last-Event-ID has to be a number")
+			return
+		}
+	}
+
+	subscription, missed := app.events.Subscribe(claims.UserId, lastEventID)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if claims.ExpiresAt > 0 {
+		expiration := time.NewTimer(time.Until(time.Unix(claims.ExpiresAt, 0)))
+		defer expiration.Stop()
+		expired = expiration.C
+	}
+
+	for {
+		select {
+		case event := <-subscription.Events():
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-subscription.Done():
+			app.logger.Infof("Stream of user %s closed by server", claims.UserId)
+			return
+		case <-expired:
+			app.logger.Infof("Token of user %s expired, closing stream", claims.UserId)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
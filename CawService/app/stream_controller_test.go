@@ -0,0 +1,123 @@
+package app
+
+import (
+	"Caw/UserService/events"
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Cannot read event %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestGetStreamHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	// published before connection, delivered thanks to Last-Event-ID
+	app.events.Publish(events.EventFollow, "missed", userID.Hex())
+	app.events.Publish(events.EventFollow, "other user", bson.NewObjectId().Hex())
+
+	request := NewTestRequest(t, "GET", server.URL+uriBuilder.Stream().Done(), nil).
+		WithAuthorization(userID.Hex())
+	request.Header.Set("Last-Event-ID", "0")
+
+	response, err := http.DefaultClient.Do(request.Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	missed := readEvent(t, reader)
+	assert.Equal(t, []string{"event: follow", `data: "missed"`}, missed[1:])
+	missedID, err := strconv.ParseUint(strings.TrimPrefix(missed[0], "id: "), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.events.Publish(events.EventCaw, "new caw", userID.Hex())
+	assert.Equal(t, []string{fmt.Sprintf("id: %d", missedID+2), "event: caw", `data: "new caw"`}, readEvent(t, reader))
+
+	done := make(chan struct{})
+	go func() {
+		reader.ReadString('\n')
+		close(done)
+	}()
+	app.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stream was not closed")
+	}
+}
+
+func TestGetStreamHandlerInvalidLastEventID(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	request := NewTestRequest(t, "GET", uriBuilder.Stream().Done(), nil).
+		WithAuthorization(userID.Hex())
+	request.Header.Set("Last-Event-ID", "abc")
+
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request.Request)
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestGetStreamHandlerUnknownLastEventID(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	app := createApp(UserDataStoreMock{}, CawDataStoreMock{})
+	defer app.Close()
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	app.events.Publish(events.EventFollow, "missed", userID.Hex())
+
+	// event id issued before restart of the service
+	request := NewTestRequest(t, "GET", server.URL+uriBuilder.Stream().Done(), nil).
+		WithAuthorization(userID.Hex())
+	request.Header.Set("Last-Event-ID", "1")
+
+	response, err := http.DefaultClient.Do(request.Request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	event := readEvent(t, bufio.NewReader(response.Body))
+	assert.Equal(t, []string{"event: " + events.EventReset, "data: {}"}, event[1:])
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// maxSuggestions limits number of suggestions computed for user
+const maxSuggestions = 20
+
+// computeSuggestions returns accounts followed by accounts which user follows
+func (app *App) computeSuggestions(userID string) ([]models.Suggestion, error) {
+	userDataStore := app.newUserDataStore()
+	defer userDataStore.Close()
+	return userDataStore.GetFollowSuggestions(userID, maxSuggestions)
+}
+
+// GET /v1/users/{userID}/suggestions
+// getUserSuggestionsHandler handle HTTP GET method and returns accounts recommended to requestor
+func (app *App) getUserSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	if !app.isRequestor(w, r, userID) {
+		return
+	}
+
+	suggestions, err := app.suggestions.Get(userID)
+	if err != nil {
+		app.logger.Errorf("Cannot get suggestions. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
+		return
+	}
+
+	js, err := json.Marshal(suggestions)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal suggestions. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGetUserSuggestionsHandler(t *testing.T) {
+	t.Parallel()
+	userID := bson.NewObjectId().Hex()
+	suggestion := models.Suggestion{
+		User:       models.Follow{UserID: bson.NewObjectId(), Name: "popular"},
+		Score:      2,
+		FollowedBy: []models.Follow{{UserID: bson.NewObjectId(), Name: "bob"}},
+		Reason:     "Followed by bob and 1 other",
+	}
+	var testCases = []struct {
+		Name               string
+		UserID             string
+		RequestorID        string
+		DataStoreError     error
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "GetSuggestionsTest",
+			UserID:             userID,
+			RequestorID:        userID,
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetSuggestionsOfOtherUserTest",
+			UserID:             userID,
+			RequestorID:        bson.NewObjectId().Hex(),
+			ExpectedStatusCode: http.StatusForbidden,
+		},
+		{
+			Name:               "UserDoesNotExistTest",
+			UserID:             "1",
+			RequestorID:        "1",
+			DataStoreError:     infrastructure.ErrNotFound,
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "StorageInternalErrorTest",
+			UserID:             userID,
+			RequestorID:        userID,
+			DataStoreError:     errors.New("StorageInternalError"),
+			ExpectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		userDataStoreMock := UserDataStoreMock{
+			OnGetFollowSuggestions: func(userID string, limit int) ([]models.Suggestion, error) {
+				if testCase.DataStoreError != nil {
+					return nil, testCase.DataStoreError
+				}
+				return []models.Suggestion{suggestion}, nil
+			},
+		}
+		app := createApp(userDataStoreMock, CawDataStoreMock{})
+
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser(testCase.UserID).Suggestions().Done(), nil).
+			WithAuthorization(testCase.RequestorID)
+		request.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var suggestions []models.Suggestion
+			err := json.NewDecoder(recorder.Body).Decode(&suggestions)
+			assert.Nil(t, err)
+			assert.Equal(t, []models.Suggestion{suggestion}, suggestions)
+		}
+		app.Close()
+	}
+}
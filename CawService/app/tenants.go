@@ -0,0 +1,84 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Tenants serves requests of every tenant by its own App, so stored data and
+// in-memory state like search index, trends and event streams are isolated
+type Tenants struct {
+	apps   map[string]*App
+	source string
+	logger *logrus.Logger
+}
+
+// NewTenants creates Tenants serving apps keyed by tenant. Tenant of request is
+// identified by source, utils.TenantSourceHost or utils.TenantSourceClaim.
+func NewTenants(apps map[string]*App, source string, logger *logrus.Logger) *Tenants {
+	return &Tenants{
+		apps:   apps,
+		source: source,
+		logger: logger,
+	}
+}
+
+func (t *Tenants) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant := t.tenant(r)
+	app, ok := t.apps[tenant]
+	if !ok {
+		t.logger.Errorf("Unknown tenant. tenant: %q, host: %s", tenant, r.Host)
+		writeProblem(w, r, http.StatusNotFound, models.CodeTenantNotFound, "Tenant does not exist")
+		return
+	}
+	app.ServeHTTP(w, r)
+}
+
+// tenant returns tenant of request, token is only decoded here, it is verified by App of the tenant
+func (t *Tenants) tenant(r *http.Request) string {
+	if t.source == utils.TenantSourceClaim {
+		words := strings.Fields(r.Header.Get("Authorization"))
+		if len(words) == 2 && words[0] == "Bearer" {
+			if claims, err := utils.DecodeUserToken(words[1]); err == nil {
+				return claims.Tenant()
+			}
+		}
+		// websocket and event stream clients cannot always set headers
+		if tenant := r.Header.Get(utils.TenantHeader); tenant != "" {
+			return tenant
+		}
+		return r.URL.Query().Get("tenant")
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.SplitN(host, ".", 2)[0]
+}
+
+// UpdateConfig updates App of every tenant
+func (t *Tenants) UpdateConfig(appConfig *utils.AppConfig) {
+	for tenant, app := range t.apps {
+		app.UpdateConfig(appConfig.ForTenant(tenant))
+	}
+}
+
+// LoadSearchIndex loads search index of every tenant
+func (t *Tenants) LoadSearchIndex() {
+	for _, app := range t.apps {
+		app.LoadSearchIndex()
+	}
+}
+
+// Close closes App of every tenant
+func (t *Tenants) Close() {
+	for _, app := range t.apps {
+		app.Close()
+	}
+}
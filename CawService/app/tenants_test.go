@@ -0,0 +1,125 @@
+package app
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createTenantApp creates App of tenant whose only user is named after the tenant
+func createTenantApp(tenant string) *App {
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUser: func(string) (*models.User, error) {
+			return &models.User{Name: tenant}, nil
+		},
+	}
+	dataStoreFactoryMock := DataStoreFactoryMock{
+		OnCreateUserDataStore: func() infrastructure.UserDataStore {
+			return userDataStoreMock
+		},
+	}
+	return New((&utils.AppConfig{}).ForTenant(tenant), dataStoreFactoryMock, Logger)
+}
+
+func TestTenants(t *testing.T) {
+	t.Parallel()
+	apps := map[string]*App{
+		"cats": createTenantApp("cats"),
+		"dogs": createTenantApp("dogs"),
+	}
+	var testCases = []struct {
+		Name               string
+		Source             string
+		Host               string
+		TenantHeader       string
+		TokenTenant        string
+		ExpectedStatusCode int
+		ExpectedUserName   string
+	}{
+		{
+			Name:               "HostSelectsTenantTest",
+			Source:             utils.TenantSourceHost,
+			Host:               "dogs.caw.io:9090",
+			TokenTenant:        "dogs",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedUserName:   "dogs",
+		},
+		{
+			Name:               "TokenOfOtherTenantIsRejectedTest",
+			Source:             utils.TenantSourceHost,
+			Host:               "dogs.caw.io",
+			TokenTenant:        "cats",
+			ExpectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			Name:               "UnknownHostTest",
+			Source:             utils.TenantSourceHost,
+			Host:               "birds.caw.io",
+			TokenTenant:        "birds",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+		{
+			Name:               "TokenClaimSelectsTenantTest",
+			Source:             utils.TenantSourceClaim,
+			Host:               "caw.io",
+			TenantHeader:       "dogs",
+			TokenTenant:        "cats",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedUserName:   "cats",
+		},
+		{
+			Name:               "TokenWithoutTenantTest",
+			Source:             utils.TenantSourceClaim,
+			Host:               "caw.io",
+			ExpectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		tenants := NewTenants(apps, testCase.Source, Logger)
+		request := NewTestRequest(t, "GET", uriBuilder.User().WithUser("597bd1d34ac00c75e9280ae4").Done(), nil).
+			WithTenantAuthorization("597bd1d34ac00c75e9280ae4", testCase.TokenTenant)
+		request.Host = testCase.Host
+		if testCase.TenantHeader != "" {
+			request.Header.Set(utils.TenantHeader, testCase.TenantHeader)
+		}
+
+		recorder := httptest.NewRecorder()
+		tenants.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusNotFound {
+			var problem models.Problem
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&problem))
+			assert.Equal(t, models.CodeTenantNotFound, problem.Code)
+		}
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var user models.User
+			assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&user))
+			assert.Equal(t, testCase.ExpectedUserName, user.Name)
+		}
+	}
+}
+
+func TestTenantsWithoutToken(t *testing.T) {
+	t.Parallel()
+	tenants := NewTenants(map[string]*App{"cats": createTenantApp("cats")}, utils.TenantSourceClaim, Logger)
+
+	request := NewTestRequest(t, "GET", uriBuilder.OpenAPI().Done(), nil)
+	request.Header.Set(utils.TenantHeader, "cats")
+	recorder := httptest.NewRecorder()
+	tenants.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	request = NewTestRequest(t, "GET", uriBuilder.OpenAPI().Done()+"?tenant=dogs", nil)
+	recorder = httptest.NewRecorder()
+	tenants.ServeHTTP(recorder, request.Request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/trends"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTrendsLimit = 10
+	maxTrendsLimit     = 50
+)
+
+// GET /v1/trends?window=$&limit=$
+// getTrendsHandler handle HTTP GET method and returns hashtags trending in requested window.
+// First configured window is used when window is not provided.
+func (app *App) getTrendsHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+
+	windows := app.trends.Windows()
+	if len(windows) == 0 {
+		app.logger.Error("Trend windows are not configured")
+		writeProblem(w, r, http.StatusNotFound, models.CodeNotFound, "Trends are not available")
+		return
+	}
+	window := windows[0]
+	if qWindow := vals.Get("window"); qWindow != "" {
+		var err error
+		if window, err = time.ParseDuration(qWindow); err != nil {
+			app.logger.Errorf("Cannot parse trend window %s. err: %s", qWindow, err)
+			writeParameterProblem(w, r, "window", "Window has to be a duration like 1h")
+			return
+		}
+	}
+
+	limit := defaultTrendsLimit
+	if qLimit := vals.Get("limit"); qLimit != "" {
+		v, err := strconv.Atoi(qLimit)
+		if err != nil || v < 1 || v > maxTrendsLimit {
+			writeParameterProblem(w, r, "limit", "Limit has to be a number between 1 and "+strconv.Itoa(maxTrendsLimit))
+			return
+		}
+		limit = v
+	}
+
+	result, err := app.trends.Top(window, limit)
+	if err != nil {
+		app.logger.Errorf("Cannot get trends. window: %s, err: %s", window, err)
+		if err == trends.ErrUnknownWindow {
+			writeProblem(w, r, http.StatusBadRequest, models.CodeTrendWindowUnsupported, "Unsupported trend window")
+			return
+		}
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal trends. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+
+	w.Write(js)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGetTrendsHandler(t *testing.T) {
+	t.Parallel()
+
+	userID := bson.NewObjectId()
+	testCases := []struct {
+		Name               string
+		Query              string
+		ExpectedWindow     string
+		ExpectedStatusCode int
+	}{
+		{
+			Name:               "GetTrendsDefaultWindowTest",
+			Query:              "",
+			ExpectedWindow:     "1h0m0s",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetTrendsRequestedWindowTest",
+			Query:              "?window=24h&limit=5",
+			ExpectedWindow:     "24h0m0s",
+			ExpectedStatusCode: http.StatusOK,
+		},
+		{
+			Name:               "GetTrendsUnknownWindowTest",
+			Query:              "?window=2h",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "GetTrendsInvalidWindowTest",
+			Query:              "?window=hour",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "GetTrendsInvalidLimitTest",
+			Query:              "?limit=1000",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		request := NewTestRequest(t, "GET", uriBuilder.Trends().Done()+testCase.Query, nil).
+			WithAuthorization(userID.Hex())
+
+		dataStoreFactoryMock := DataStoreFactoryMock{}
+		app := New(&utils.AppConfig{TrendWindows: []time.Duration{time.Hour, 24 * time.Hour}}, dataStoreFactoryMock, Logger)
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+		app.Close()
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code,
+			"status codes are different %v %v", testCase.ExpectedStatusCode, recorder.Code)
+
+		if recorder.Code == http.StatusOK {
+			var trends models.Trends
+			err := json.NewDecoder(recorder.Body).Decode(&trends)
+			assert.Nil(t, err, "cannot to decode trends %v", err)
+			assert.Equal(t, testCase.ExpectedWindow, trends.Window)
+		}
+	}
+}
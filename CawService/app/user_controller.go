@@ -1,16 +1,24 @@
 package app
 
 import (
+	"Caw/UserService/events"
 	"Caw/UserService/infrastructure"
 	"Caw/UserService/models"
 	"Caw/UserService/utils"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 50
+)
+
 // GET /v1/users/{userID}
 // getUserHandler handle HTTP GET method and returns requested user
 func (app *App) getUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -21,18 +29,14 @@ func (app *App) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := ds.GetUser(userID)
 	if err != nil {
 		app.logger.Errorf("Cannot to get user. userID: %s, err: %s", userID, err)
-		if err == infrastructure.ErrNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
 
 	js, err := user.ToPublic().ToJSON()
 	if err != nil {
 		app.logger.Errorf("Cannot to convert user to json. err: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w, r)
 		return
 	}
 
@@ -48,20 +52,24 @@ func (app *App) postUserHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	if err != nil {
 		app.logger.Errorf("Cannot to convert payload to User. err: %s", err)
-		w.WriteHeader(http.StatusBadRequest)
+		writePayloadError(w, r, err, "Payload has to be a user in JSON format")
 		return
 	}
 
-	if !user.IsValid() {
-		app.logger.Errorf("User is invalid. err: %s", user)
-		w.WriteHeader(http.StatusUnprocessableEntity)
+	if fieldErrors := user.Validate(app.limits); len(fieldErrors) > 0 {
+		app.logger.Errorf("User is invalid. err: %v", fieldErrors)
+		writeValidationProblem(w, r, fieldErrors)
 		return
 	}
 
 	passwordHash, err := utils.HashPassword(user.Password)
 	if err != nil {
 		app.logger.Errorf("Cannot to hash password. err: %s", err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeValidationProblem(w, r, []models.FieldError{{
+			Field:   "password",
+			Code:    models.FieldInvalid,
+			Message: "Password cannot be hashed",
+		}})
 		return
 	}
 
@@ -73,19 +81,19 @@ func (app *App) postUserHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		app.logger.Errorf("Cannot to store user. name: %s, email: %s, err: %s", user.Name, user.Email, err)
 		if err == infrastructure.ErrUserExists {
-			app.logger.Errorf("User already exists. name: %s, email: %s", user.Name, user.Email)
-			writeErrMsg(http.StatusConflict, "User already exists", w)
-			return
+			writeProblem(w, r, http.StatusConflict, models.CodeUserExists, "User with this name or email already exists")
+		} else {
+			writeInternalError(w, r)
 		}
-
-		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	app.search.IndexUser(*storedUser)
+	app.names.Put(*storedUser)
 
 	js, err := storedUser.ToPublic().ToJSON()
 	if err != nil {
 		app.logger.Errorf("Cannot to convert user to js. err: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w, r)
 		return
 	}
 
@@ -103,23 +111,93 @@ func (app *App) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	userClaims, ok := r.Context().Value("userClaims").(*utils.UserClaims)
 	if !ok {
 		app.logger.Error("Cannot retrieve user claims")
-		w.WriteHeader(http.StatusForbidden)
+		writeMissingClaims(w, r)
 		return
 	}
 
 	if userClaims.UserId != userID {
 		app.logger.Errorf("Cannot to delete user %v by user %v", userID, userClaims.UserId)
-		w.WriteHeader(http.StatusForbidden)
+		writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "Users can be deleted only by themselves")
 		return
 	}
 
 	ds := app.newUserDataStore()
 	defer ds.Close()
-	if err := ds.DeleteUser(userID); err != nil {
+	following, err := ds.GetUserFollowing(userID)
+	if err != nil {
+		app.logger.Errorf("Cannot get users followed by deleted user. userID: %s, err: %s", userID, err)
+		writeError(w, r, err, models.CodeUserNotFound)
+		return
+	}
+
+	// caws are purged with the user, so search index agrees with datastore
+	report, err := infrastructure.PurgeUser(app.dataStoreFactory, userID)
+	if err != nil {
 		app.logger.Errorf("Cannot to delete user. userID: %s, err: %s", userID, err)
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
+	app.logger.Infof("User deleted. userID: %s, follows: %d, caws: %d, notifications: %d",
+		userID, report.FollowsRemoved, report.CawsRemoved, report.NotificationsRemoved)
+	app.search.RemoveUser(userID)
+	app.names.Remove(userID)
+	for _, followed := range following {
+		app.names.AddFollowers(followed.UserID.Hex(), -1)
+	}
+}
+
+// GET /v1/users/autocomplete?prefix=$&limit=$
+// getUsersAutocompleteHandler handle HTTP GET method and returns users whose names start with prefix.
+// Users followed by requestor and users with more followers are returned first.
+func (app *App) getUsersAutocompleteHandler(w http.ResponseWriter, r *http.Request) {
+	vals := r.URL.Query()
+	claims, ok := userClaims(r)
+	if !ok {
+		app.logger.Error("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	prefix := strings.TrimPrefix(vals.Get("prefix"), "@")
+	if prefix == "" {
+		writeParameterProblem(w, r, "prefix", "Prefix cannot be empty")
+		return
+	}
+
+	limit := defaultAutocompleteLimit
+	if qLimit := vals.Get("limit"); qLimit != "" {
+		v, err := strconv.Atoi(qLimit)
+		if err != nil || v < 1 || v > maxAutocompleteLimit {
+			writeParameterProblem(w, r, "limit", "Limit has to be a number between 1 and "+strconv.Itoa(maxAutocompleteLimit))
+			return
+		}
+		limit = v
+	}
+
+	ds := app.newUserDataStore()
+	defer ds.Close()
+	following, err := ds.GetUserFollowing(claims.UserId)
+	if err != nil && err != infrastructure.ErrNotFound {
+		app.logger.Errorf("Cannot to get user following. userID: %s, err: %s", claims.UserId, err)
+		writeInternalError(w, r)
+		return
+	}
+	followingIDs := map[string]bool{}
+	for _, follow := range following {
+		followingIDs[follow.UserID.Hex()] = true
+	}
+
+	users := []models.PublicUser{}
+	for _, user := range app.names.Complete(prefix, followingIDs, limit) {
+		users = append(users, user.ToPublic())
+	}
+	js, err := json.Marshal(users)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal users. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Write(js)
 }
 
 // GET /v1/users/{userID}/followers
@@ -132,18 +210,14 @@ func (app *App) getUserFollowersHandler(w http.ResponseWriter, r *http.Request)
 	followers, err := ds.GetUserFollowers(userID)
 	if err != nil {
 		app.logger.Errorf("Cannot to get user followers. userID: %s, err: %s", userID, err)
-		if err == infrastructure.ErrNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
 
 	js, err := json.Marshal(followers)
 	if err != nil {
 		app.logger.Errorf("Cannot to marshal followers. err: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w, r)
 		return
 	}
 
@@ -160,18 +234,14 @@ func (app *App) getUserFollowingHandler(w http.ResponseWriter, r *http.Request)
 	following, err := ds.GetUserFollowing(userID)
 	if err != nil {
 		app.logger.Errorf("Cannot to get user following. userID: %s, err: %s", userID, err)
-		if err == infrastructure.ErrNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
 
 	js, err := json.Marshal(following)
 	if err != nil {
 		app.logger.Errorf("Cannot to marshal user following. err: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeInternalError(w, r)
 		return
 	}
 
@@ -183,34 +253,88 @@ func (app *App) getUserFollowingHandler(w http.ResponseWriter, r *http.Request)
 func (app *App) postUserFollowingHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	followerID := vars["userID"]
+
+	userClaims, ok := r.Context().Value("userClaims").(*utils.UserClaims)
+	if !ok {
+		app.logger.Errorf("Cannot retrieve user claims")
+		writeMissingClaims(w, r)
+		return
+	}
+
+	if userClaims.UserId != followerID {
+		app.logger.Errorf("Cannot add user following %v by user %v", followerID, userClaims.UserId)
+		writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "Users can follow other users only on their own behalf")
+		return
+	}
+
 	var followingUser models.Follow
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&followingUser)
+	err := models.DecodeJSON(r.Body, &followingUser)
+	defer r.Body.Close()
 	if err != nil {
 		app.logger.Errorf("Cannot to convert payload to Following. err: %s", err)
-		w.WriteHeader(http.StatusUnprocessableEntity)
+		writePayloadError(w, r, err, "Payload has to be a followed user in JSON format")
 		return
 	}
-	defer r.Body.Close()
 
 	ds := app.newUserDataStore()
 	defer ds.Close()
+	uriBuilder := utils.NewUriBuilder()
+	location := uriBuilder.User().WithUser(followerID).Following().WithFollowing(followingUser.UserID.Hex()).Done()
 	err = ds.AddFollowingUser(followerID, followingUser.UserID.Hex())
+	if err == infrastructure.ErrFollowExists {
+		app.writeFollowRelation(ds, followerID, followingUser.UserID.Hex(), location, w, r)
+		return
+	}
 	if err != nil {
 		app.logger.Errorf("Cannot to add following user. followerID: %s, followingUser: %s, err: %s", followerID, followingUser.UserID.Hex(), err)
-		if err == infrastructure.ErrNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err, models.CodeUserNotFound)
 		return
 	}
+	app.names.AddFollowers(followingUser.UserID.Hex(), 1)
+	app.suggestions.Invalidate(followerID)
 
-	uriBuilder := utils.NewUriBuilder()
-	w.Header().Set("Location", uriBuilder.User().WithUser(followerID).Following().WithFollowing(followingUser.UserID.Hex()).Done())
+	follower, err := ds.GetUser(followerID)
+	if err != nil {
+		app.logger.Errorf("Cannot to get follower for notification. followerID: %s, err: %s", followerID, err)
+	} else {
+		app.notify(models.Notification{
+			UserID: followingUser.UserID,
+			Type:   models.NotificationFollow,
+			Actor:  models.Follow{UserID: follower.ID, Name: follower.Name},
+		})
+		relation := models.FollowRelation{
+			Follower:  models.Follow{UserID: follower.ID, Name: follower.Name},
+			Following: followingUser,
+		}
+		if err = app.events.Publish(events.EventFollow, relation, followerID, followingUser.UserID.Hex()); err != nil {
+			app.logger.Errorf("Cannot publish follow. err: %s", err)
+		}
+	}
+
+	w.Header().Set("Location", location)
 	w.WriteHeader(http.StatusCreated)
 }
 
+// writeFollowRelation writes already existing follow relation, so repeated follows are idempotent
+func (app *App) writeFollowRelation(ds infrastructure.UserDataStore, followerID, followingID, location string, w http.ResponseWriter, r *http.Request) {
+	relation, err := ds.GetFollowRelation(followerID, followingID)
+	if err != nil {
+		app.logger.Errorf("Cannot to get follow relation. followerID: %s, followingID: %s, err: %s", followerID, followingID, err)
+		writeInternalError(w, r)
+		return
+	}
+
+	js, err := json.Marshal(relation)
+	if err != nil {
+		app.logger.Errorf("Cannot to marshal follow relation. err: %s", err)
+		writeInternalError(w, r)
+		return
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
 // DELETE /v1/users/{userID}/following/{fid}
 // deleteUserFollowingHandler handle HTTP DELETE method by removing follow repationship
 func (app *App) deleteUserFollowingHandler(w http.ResponseWriter, r *http.Request) {
@@ -221,13 +345,13 @@ func (app *App) deleteUserFollowingHandler(w http.ResponseWriter, r *http.Reques
 	userClaims, ok := r.Context().Value("userClaims").(*utils.UserClaims)
 	if !ok {
 		app.logger.Errorf("Cannot retrieve user claims")
-		w.WriteHeader(http.StatusForbidden)
+		writeMissingClaims(w, r)
 		return
 	}
 
 	if userClaims.UserId != userID {
 		app.logger.Errorf("Cannot delete user following %v by user %v", userID, userClaims.UserId)
-		w.WriteHeader(http.StatusForbidden)
+		writeProblem(w, r, http.StatusForbidden, models.CodeForbidden, "Users can be unfollowed only by their followers")
 		return
 	}
 	ds := app.newUserDataStore()
@@ -236,11 +360,9 @@ func (app *App) deleteUserFollowingHandler(w http.ResponseWriter, r *http.Reques
 	err := ds.UnfollowUser(userID, followingID)
 	if err != nil {
 		app.logger.Errorf("Cannot to unfollow user: %s, followingID: %s, err %s", userID, followingID, err)
-		if err == infrastructure.ErrNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err, models.CodeFollowNotFound)
 		return
 	}
+	app.names.AddFollowers(followingID, -1)
+	app.suggestions.Invalidate(userID)
 }
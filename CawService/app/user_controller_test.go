@@ -3,6 +3,7 @@ package app
 import (
 	"Caw/UserService/infrastructure"
 	"Caw/UserService/models"
+	"Caw/UserService/search"
 	"Caw/UserService/utils"
 	"bytes"
 	"encoding/json"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 
 	"gopkg.in/mgo.v2/bson"
 )
@@ -22,19 +24,55 @@ var Logger = logrus.New()
 var uriBuilder = utils.NewUriBuilder()
 
 type CawDataStoreMock struct {
-	OnStore func(caw models.Caw) (*models.Caw, error)
+	OnStore         func(caw models.Caw) (*models.Caw, error)
+	OnGetByID       func(cawID, viewerID string) (*models.Caw, error)
+	OnGetByUserID   func(userID, viewerID string, page int, newestFirst bool) ([]models.Caw, error)
+	OnGetByHashtag  func(hashtag, viewerID string, page int) ([]models.Caw, error)
+	OnGetByMention  func(userID, viewerID string, page int) ([]models.Caw, error)
+	OnForEachPublic func(fn func(caw models.Caw) error) error
+	OnEdit          func(cawID, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error)
+	OnGetRevisions  func(cawID string) ([]models.CawRevision, error)
 }
 
 func (m CawDataStoreMock) Store(caw models.Caw) (*models.Caw, error) {
 	return m.OnStore(caw)
 }
 
-func (m CawDataStoreMock) GetByUserID(userID string, page int) ([]models.Caw, error) {
-	return nil, nil
+func (m CawDataStoreMock) GetByUserID(userID string, viewerID string, page int, newestFirst bool) ([]models.Caw, error) {
+	if m.OnGetByUserID == nil {
+		return nil, nil
+	}
+	return m.OnGetByUserID(userID, viewerID, page, newestFirst)
 }
 
-func (m CawDataStoreMock) GetByID(cawID string) (*models.Caw, error) {
-	return nil, nil
+func (m CawDataStoreMock) GetByHashtag(hashtag string, viewerID string, page int) ([]models.Caw, error) {
+	return m.OnGetByHashtag(hashtag, viewerID, page)
+}
+
+func (m CawDataStoreMock) GetByMention(userID string, viewerID string, page int) ([]models.Caw, error) {
+	return m.OnGetByMention(userID, viewerID, page)
+}
+
+func (m CawDataStoreMock) GetByID(cawID string, viewerID string) (*models.Caw, error) {
+	if m.OnGetByID == nil {
+		return nil, nil
+	}
+	return m.OnGetByID(cawID, viewerID)
+}
+
+func (m CawDataStoreMock) ForEachPublic(fn func(caw models.Caw) error) error {
+	if m.OnForEachPublic == nil {
+		return nil
+	}
+	return m.OnForEachPublic(fn)
+}
+
+func (m CawDataStoreMock) Edit(cawID string, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error) {
+	return m.OnEdit(cawID, message, mentions, maxEdits)
+}
+
+func (m CawDataStoreMock) GetRevisions(cawID string) ([]models.CawRevision, error) {
+	return m.OnGetRevisions(cawID)
 }
 
 func (m CawDataStoreMock) Delete(cawID string) error {
@@ -45,20 +83,32 @@ func (m CawDataStoreMock) Close() {
 }
 
 type UserDataStoreMock struct {
-	OnGetUser          func(userID string) (*models.User, error)
-	OnGetUserByName    func(userID string) (*models.User, error)
-	OnStoreUser        func(user models.User) (*models.User, error)
-	OnDeleteUser       func(userID string) error
-	OnGetUserFollowers func(userID string) ([]models.Follow, error)
-	OnGetUserFollowing func(userID string) ([]models.Follow, error)
-	OnAddFollowingUser func(followerID, followingID string) error
-	OnUnfollowUser     func(followerID, followingID string) error
+	OnGetUser              func(userID string) (*models.User, error)
+	OnGetUserByName        func(userID string) (*models.User, error)
+	OnIsSuspended          func(userID string) (bool, error)
+	OnStoreUser            func(user models.User) (*models.User, error)
+	OnDeleteUser           func(userID string) error
+	OnGetUserFollowers     func(userID string) ([]models.Follow, error)
+	OnGetUserFollowing     func(userID string) ([]models.Follow, error)
+	OnAddFollowingUser     func(followerID, followingID string) error
+	OnUnfollowUser         func(followerID, followingID string) error
+	OnForEachUser          func(fn func(user models.User) error) error
+	OnGetFollowSuggestions func(userID string, limit int) ([]models.Suggestion, error)
+	OnGetFollowRelation    func(followerID, followingID string) (*models.FollowRelation, error)
+	OnGetMutuals           func(userID string, page int) ([]models.Follow, error)
 }
 
 func (m UserDataStoreMock) GetUser(userID string) (*models.User, error) {
 	return m.OnGetUser(userID)
 }
 
+func (m UserDataStoreMock) IsSuspended(userID string) (bool, error) {
+	if m.OnIsSuspended == nil {
+		return false, nil
+	}
+	return m.OnIsSuspended(userID)
+}
+
 func (m UserDataStoreMock) GetUserByName(userID string) (*models.User, error) {
 	return m.OnGetUserByName(userID)
 }
@@ -71,7 +121,17 @@ func (m UserDataStoreMock) DeleteUser(userID string) error {
 	return m.OnDeleteUser(userID)
 }
 
+func (m UserDataStoreMock) ForEachUser(fn func(user models.User) error) error {
+	if m.OnForEachUser == nil {
+		return nil
+	}
+	return m.OnForEachUser(fn)
+}
+
 func (m UserDataStoreMock) GetUserFollowers(userID string) ([]models.Follow, error) {
+	if m.OnGetUserFollowers == nil {
+		return nil, nil
+	}
 	return m.OnGetUserFollowers(userID)
 }
 
@@ -87,15 +147,118 @@ func (m UserDataStoreMock) UnfollowUser(followerID, followingID string) error {
 	return m.OnUnfollowUser(followerID, followingID)
 }
 
+func (m UserDataStoreMock) GetFollowRelation(followerID string, followingID string) (*models.FollowRelation, error) {
+	return m.OnGetFollowRelation(followerID, followingID)
+}
+
+func (m UserDataStoreMock) GetMutuals(userID string, page int) ([]models.Follow, error) {
+	return m.OnGetMutuals(userID, page)
+}
+
+func (m UserDataStoreMock) GetFollowSuggestions(userID string, limit int) ([]models.Suggestion, error) {
+	return m.OnGetFollowSuggestions(userID, limit)
+}
+
 func (m UserDataStoreMock) Close() {
 }
 
+type NotificationDataStoreMock struct {
+	OnStore              func(notification models.Notification) (*models.Notification, error)
+	OnGetByUserID        func(userID string, unreadOnly bool, page int) ([]models.Notification, error)
+	OnGetGroupedByUserID func(userID string, unreadOnly bool, page int) ([]models.Notification, error)
+	OnCountUnread        func(userID string) (int, error)
+	OnMarkAsRead         func(userID string, notificationIDs []string) (int, error)
+	OnDeleteByUserID     func(userID string) (int, error)
+	OnMarkAllAsRead      func(userID string) (int, error)
+}
+
+func (m NotificationDataStoreMock) Store(notification models.Notification) (*models.Notification, error) {
+	if m.OnStore == nil {
+		return &notification, nil
+	}
+	return m.OnStore(notification)
+}
+
+func (m NotificationDataStoreMock) GetByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	return m.OnGetByUserID(userID, unreadOnly, page)
+}
+
+func (m NotificationDataStoreMock) GetGroupedByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	return m.OnGetGroupedByUserID(userID, unreadOnly, page)
+}
+
+func (m NotificationDataStoreMock) CountUnread(userID string) (int, error) {
+	return m.OnCountUnread(userID)
+}
+
+func (m NotificationDataStoreMock) MarkAsRead(userID string, notificationIDs []string) (int, error) {
+	return m.OnMarkAsRead(userID, notificationIDs)
+}
+
+func (m NotificationDataStoreMock) MarkAllAsRead(userID string) (int, error) {
+	return m.OnMarkAllAsRead(userID)
+}
+
+func (m NotificationDataStoreMock) DeleteByUserID(userID string) (int, error) {
+	if m.OnDeleteByUserID == nil {
+		return 0, nil
+	}
+	return m.OnDeleteByUserID(userID)
+}
+
+func (m NotificationDataStoreMock) Close() {
+}
+
+type AdminDataStoreMock struct {
+	OnRemoveFollows func(userID string) (int, error)
+	OnPurgeCaws     func(userID string) (int, error)
+}
+
+func (m AdminDataStoreMock) SetSuspended(userID string, suspended bool) error {
+	return nil
+}
+
+func (m AdminDataStoreMock) SetPassword(userID string, passwordHash string) error {
+	return nil
+}
+
+func (m AdminDataStoreMock) RemoveFollows(userID string) (int, error) {
+	if m.OnRemoveFollows == nil {
+		return 0, nil
+	}
+	return m.OnRemoveFollows(userID)
+}
+
+func (m AdminDataStoreMock) PurgeCaws(userID string) (int, error) {
+	if m.OnPurgeCaws == nil {
+		return 0, nil
+	}
+	return m.OnPurgeCaws(userID)
+}
+
+func (m AdminDataStoreMock) EnsureIndexes() error {
+	return nil
+}
+
+func (m AdminDataStoreMock) Stats() ([]infrastructure.CollectionStats, error) {
+	return nil, nil
+}
+
+func (m AdminDataStoreMock) Close() {
+}
+
 type DataStoreFactoryMock struct {
-	OnCreateUserDataStore func() infrastructure.UserDataStore
-	OnCreateCawDataStore  func() infrastructure.CawDataStore
+	OnCreateUserDataStore         func() infrastructure.UserDataStore
+	OnCreateCawDataStore          func() infrastructure.CawDataStore
+	OnCreateNotificationDataStore func() infrastructure.NotificationDataStore
+	OnCreateCounterDataStore      func() infrastructure.CounterDataStore
+	OnCreateAdminDataStore        func() infrastructure.AdminDataStore
 }
 
 func (m DataStoreFactoryMock) CreateUserDataStore() infrastructure.UserDataStore {
+	if m.OnCreateUserDataStore == nil {
+		return UserDataStoreMock{}
+	}
 	return m.OnCreateUserDataStore()
 }
 
@@ -103,6 +266,21 @@ func (m DataStoreFactoryMock) CreateCawDataStore() infrastructure.CawDataStore {
 	return m.OnCreateCawDataStore()
 }
 
+func (m DataStoreFactoryMock) CreateNotificationDataStore() infrastructure.NotificationDataStore {
+	return m.OnCreateNotificationDataStore()
+}
+
+func (m DataStoreFactoryMock) CreateCounterDataStore() infrastructure.CounterDataStore {
+	return m.OnCreateCounterDataStore()
+}
+
+func (m DataStoreFactoryMock) CreateAdminDataStore() infrastructure.AdminDataStore {
+	if m.OnCreateAdminDataStore == nil {
+		return AdminDataStoreMock{}
+	}
+	return m.OnCreateAdminDataStore()
+}
+
 func (m DataStoreFactoryMock) Close() {
 }
 
@@ -170,7 +348,7 @@ func TestPostUserHandler(t *testing.T) {
 	}{
 		{
 			Name: "SuccessfullyCreatedUserTest",
-			User: models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar"},
+			User: models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar12"},
 			ExpectedStoredUser: &models.User{
 				ID:    bson.ObjectIdHex(userID),
 				Name:  "foobar",
@@ -187,19 +365,19 @@ func TestPostUserHandler(t *testing.T) {
 		},
 		{
 			Name:               "WrongEmailFormatReturnsErrorTest",
-			User:               models.User{Name: "foobar", Email: "foobargmail.com", Password: "foobar"},
+			User:               models.User{Name: "foobar", Email: "foobargmail.com", Password: "foobar12"},
 			ExpectedStoredUser: nil,
 			ExpectedStatusCode: http.StatusUnprocessableEntity,
 		},
 		{
 			Name:               "StorageSystemOutageReturnsErrorTest",
-			User:               models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar"},
+			User:               models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar12"},
 			StoreError:         errors.New("Unknow error"),
 			ExpectedStatusCode: http.StatusInternalServerError,
 		},
 		{
-			Name:               "StorageSystemOutageReturnsErrorTest",
-			User:               models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar"},
+			Name:               "ExistingUserReturnsConflictTest",
+			User:               models.User{Name: "foobar", Email: "foobar@gmail.com", Password: "foobar12"},
 			StoreError:         infrastructure.ErrUserExists,
 			ExpectedStatusCode: http.StatusConflict,
 		},
@@ -417,12 +595,19 @@ func TestDeleteUserHandler(t *testing.T) {
 			ExpectedStatusCode:   http.StatusForbidden,
 		},
 		{
-			Name:                 "InvalidUserIDTest",
+			Name:                 "NotExistingUserTest",
 			UserIDForDeletion:    bson.ObjectIdHex("597bd1d34ac00c75e9280ae4"),
 			AuthenticateAsUserID: bson.ObjectIdHex("597bd1d34ac00c75e9280ae4"),
-			UserDataStoreError:   errors.New("Unknow error"),
+			UserDataStoreError:   infrastructure.ErrNotFound,
 			ExpectedStatusCode:   http.StatusNotFound,
 		},
+		{
+			Name:                 "DataStoreFailureTest",
+			UserIDForDeletion:    bson.ObjectIdHex("597bd1d34ac00c75e9280ae4"),
+			AuthenticateAsUserID: bson.ObjectIdHex("597bd1d34ac00c75e9280ae4"),
+			UserDataStoreError:   errors.New("Unknow error"),
+			ExpectedStatusCode:   http.StatusInternalServerError,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -431,8 +616,17 @@ func TestDeleteUserHandler(t *testing.T) {
 			WithAuthorization(testCase.AuthenticateAsUserID.Hex())
 
 		userDataStoreMock := &UserDataStoreMock{
+			OnGetUser: func(userID string) (*models.User, error) {
+				if testCase.UserDataStoreError != nil {
+					return nil, testCase.UserDataStoreError
+				}
+				return &models.User{ID: bson.ObjectIdHex(userID)}, nil
+			},
+			OnGetUserFollowing: func(userID string) ([]models.Follow, error) {
+				return []models.Follow{}, nil
+			},
 			OnDeleteUser: func(userID string) error {
-				return testCase.UserDataStoreError
+				return nil
 			},
 		}
 		cawDataStoreMock := &CawDataStoreMock{}
@@ -448,6 +642,63 @@ func TestDeleteUserHandler(t *testing.T) {
 	}
 }
 
+func TestDeleteUserHandlerPurgesUser(t *testing.T) {
+	t.Parallel()
+	user := models.User{ID: bson.NewObjectId(), Name: "bob"}
+	followed := models.User{ID: bson.NewObjectId(), Name: "alice", FollowersCount: 1}
+	userID := user.ID.Hex()
+
+	var purged []string
+	userDataStoreMock := &UserDataStoreMock{
+		OnGetUser: func(userID string) (*models.User, error) {
+			return &user, nil
+		},
+		OnGetUserFollowing: func(userID string) ([]models.Follow, error) {
+			return []models.Follow{{UserID: followed.ID, Name: followed.Name}}, nil
+		},
+		OnDeleteUser: func(userID string) error {
+			purged = append(purged, "user:"+userID)
+			return nil
+		},
+	}
+	adminDataStoreMock := AdminDataStoreMock{
+		OnRemoveFollows: func(userID string) (int, error) {
+			purged = append(purged, "follows:"+userID)
+			return 1, nil
+		},
+		OnPurgeCaws: func(userID string) (int, error) {
+			purged = append(purged, "caws:"+userID)
+			return 1, nil
+		},
+	}
+	notificationDataStoreMock := NotificationDataStoreMock{
+		OnDeleteByUserID: func(userID string) (int, error) {
+			purged = append(purged, "notifications:"+userID)
+			return 3, nil
+		},
+	}
+	app := New(&utils.AppConfig{}, DataStoreFactoryMock{
+		OnCreateUserDataStore:         func() infrastructure.UserDataStore { return userDataStoreMock },
+		OnCreateCawDataStore:          func() infrastructure.CawDataStore { return CawDataStoreMock{} },
+		OnCreateNotificationDataStore: func() infrastructure.NotificationDataStore { return notificationDataStoreMock },
+		OnCreateAdminDataStore:        func() infrastructure.AdminDataStore { return adminDataStoreMock },
+	}, Logger)
+	app.search.IndexCaw(models.Caw{ID: bson.NewObjectId(), UserID: user.ID, Message: "hello world"})
+	app.names.Put(user)
+	app.names.Put(followed)
+
+	recorder := httptest.NewRecorder()
+	request := NewTestRequest(t, "DELETE", uriBuilder.User().WithUser(userID).Done(), nil).WithAuthorization(userID)
+	app.ServeHTTP(recorder, request.Request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"follows:" + userID, "caws:" + userID, "notifications:" + userID, "user:" + userID}, purged)
+	caws, _ := app.search.SearchCaws("hello", search.CawFilter{}, 0)
+	assert.Empty(t, caws, "purged caws cannot be found")
+	completed := app.names.Complete("alice", nil, 1)
+	assert.Equal(t, uint64(0), completed[0].FollowersCount, "followed user lost follower")
+}
+
 func TestPostUserFollowingHandler(t *testing.T) {
 	t.Parallel()
 	followerID := bson.ObjectIdHex("597bd1d34ac00c75e9280ae4")
@@ -458,6 +709,7 @@ func TestPostUserFollowingHandler(t *testing.T) {
 		Follower               models.Follow
 		Following              models.Follow
 		UserDataStoreError     error
+		AuthenticateAsUserID   string
 		ExpectedLocationHeader string
 		ExpectedStatusCode     int
 	}{
@@ -485,6 +737,30 @@ func TestPostUserFollowingHandler(t *testing.T) {
 			ExpectedLocationHeader: "",
 			ExpectedStatusCode:     http.StatusInternalServerError,
 		},
+		{
+			Name:                   "RepeatedFollowReturnsExistingRelationTest",
+			Follower:               models.Follow{UserID: followerID, Name: "follower user"},
+			Following:              models.Follow{UserID: followingID, Name: "followed user"},
+			UserDataStoreError:     infrastructure.ErrFollowExists,
+			ExpectedLocationHeader: uriBuilder.User().WithUser(followerID.Hex()).Following().WithFollowing(followingID.Hex()).Done(),
+			ExpectedStatusCode:     http.StatusOK,
+		},
+		{
+			Name:                   "SelfFollowTest",
+			Follower:               models.Follow{UserID: followerID, Name: "follower user"},
+			Following:              models.Follow{UserID: followerID, Name: "follower user"},
+			UserDataStoreError:     infrastructure.ErrSelfFollow,
+			ExpectedLocationHeader: "",
+			ExpectedStatusCode:     http.StatusUnprocessableEntity,
+		}, {
+			Name:                   "FollowByUserThatIsNotFollowerTest",
+			Follower:               models.Follow{UserID: followerID, Name: "follower user"},
+			Following:              models.Follow{UserID: followingID, Name: "followed user"},
+			UserDataStoreError:     nil,
+			AuthenticateAsUserID:   followingID.Hex(),
+			ExpectedLocationHeader: "",
+			ExpectedStatusCode:     http.StatusForbidden,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -494,17 +770,34 @@ func TestPostUserFollowingHandler(t *testing.T) {
 			t.Fatal("Cannot marshal follow", err)
 		}
 
+		authenticateAs := testCase.Follower.UserID.Hex()
+		if testCase.AuthenticateAsUserID != "" {
+			authenticateAs = testCase.AuthenticateAsUserID
+		}
 		request := NewTestRequest(t, "POST", uriBuilder.User().WithUser(testCase.Follower.UserID.Hex()).Following().Done(), bytes.NewBuffer(js)).
-			WithAuthorization(testCase.Follower.UserID.Hex())
+			WithAuthorization(authenticateAs)
 
 		userDataStoreMock := &UserDataStoreMock{
 			OnAddFollowingUser: func(followerID string, followedID string) error {
 				return testCase.UserDataStoreError
 			},
+			OnGetUser: func(userID string) (*models.User, error) {
+				return &models.User{ID: testCase.Follower.UserID, Name: testCase.Follower.Name}, nil
+			},
+			OnGetFollowRelation: func(followerID, followingID string) (*models.FollowRelation, error) {
+				return &models.FollowRelation{Follower: testCase.Follower, Following: testCase.Following}, nil
+			},
 		}
 		cawDataStoreMock := &CawDataStoreMock{}
+		var notifications []models.Notification
+		notificationDataStoreMock := NotificationDataStoreMock{
+			OnStore: func(notification models.Notification) (*models.Notification, error) {
+				notifications = append(notifications, notification)
+				return &notification, nil
+			},
+		}
 
-		app := createApp(userDataStoreMock, cawDataStoreMock)
+		app := createAppWithNotifications(userDataStoreMock, cawDataStoreMock, notificationDataStoreMock)
 
 		recorder := httptest.NewRecorder()
 		app.ServeHTTP(recorder, request.Request)
@@ -518,6 +811,30 @@ func TestPostUserFollowingHandler(t *testing.T) {
 			if location != testCase.ExpectedLocationHeader {
 				t.Errorf("Created resource location %v does not equals expected %v", location, testCase.ExpectedLocationHeader)
 			}
+
+			if len(notifications) != 1 || notifications[0].Type != models.NotificationFollow ||
+				notifications[0].UserID != testCase.Following.UserID || !notifications[0].Actor.Equal(testCase.Follower) {
+				t.Errorf("Follow notification was not stored: %v", notifications)
+			}
+		}
+
+		if testCase.ExpectedStatusCode == http.StatusForbidden && len(notifications) != 0 {
+			t.Errorf("Forbidden follow cannot notify: %v", notifications)
+		}
+
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			location := recorder.Header().Get("Location")
+			if location != testCase.ExpectedLocationHeader {
+				t.Errorf("Existing resource location %v does not equals expected %v", location, testCase.ExpectedLocationHeader)
+			}
+
+			var relation models.FollowRelation
+			if err := json.NewDecoder(recorder.Body).Decode(&relation); err != nil || !relation.Following.Equal(testCase.Following) {
+				t.Errorf("Existing follow relation was not returned: %v, err: %v", relation, err)
+			}
+			if len(notifications) != 0 {
+				t.Errorf("Repeated follow cannot notify: %v", notifications)
+			}
 		}
 	}
 }
@@ -594,6 +911,12 @@ func TestDeleteUserFollowing(t *testing.T) {
 
 func createApp(userDataStore infrastructure.UserDataStore,
 	cawDataStore infrastructure.CawDataStore) *App {
+	return createAppWithNotifications(userDataStore, cawDataStore, NotificationDataStoreMock{})
+}
+
+func createAppWithNotifications(userDataStore infrastructure.UserDataStore,
+	cawDataStore infrastructure.CawDataStore,
+	notificationDataStore infrastructure.NotificationDataStore) *App {
 	dataStoreFactoryMock := DataStoreFactoryMock{
 		OnCreateUserDataStore: func() infrastructure.UserDataStore {
 			return userDataStore
@@ -601,6 +924,9 @@ func createApp(userDataStore infrastructure.UserDataStore,
 		OnCreateCawDataStore: func() infrastructure.CawDataStore {
 			return cawDataStore
 		},
+		OnCreateNotificationDataStore: func() infrastructure.NotificationDataStore {
+			return notificationDataStore
+		},
 	}
 
 	return New(&utils.AppConfig{}, dataStoreFactoryMock, Logger)
@@ -630,11 +956,83 @@ func NewTestRequest(t *testing.T, method, uri string, body io.Reader) *TestReque
 }
 
 func (r *TestRequest) WithAuthorization(userID string) *TestRequest {
+	return r.WithTenantAuthorization(userID, "")
+}
+
+func (r *TestRequest) WithTenantAuthorization(userID string, tenant string) *TestRequest {
 	expiresAt := time.Now().Add(time.Duration(1 * time.Minute)).Unix()
-	token, err := utils.NewUserToken(userID, expiresAt)
+	token, err := utils.NewTenantUserToken(userID, tenant, expiresAt)
 	if err != nil {
 		r.t.Fatal(err)
 	}
 	r.Header.Set("Authorization", "Bearer "+token)
 	return r
 }
+
+func TestGetUsersAutocompleteHandler(t *testing.T) {
+	t.Parallel()
+	requestorID := bson.NewObjectId()
+	followedID := bson.NewObjectId()
+	userDataStoreMock := UserDataStoreMock{
+		OnGetUserFollowing: func(userID string) ([]models.Follow, error) {
+			return []models.Follow{{UserID: followedID, Name: "anycmon"}}, nil
+		},
+	}
+	app := createApp(userDataStoreMock, CawDataStoreMock{})
+	defer app.Close()
+	app.names.Put(models.User{ID: bson.NewObjectId(), Name: "anna", FollowersCount: 10, Password: "secret"})
+	app.names.Put(models.User{ID: followedID, Name: "anycmon"})
+
+	var testCases = []struct {
+		Name               string
+		Query              string
+		ExpectedStatusCode int
+		ExpectedNames      []string
+	}{
+		{
+			Name:               "FollowedUsersFirstTest",
+			Query:              "?prefix=an",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedNames:      []string{"anycmon", "anna"},
+		},
+		{
+			Name:               "MentionPrefixTest",
+			Query:              "?prefix=@ann&limit=1",
+			ExpectedStatusCode: http.StatusOK,
+			ExpectedNames:      []string{"anna"},
+		},
+		{
+			Name:               "EmptyPrefixTest",
+			Query:              "?prefix=",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			Name:               "InvalidLimitTest",
+			Query:              "?prefix=an&limit=0",
+			ExpectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		request := NewTestRequest(t, "GET", uriBuilder.User().Autocomplete().Done()+testCase.Query, nil).
+			WithAuthorization(requestorID.Hex())
+		request.Header.Set("Accept", "application/json")
+
+		recorder := httptest.NewRecorder()
+		app.ServeHTTP(recorder, request.Request)
+
+		assert.Equal(t, testCase.ExpectedStatusCode, recorder.Code)
+		if testCase.ExpectedStatusCode == http.StatusOK {
+			var users []models.PublicUser
+			err := json.NewDecoder(recorder.Body).Decode(&users)
+			assert.Nil(t, err)
+			names := []string{}
+			for _, user := range users {
+				names = append(names, user.Name)
+				assert.Empty(t, user.Password)
+			}
+			assert.Equal(t, testCase.ExpectedNames, names)
+		}
+	}
+}
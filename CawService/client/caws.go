@@ -0,0 +1,54 @@
+package client
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"context"
+	"strconv"
+)
+
+// PostCaw posts caw as its author given by caw.UserID
+func (c *Client) PostCaw(ctx context.Context, caw models.Caw) (*models.Caw, error) {
+	var posted models.Caw
+	path := utils.NewUriBuilder().User().WithUser(caw.UserID.Hex()).Caws().Done()
+	if err := c.send(ctx, "POST", path, caw, &posted); err != nil {
+		return nil, err
+	}
+	return &posted, nil
+}
+
+// GetCaw returns caw with cawID if it is visible to authenticated user
+func (c *Client) GetCaw(ctx context.Context, cawID string) (*models.Caw, error) {
+	var caw models.Caw
+	if err := c.get(ctx, utils.NewUriBuilder().Caw().WithCaw(cawID).Done(), &caw); err != nil {
+		return nil, err
+	}
+	return &caw, nil
+}
+
+// GetUserCaws returns page of caws posted by user with userID, the newest first
+func (c *Client) GetUserCaws(ctx context.Context, userID string, page int) ([]models.Caw, error) {
+	caws := []models.Caw{}
+	path := utils.NewUriBuilder().User().WithUser(userID).Caws().Done() +
+		"?order=" + models.OrderNewest + "&page=" + strconv.Itoa(page)
+	if err := c.get(ctx, path, &caws); err != nil {
+		return nil, err
+	}
+	return caws, nil
+}
+
+// EditCaw replaces message of caw posted by user with userID
+func (c *Client) EditCaw(ctx context.Context, userID, cawID, message string) (*models.Caw, error) {
+	var edited models.Caw
+	path := utils.NewUriBuilder().User().WithUser(userID).Caws().WithCaw(cawID).Done()
+	if err := c.send(ctx, "PATCH", path, map[string]string{"message": message}, &edited); err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// DeleteCaw deletes caw posted by user with userID
+func (c *Client) DeleteCaw(ctx context.Context, userID, cawID string) error {
+	path := utils.NewUriBuilder().User().WithUser(userID).Caws().WithCaw(cawID).Done()
+	return c.send(ctx, "DELETE", path, nil, nil)
+}
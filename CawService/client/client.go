@@ -0,0 +1,343 @@
+// Package client provides typed Go client of Caw API. Payloads are exchanged as
+// models types and failed requests are reported as models.Problem errors.
+package client
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	appJSON = "application/json"
+
+	defaultMaxRetries    = 3
+	defaultRetryBackoff  = 200 * time.Millisecond
+	defaultRefreshMargin = time.Minute
+	// maxRetryAfter caps delay requested by server in Retry-After header
+	maxRetryAfter = 30 * time.Second
+)
+
+// ErrNotAuthenticated is returned by requests requiring token before Login was called
+var ErrNotAuthenticated = errors.New("Client is not authenticated")
+
+// Client calls Caw API. Client is safe for concurrent use.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	maxRetries    int
+	retryBackoff  time.Duration
+	refreshMargin time.Duration
+	tenant        string
+
+	mu    sync.Mutex
+	auth  *models.Auth
+	token *models.Token
+	// refreshing is closed when refresh of token in progress finishes
+	refreshing chan struct{}
+}
+
+// Option configures Client
+type Option func(c *Client)
+
+// WithHTTPClient makes Client send requests with httpClient instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries configures how many times failed requests are repeated. Idempotent requests
+// are repeated on 5xx or 429, other requests only on 503 or 429 which are not processed
+// by the service. Delay between attempts starts at backoff and doubles with every attempt.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithRefreshMargin configures how long before expiration token is refreshed
+func WithRefreshMargin(margin time.Duration) Option {
+	return func(c *Client) {
+		c.refreshMargin = margin
+	}
+}
+
+// WithToken makes Client use previously obtained token. Token cannot be refreshed
+// until Login is called.
+func WithToken(token models.Token) Option {
+	return func(c *Client) {
+		c.token = &token
+	}
+}
+
+// WithTenant sends tenant in header of every request, it is needed when service
+// identifies tenants by token claim and requests have no token yet
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// New creates Client of API served at baseURL, e.g. http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		httpClient:    http.DefaultClient,
+		maxRetries:    defaultMaxRetries,
+		retryBackoff:  defaultRetryBackoff,
+		refreshMargin: defaultRefreshMargin,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Login authenticates user and keeps credentials, so token can be refreshed before it expires
+func (c *Client) Login(ctx context.Context, name, password string) (*models.Token, error) {
+	auth := models.Auth{Name: name, Password: password}
+	token, err := c.authenticate(ctx, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auth = &auth
+	c.token = token
+	return token, nil
+}
+
+// Token returns current token or nil if Client is not authenticated
+func (c *Client) Token() *models.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == nil {
+		return nil
+	}
+	token := *c.token
+	return &token
+}
+
+// UserID returns id of authenticated user read from claims of current token
+func (c *Client) UserID() (string, error) {
+	token := c.Token()
+	if token == nil {
+		return "", ErrNotAuthenticated
+	}
+	parts := strings.Split(token.AccessToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("Access token is not JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	var claims utils.UserClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", err
+	}
+	return claims.UserId, nil
+}
+
+func (c *Client) authenticate(ctx context.Context, auth models.Auth) (*models.Token, error) {
+	var token models.Token
+	if err := c.do(ctx, "POST", utils.NewUriBuilder().Auth().Done(), auth, "", &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// accessToken returns token valid for at least refresh margin, refreshing it with stored
+// credentials. Token is refreshed without holding the lock and concurrent requests wait
+// for refresh in progress instead of starting their own.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	for {
+		c.mu.Lock()
+		if c.token == nil {
+			c.mu.Unlock()
+			return "", ErrNotAuthenticated
+		}
+		if c.auth == nil || time.Now().Add(c.refreshMargin).Before(c.token.ExpiresAt) {
+			token := c.token.AccessToken
+			c.mu.Unlock()
+			return token, nil
+		}
+		if refreshing := c.refreshing; refreshing != nil {
+			c.mu.Unlock()
+			select {
+			case <-refreshing:
+				continue
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		auth, refreshing := c.auth, make(chan struct{})
+		c.refreshing = refreshing
+		c.mu.Unlock()
+
+		token, err := c.authenticate(ctx, *auth)
+
+		c.mu.Lock()
+		c.refreshing = nil
+		// token of Login called during refresh is kept
+		if err == nil && c.auth == auth {
+			c.token = token
+		}
+		c.mu.Unlock()
+		close(refreshing)
+		if err != nil {
+			return "", err
+		}
+		return token.AccessToken, nil
+	}
+}
+
+// get sends authenticated GET request and decodes response into result
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "GET", path, nil, token, result)
+}
+
+// send sends authenticated request with JSON body and decodes response into result
+func (c *Client) send(ctx context.Context, method, path string, body interface{}, result interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, method, path, body, token, result)
+}
+
+// do sends request with body encoded as JSON and decodes JSON response into result.
+// Failed requests which are safe to repeat are repeated until retries are exhausted.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, token string, result interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequest(method, c.baseURL+path, reader)
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", appJSON)
+		if payload != nil {
+			req.Header.Set("Content-Type", appJSON)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if c.tenant != "" {
+			req.Header.Set(utils.TenantHeader, c.tenant)
+		}
+
+		res, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if !isRetryable(method, res.StatusCode) || attempt >= c.maxRetries {
+			return decodeResponse(res, result)
+		}
+
+		delay := c.retryDelay(attempt, res)
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay returns delay requested by Retry-After header or exponential backoff
+func (c *Client) retryDelay(attempt int, res *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		delay := time.Duration(seconds) * time.Second
+		if delay > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return delay
+	}
+	return c.retryBackoff << uint(attempt)
+}
+
+// fallbackCode returns problem code describing status of response without problem details
+func fallbackCode(status int) string {
+	switch {
+	case status == http.StatusUnauthorized:
+		return models.CodeUnauthenticated
+	case status == http.StatusForbidden:
+		return models.CodeForbidden
+	case status == http.StatusNotFound:
+		return models.CodeNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return models.CodePayloadTooLarge
+	case status == http.StatusTooManyRequests:
+		return models.CodeRateLimited
+	case status >= http.StatusInternalServerError:
+		return models.CodeInternalError
+	default:
+		return models.CodeInvalidPayload
+	}
+}
+
+// isRetryable returns true if request failed with status can be repeated. Request of
+// not idempotent method could be processed before it failed, so it is repeated only
+// when status says it was rejected.
+func isRetryable(method string, status int) bool {
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		return true
+	}
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE", "OPTIONS":
+		return status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// decodeResponse decodes successful response into result and failed one into models.Problem
+func decodeResponse(res *http.Response, result interface{}) error {
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusBadRequest {
+		var problem models.Problem
+		if err := json.NewDecoder(res.Body).Decode(&problem); err != nil || problem.Code == "" {
+			// responses of proxies or router are not problem details
+			problem = models.NewProblem(res.StatusCode, fallbackCode(res.StatusCode), res.Status)
+		}
+		return problem
+	}
+	if result == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil || len(body) == 0 {
+		return err
+	}
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"Caw/UserService/app"
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = logrus.New()
+
+// testServer serves real App backed by memoryStore and counts authentication requests
+type testServer struct {
+	*httptest.Server
+	app            *app.App
+	authentication int32
+	requests       int32
+	// failures is number of next requests answered with failureStatus before reaching App
+	failures      int32
+	failureStatus int
+}
+
+func newTestServer() *testServer {
+	s := &testServer{failureStatus: http.StatusServiceUnavailable}
+	s.app = app.New(&utils.AppConfig{TokenExpiresInMinutes: 60}, newMemoryStore(), logger)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		if atomic.AddInt32(&s.failures, -1) >= 0 {
+			w.WriteHeader(s.failureStatus)
+			return
+		}
+		if r.URL.Path == utils.NewUriBuilder().Auth().Done() {
+			atomic.AddInt32(&s.authentication, 1)
+		}
+		s.app.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+func (s *testServer) Close() {
+	s.Server.Close()
+	s.app.Close()
+}
+
+func createUser(t *testing.T, c *Client, name string) *models.User {
+	user, err := c.CreateUser(context.Background(), models.User{
+		Name:     name,
+		Email:    name + "@caw.com",
+		Password: "foobar12",
+	})
+	if err != nil {
+		t.Fatalf("Cannot create user %s. err: %s", name, err)
+	}
+	return user
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	c := New(server.URL)
+	alice := createUser(t, c, "alice")
+	bob := createUser(t, c, "bob")
+
+	_, err := c.GetUser(ctx, alice.ID.Hex())
+	assert.Equal(t, ErrNotAuthenticated, err)
+
+	token, err := c.Login(ctx, "alice", "foobar12")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer", token.Type)
+	userID, err := c.UserID()
+	assert.Nil(t, err)
+	assert.Equal(t, alice.ID.Hex(), userID)
+
+	user, err := c.GetUser(ctx, bob.ID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", user.Name)
+
+	assert.Nil(t, c.Follow(ctx, alice.ID.Hex(), bob.ID.Hex()))
+	assert.Nil(t, c.Follow(ctx, alice.ID.Hex(), bob.ID.Hex()), "repeated follow has to succeed")
+	followers, err := c.Followers(ctx, bob.ID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, []models.Follow{{UserID: alice.ID, Name: "alice"}}, followers)
+	assert.Nil(t, c.Unfollow(ctx, alice.ID.Hex(), bob.ID.Hex()))
+	following, err := c.Following(ctx, alice.ID.Hex())
+	assert.Nil(t, err)
+	assert.Empty(t, following)
+
+	caw, err := c.PostCaw(ctx, models.Caw{UserID: alice.ID, Message: "hello #caw"})
+	assert.Nil(t, err)
+	assert.Equal(t, "hello #caw", caw.Message)
+	caw, err = c.EditCaw(ctx, alice.ID.Hex(), caw.ID.Hex(), "hello #go")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, caw.EditCount)
+	fetched, err := c.GetCaw(ctx, caw.ID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, "hello #go", fetched.Message)
+	newest, err := c.PostCaw(ctx, models.Caw{UserID: alice.ID, Message: "newest"})
+	assert.Nil(t, err)
+	caws, err := c.GetUserCaws(ctx, alice.ID.Hex(), 0)
+	assert.Nil(t, err)
+	if assert.Len(t, caws, 2) {
+		assert.Equal(t, newest.ID, caws[0].ID, "caws are listed the newest first")
+	}
+	assert.Nil(t, c.DeleteCaw(ctx, alice.ID.Hex(), caw.ID.Hex()))
+
+	_, err = c.GetCaw(ctx, caw.ID.Hex())
+	problem, ok := err.(models.Problem)
+	assert.True(t, ok, "error has to be problem details, given %v", err)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, models.CodeCawNotFound, problem.Code)
+
+	_, err = c.PostCaw(ctx, models.Caw{UserID: alice.ID, Message: ""})
+	problem, ok = err.(models.Problem)
+	assert.True(t, ok, "error has to be problem details, given %v", err)
+	assert.Equal(t, models.CodeValidationFailed, problem.Code)
+}
+
+func TestClientRefreshesToken(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	// tokens expire in an hour, so margin longer than that refreshes token before every request
+	c := New(server.URL, WithRefreshMargin(2*time.Hour))
+	user := createUser(t, c, "alice")
+	_, err := c.Login(ctx, "alice", "foobar12")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.authentication))
+
+	_, err = c.GetUser(ctx, user.ID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.authentication))
+
+	c = New(server.URL, WithToken(*c.Token()))
+	_, err = c.GetUser(ctx, user.ID.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.authentication), "token without credentials cannot be refreshed")
+}
+
+func TestClientRefreshesTokenOnce(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	c := New(server.URL)
+	user := createUser(t, c, "alice")
+	_, err := c.Login(ctx, "alice", "foobar12")
+	assert.Nil(t, err)
+	c.mu.Lock()
+	c.token.ExpiresAt = time.Now()
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetUser(ctx, user.ID.Hex())
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.authentication), "concurrent requests refresh token once")
+}
+
+func TestClientRetries(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Method           string
+		Failures         int32
+		FailureStatus    int
+		MaxRetries       int
+		ExpectedStatus   int
+		ExpectedRequests int32
+	}{
+		{"RetriedServiceUnavailableTest", "POST", 2, http.StatusServiceUnavailable, 2, http.StatusOK, 3},
+		{"RetriedTooManyRequestsTest", "POST", 1, http.StatusTooManyRequests, 2, http.StatusOK, 2},
+		{"NotRetriedInternalErrorOfPostTest", "POST", 1, http.StatusInternalServerError, 2, http.StatusInternalServerError, 1},
+		{"RetriedInternalErrorOfGetTest", "GET", 1, http.StatusInternalServerError, 2, http.StatusOK, 2},
+		{"RetriesExhaustedTest", "GET", 3, http.StatusInternalServerError, 2, http.StatusInternalServerError, 3},
+		{"NotRetriedClientErrorTest", "POST", 1, http.StatusBadRequest, 2, http.StatusBadRequest, 1},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		server := newTestServer()
+		ctx := context.Background()
+		c := New(server.URL, WithRetries(testCase.MaxRetries, time.Millisecond))
+		var err error
+		if testCase.Method == "GET" {
+			user := createUser(t, c, "alice")
+			_, err = c.Login(ctx, "alice", "foobar12")
+			assert.Nil(t, err)
+			atomic.StoreInt32(&server.requests, 0)
+			atomic.StoreInt32(&server.failures, testCase.Failures)
+			server.failureStatus = testCase.FailureStatus
+			_, err = c.GetUser(ctx, user.ID.Hex())
+		} else {
+			atomic.StoreInt32(&server.failures, testCase.Failures)
+			server.failureStatus = testCase.FailureStatus
+			_, err = c.CreateUser(ctx, models.User{Name: "alice", Email: "alice@caw.com", Password: "foobar12"})
+		}
+		if testCase.ExpectedStatus == http.StatusOK {
+			assert.Nil(t, err)
+		} else {
+			problem, ok := err.(models.Problem)
+			assert.True(t, ok, "error has to be problem details, given %v", err)
+			assert.Equal(t, testCase.ExpectedStatus, problem.Status)
+		}
+		assert.Equal(t, testCase.ExpectedRequests, atomic.LoadInt32(&server.requests), "requests count")
+		server.Close()
+	}
+}
+
+func TestClientCanceledContext(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	atomic.StoreInt32(&server.failures, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := New(server.URL, WithRetries(10, time.Hour))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := c.CreateUser(ctx, models.User{Name: "alice", Email: "alice@caw.com", Password: "foobar12"})
+	assert.Equal(t, context.Canceled, err)
+}
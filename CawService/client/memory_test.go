@@ -0,0 +1,386 @@
+package client
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// memoryStore keeps users, follows and caws in memory, so real App can be served in tests
+type memoryStore struct {
+	mu      sync.Mutex
+	users   map[string]models.User
+	follows []models.FollowRelation
+	caws    map[string]models.Caw
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users: map[string]models.User{},
+		caws:  map[string]models.Caw{},
+	}
+}
+
+func (s *memoryStore) CreateUserDataStore() infrastructure.UserDataStore {
+	return memoryUserDataStore{s}
+}
+
+func (s *memoryStore) CreateCawDataStore() infrastructure.CawDataStore {
+	return memoryCawDataStore{s}
+}
+
+func (s *memoryStore) CreateNotificationDataStore() infrastructure.NotificationDataStore {
+	return memoryNotificationDataStore{}
+}
+
+func (s *memoryStore) CreateCounterDataStore() infrastructure.CounterDataStore {
+	return memoryCounterDataStore{}
+}
+
+func (s *memoryStore) CreateAdminDataStore() infrastructure.AdminDataStore {
+	return memoryAdminDataStore{s}
+}
+
+func (s *memoryStore) Close() {}
+
+type memoryUserDataStore struct {
+	*memoryStore
+}
+
+func (ds memoryUserDataStore) GetUser(userID string) (*models.User, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	user, ok := ds.users[userID]
+	if !ok {
+		return nil, infrastructure.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (ds memoryUserDataStore) IsSuspended(userID string) (bool, error) {
+	user, err := ds.GetUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Suspended, nil
+}
+
+func (ds memoryUserDataStore) GetUserByName(name string) (*models.User, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, user := range ds.users {
+		if user.Name == name {
+			return &user, nil
+		}
+	}
+	return nil, infrastructure.ErrNotFound
+}
+
+func (ds memoryUserDataStore) StoreUser(user models.User) (*models.User, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, other := range ds.users {
+		if other.Name == user.Name || other.Email == user.Email {
+			return nil, infrastructure.ErrUserExists
+		}
+	}
+	user.ID = bson.NewObjectId()
+	ds.users[user.ID.Hex()] = user
+	return &user, nil
+}
+
+func (ds memoryUserDataStore) DeleteUser(userID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if _, ok := ds.users[userID]; !ok {
+		return infrastructure.ErrNotFound
+	}
+	delete(ds.users, userID)
+	return nil
+}
+
+func (ds memoryUserDataStore) ForEachUser(fn func(user models.User) error) error {
+	ds.mu.Lock()
+	users := []models.User{}
+	for _, user := range ds.users {
+		users = append(users, user)
+	}
+	ds.mu.Unlock()
+	for _, user := range users {
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ds memoryUserDataStore) GetUserFollowers(userID string) ([]models.Follow, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	followers := []models.Follow{}
+	for _, relation := range ds.follows {
+		if relation.Following.UserID.Hex() == userID {
+			followers = append(followers, relation.Follower)
+		}
+	}
+	return followers, nil
+}
+
+func (ds memoryUserDataStore) GetUserFollowing(userID string) ([]models.Follow, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	following := []models.Follow{}
+	for _, relation := range ds.follows {
+		if relation.Follower.UserID.Hex() == userID {
+			following = append(following, relation.Following)
+		}
+	}
+	return following, nil
+}
+
+func (ds memoryUserDataStore) AddFollowingUser(followerID string, followingID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if followerID == followingID {
+		return infrastructure.ErrSelfFollow
+	}
+	follower, ok := ds.users[followerID]
+	following, ok2 := ds.users[followingID]
+	if !ok || !ok2 {
+		return infrastructure.ErrNotFound
+	}
+	for _, relation := range ds.follows {
+		if relation.Follower.UserID == follower.ID && relation.Following.UserID == following.ID {
+			return infrastructure.ErrFollowExists
+		}
+	}
+	ds.follows = append(ds.follows, models.FollowRelation{
+		Follower:  models.Follow{UserID: follower.ID, Name: follower.Name},
+		Following: models.Follow{UserID: following.ID, Name: following.Name},
+	})
+	follower.FollowingCount++
+	following.FollowersCount++
+	ds.users[followerID] = follower
+	ds.users[followingID] = following
+	return nil
+}
+
+func (ds memoryUserDataStore) UnfollowUser(followerID string, followingID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for i, relation := range ds.follows {
+		if relation.Follower.UserID.Hex() == followerID && relation.Following.UserID.Hex() == followingID {
+			ds.follows = append(ds.follows[:i], ds.follows[i+1:]...)
+			return nil
+		}
+	}
+	return infrastructure.ErrNotFound
+}
+
+func (ds memoryUserDataStore) GetFollowRelation(followerID string, followingID string) (*models.FollowRelation, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, relation := range ds.follows {
+		if relation.Follower.UserID.Hex() == followerID && relation.Following.UserID.Hex() == followingID {
+			return &relation, nil
+		}
+	}
+	return nil, infrastructure.ErrNotFound
+}
+
+func (ds memoryUserDataStore) GetMutuals(userID string, page int) ([]models.Follow, error) {
+	return []models.Follow{}, nil
+}
+
+func (ds memoryUserDataStore) GetFollowSuggestions(userID string, limit int) ([]models.Suggestion, error) {
+	return []models.Suggestion{}, nil
+}
+
+func (ds memoryUserDataStore) Close() {}
+
+type memoryCawDataStore struct {
+	*memoryStore
+}
+
+func (ds memoryCawDataStore) Store(caw models.Caw) (*models.Caw, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	caw.ID = bson.NewObjectId()
+	caw.CreatedAt = time.Now()
+	ds.caws[caw.ID.Hex()] = caw
+	return &caw, nil
+}
+
+func (ds memoryCawDataStore) GetByID(cawID string, viewerID string) (*models.Caw, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	caw, ok := ds.caws[cawID]
+	if !ok {
+		return nil, infrastructure.ErrNotFound
+	}
+	return &caw, nil
+}
+
+func (ds memoryCawDataStore) GetByUserID(userID string, viewerID string, page int, newestFirst bool) ([]models.Caw, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	caws := []models.Caw{}
+	for _, caw := range ds.caws {
+		if caw.UserID.Hex() == userID {
+			caws = append(caws, caw)
+		}
+	}
+	sort.Slice(caws, func(i, j int) bool {
+		if newestFirst {
+			return caws[i].CreatedAt.After(caws[j].CreatedAt)
+		}
+		return caws[i].CreatedAt.Before(caws[j].CreatedAt)
+	})
+	return caws, nil
+}
+
+func (ds memoryCawDataStore) GetByHashtag(hashtag string, viewerID string, page int) ([]models.Caw, error) {
+	return []models.Caw{}, nil
+}
+
+func (ds memoryCawDataStore) GetByMention(userID string, viewerID string, page int) ([]models.Caw, error) {
+	return []models.Caw{}, nil
+}
+
+func (ds memoryCawDataStore) ForEachPublic(fn func(caw models.Caw) error) error {
+	return nil
+}
+
+func (ds memoryCawDataStore) Edit(cawID string, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	caw, ok := ds.caws[cawID]
+	if !ok {
+		return nil, infrastructure.ErrNotFound
+	}
+	if caw.EditCount >= maxEdits {
+		return nil, infrastructure.ErrEditLimit
+	}
+	now := time.Now()
+	caw.Message = message
+	caw.Mentions = mentions
+	caw.EditedAt = &now
+	caw.EditCount++
+	ds.caws[cawID] = caw
+	return &caw, nil
+}
+
+func (ds memoryCawDataStore) GetRevisions(cawID string) ([]models.CawRevision, error) {
+	return []models.CawRevision{}, nil
+}
+
+func (ds memoryCawDataStore) Delete(cawID string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if _, ok := ds.caws[cawID]; !ok {
+		return infrastructure.ErrNotFound
+	}
+	delete(ds.caws, cawID)
+	return nil
+}
+
+func (ds memoryCawDataStore) Close() {}
+
+type memoryNotificationDataStore struct{}
+
+func (ds memoryNotificationDataStore) Store(notification models.Notification) (*models.Notification, error) {
+	notification.ID = bson.NewObjectId()
+	return &notification, nil
+}
+
+func (ds memoryNotificationDataStore) GetByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	return []models.Notification{}, nil
+}
+
+func (ds memoryNotificationDataStore) GetGroupedByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	return []models.Notification{}, nil
+}
+
+func (ds memoryNotificationDataStore) CountUnread(userID string) (int, error) {
+	return 0, nil
+}
+
+func (ds memoryNotificationDataStore) MarkAsRead(userID string, notificationIDs []string) (int, error) {
+	return 0, nil
+}
+
+func (ds memoryNotificationDataStore) MarkAllAsRead(userID string) (int, error) {
+	return 0, nil
+}
+
+func (ds memoryNotificationDataStore) DeleteByUserID(userID string) (int, error) {
+	return 0, nil
+}
+
+func (ds memoryNotificationDataStore) Close() {}
+
+type memoryCounterDataStore struct{}
+
+func (ds memoryCounterDataStore) ReconcileUsers(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return &infrastructure.ReconcileResult{Done: true}, nil
+}
+
+func (ds memoryCounterDataStore) ReconcileCaws(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return &infrastructure.ReconcileResult{Done: true}, nil
+}
+
+func (ds memoryCounterDataStore) Close() {}
+
+// memoryAdminDataStore implements operations App uses to delete users, the rest does nothing
+type memoryAdminDataStore struct {
+	*memoryStore
+}
+
+func (ds memoryAdminDataStore) SetSuspended(userID string, suspended bool) error {
+	return nil
+}
+
+func (ds memoryAdminDataStore) SetPassword(userID string, passwordHash string) error {
+	return nil
+}
+
+func (ds memoryAdminDataStore) RemoveFollows(userID string) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	kept := []models.FollowRelation{}
+	for _, relation := range ds.follows {
+		if relation.Follower.UserID.Hex() != userID && relation.Following.UserID.Hex() != userID {
+			kept = append(kept, relation)
+		}
+	}
+	removed := len(ds.follows) - len(kept)
+	ds.follows = kept
+	return removed, nil
+}
+
+func (ds memoryAdminDataStore) PurgeCaws(userID string) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	removed := 0
+	for cawID, caw := range ds.caws {
+		if caw.UserID.Hex() == userID {
+			delete(ds.caws, cawID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (ds memoryAdminDataStore) EnsureIndexes() error {
+	return nil
+}
+
+func (ds memoryAdminDataStore) Stats() ([]infrastructure.CollectionStats, error) {
+	return nil, nil
+}
+
+func (ds memoryAdminDataStore) Close() {}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"Caw/UserService/models"
+	"Caw/UserService/utils"
+	"context"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CreateUser registers user. Password is sent in plain text and stored hashed.
+func (c *Client) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
+	var created models.User
+	if err := c.do(ctx, "POST", utils.NewUriBuilder().User().Done(), user, "", &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetUser returns user with userID
+func (c *Client) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	if err := c.get(ctx, utils.NewUriBuilder().User().WithUser(userID).Done(), &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser deletes account of authenticated user with userID
+func (c *Client) DeleteUser(ctx context.Context, userID string) error {
+	return c.send(ctx, "DELETE", utils.NewUriBuilder().User().WithUser(userID).Done(), nil, nil)
+}
+
+// Followers returns users following user with userID
+func (c *Client) Followers(ctx context.Context, userID string) ([]models.Follow, error) {
+	followers := []models.Follow{}
+	if err := c.get(ctx, utils.NewUriBuilder().User().WithUser(userID).Followers().Done(), &followers); err != nil {
+		return nil, err
+	}
+	return followers, nil
+}
+
+// Following returns users followed by user with userID
+func (c *Client) Following(ctx context.Context, userID string) ([]models.Follow, error) {
+	following := []models.Follow{}
+	if err := c.get(ctx, utils.NewUriBuilder().User().WithUser(userID).Following().Done(), &following); err != nil {
+		return nil, err
+	}
+	return following, nil
+}
+
+// Follow makes user with userID follow user with followingID. Following already
+// followed user succeeds.
+func (c *Client) Follow(ctx context.Context, userID, followingID string) error {
+	if !bson.IsObjectIdHex(followingID) {
+		return models.NewValidationProblem([]models.FieldError{{
+			Field:   "user_id",
+			Code:    models.FieldInvalid,
+			Message: "Followed user id is invalid",
+		}})
+	}
+	follow := models.Follow{UserID: bson.ObjectIdHex(followingID)}
+	return c.send(ctx, "POST", utils.NewUriBuilder().User().WithUser(userID).Following().Done(), follow, nil)
+}
+
+// Unfollow makes user with userID stop following user with followingID
+func (c *Client) Unfollow(ctx context.Context, userID, followingID string) error {
+	path := utils.NewUriBuilder().User().WithUser(userID).Following().WithFollowing(followingID).Done()
+	return c.send(ctx, "DELETE", path, nil, nil)
+}
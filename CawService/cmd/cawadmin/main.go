@@ -0,0 +1,415 @@
+// Command cawadmin performs maintenance tasks directly on the datastore,
+// MongoDB or SQLite, configured in config.yaml of the current directory.
+// The HTTP server does not have to be running.
+//
+// Usage:
+//
+//	cawadmin [-tenant T] <command> [arguments]
+//
+// In multi-tenant mode -tenant selects database of one of configured tenants.
+// Passwords of create-user and reset-password are read from CAWADMIN_PASSWORD
+// or stdin.
+package main
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"Caw/UserService/reconciler"
+	"Caw/UserService/utils"
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+)
+
+const usage = `Usage: cawadmin [-tenant T] <command> [arguments]
+
+Commands:
+  create-user <name> <email>   create user, password is read from CAWADMIN_PASSWORD or stdin
+  suspend <userID>             prevent user from authenticating and reject issued tokens
+  unsuspend <userID>           allow suspended user to authenticate again
+  delete-user <userID>         delete user with follows, caws, revisions and notifications
+  reset-password <userID>      set new password, read from CAWADMIN_PASSWORD or stdin
+  list-caws [-page N] <userID> list caws of user including not public ones
+  purge-caws <userID>          delete all caws of user and their revisions
+  repair-follows               remove duplicated and self follows, recompute follow counters (mongo)
+  reconcile                    recompute denormalized user and caw counters, fix drift
+  migrate [-dry-run]           apply pending migrations, with -dry-run only print them
+  ensure-indexes               create indexes of all collections, with sqlite apply migrations
+  stats                        print document counts, sizes and indexes of collections or tables
+
+Running services keep search index in memory, restart them after delete-user or purge-caws
+so removed caws and users cannot be found anymore.
+
+Flags:
+`
+
+func main() {
+	tenant := flag.String("tenant", "", "tenant whose database is maintained, required in multi-tenant mode")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	appConfig := utils.New()
+	logger := logrus.New()
+	database, err := tenantDatabase(appConfig, *tenant)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cawadmin: %s\n", err)
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+	var factory infrastructure.DataStoreFactory
+	switch appConfig.Store {
+	case utils.StoreMongo:
+		session, err := mgo.Dial(appConfig.Mongo)
+		if err != nil {
+			logger.Fatalf("Cannot connect to mongo %s. err: %s", appConfig.Mongo, err)
+		}
+		defer session.Close()
+
+		// repair-follows and migrate work on the session, so they do not depend on
+		// schema expected by data stores
+		switch command {
+		case "repair-follows":
+			repairFollows(session.DB(database), logger)
+			return
+		case "migrate":
+			migrate(func(dryRun bool) ([]infrastructure.MigrationResult, error) {
+				return infrastructure.Migrate(session.DB(database), logger, dryRun)
+			}, logger, args)
+			return
+		}
+		factory = infrastructure.NewFactory(session.Clone(), database, logger)
+	case utils.StoreSQLite:
+		if factory = openSQLite(appConfig, database, command, args, logger); factory == nil {
+			return
+		}
+	default:
+		logger.Fatalf("Unsupported store %q, expected %s or %s", appConfig.Store, utils.StoreMongo, utils.StoreSQLite)
+	}
+
+	admin := admin{
+		factory:  factory,
+		database: database,
+		config:   appConfig,
+		logger:   logger,
+	}
+	defer admin.factory.Close()
+
+	switch command {
+	case "create-user":
+		err = admin.createUser(args)
+	case "suspend":
+		err = admin.suspend(args, true)
+	case "unsuspend":
+		err = admin.suspend(args, false)
+	case "delete-user":
+		err = admin.deleteUser(args)
+	case "reset-password":
+		err = admin.resetPassword(args)
+	case "list-caws":
+		err = admin.listCaws(args)
+	case "purge-caws":
+		err = admin.purgeCaws(args)
+	case "reconcile":
+		err = admin.reconcile()
+	case "ensure-indexes":
+		err = admin.ensureIndexes()
+	case "stats":
+		err = admin.stats()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cawadmin: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// tenantDatabase returns name of database of tenant, tenant has to be given only in multi-tenant mode
+func tenantDatabase(appConfig *utils.AppConfig, tenant string) (string, error) {
+	if len(appConfig.Tenants) == 0 {
+		if tenant != "" {
+			return "", errors.New("tenants are not configured, -tenant cannot be used")
+		}
+		return appConfig.Database, nil
+	}
+	for _, configured := range appConfig.Tenants {
+		if configured == tenant {
+			return infrastructure.TenantDatabase(appConfig.Database, tenant), nil
+		}
+	}
+	return "", fmt.Errorf("-tenant has to be one of configured tenants %v", appConfig.Tenants)
+}
+
+func repairFollows(database *mgo.Database, logger *logrus.Logger) {
+	report, err := infrastructure.RepairFollowRelations(database, logger)
+	if err != nil {
+		logger.Fatalf("Cannot repair follow relations. err: %s", err)
+	}
+	fmt.Printf("duplicates removed: %d\nself follows removed: %d\nusers updated: %d\n",
+		report.DuplicatesRemoved, report.SelfFollowsRemoved, report.UsersUpdated)
+}
+
+// migrate applies pending migrations of the store by run
+func migrate(run func(dryRun bool) ([]infrastructure.MigrationResult, error), logger *logrus.Logger, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print pending migrations without applying them")
+	flags.Parse(args)
+
+	results, err := run(*dryRun)
+	for _, result := range results {
+		if result.Destructive {
+			fmt.Printf("%d %s (deletes data)\n", result.Version, result.Description)
+		} else {
+			fmt.Printf("%d %s\n", result.Version, result.Description)
+		}
+		for _, change := range result.Changes {
+			fmt.Printf("    %s\n", change)
+		}
+	}
+	if err != nil {
+		logger.Fatalf("Cannot migrate. err: %s", err)
+	}
+	switch {
+	case len(results) == 0:
+		fmt.Println("database is up to date")
+	case *dryRun:
+		fmt.Printf("%d migrations pending\n", len(results))
+	default:
+		fmt.Printf("%d migrations applied\n", len(results))
+	}
+}
+
+type admin struct {
+	factory  infrastructure.DataStoreFactory
+	database string
+	config   *utils.AppConfig
+	logger   *logrus.Logger
+}
+
+// limits returns the same validation limits as App uses for payloads
+func (a admin) limits() models.Limits {
+	return models.Limits{
+		NameMinLength:     a.config.UserNameMinLength,
+		NameMaxLength:     a.config.UserNameMaxLength,
+		PasswordMinLength: a.config.PasswordMinLength,
+		CawMaxLength:      a.config.CawMaxLength,
+	}.WithDefaults()
+}
+
+func (a admin) createUser(args []string) error {
+	if len(args) != 2 {
+		return errors.New("create-user requires name and email")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	user := models.User{Name: args[0], Email: args[1], Password: password}
+	if err := validationError(user.Validate(a.limits())); err != nil {
+		return err
+	}
+
+	user.Password, err = utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.CreatedAt = time.Now()
+	ds := a.factory.CreateUserDataStore()
+	defer ds.Close()
+	stored, err := ds.StoreUser(user)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("user created: %s\n", stored.ID.Hex())
+	return nil
+}
+
+func (a admin) suspend(args []string, suspended bool) error {
+	if len(args) != 1 {
+		return errors.New("user id is required")
+	}
+	ds := a.factory.CreateAdminDataStore()
+	defer ds.Close()
+	if err := ds.SetSuspended(args[0], suspended); err != nil {
+		return err
+	}
+	if suspended {
+		fmt.Println("user suspended, issued tokens are rejected")
+	} else {
+		fmt.Println("user unsuspended")
+	}
+	return nil
+}
+
+// searchIndexNote is printed after caws or users are removed, cawadmin works directly on the datastore
+// and cannot reach search index held in memory of running service instances
+const searchIndexNote = "restart service instances to remove deleted caws and users from search index"
+
+// deleteUser removes user with everything referencing the user, the same way as
+// DELETE /v1/users/{userID} does
+func (a admin) deleteUser(args []string) error {
+	if len(args) != 1 {
+		return errors.New("user id is required")
+	}
+	report, err := infrastructure.PurgeUser(a.factory, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("follows removed: %d\ncaws removed: %d\nnotifications removed: %d\nuser deleted\n%s\n",
+		report.FollowsRemoved, report.CawsRemoved, report.NotificationsRemoved, searchIndexNote)
+	return nil
+}
+
+func (a admin) resetPassword(args []string) error {
+	if len(args) != 1 {
+		return errors.New("user id is required")
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	user := models.User{Password: password}
+	fieldErrors := []models.FieldError{}
+	for _, fieldError := range user.Validate(a.limits()) {
+		if fieldError.Field == "password" {
+			fieldErrors = append(fieldErrors, fieldError)
+		}
+	}
+	if err := validationError(fieldErrors); err != nil {
+		return err
+	}
+
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	ds := a.factory.CreateAdminDataStore()
+	defer ds.Close()
+	if err := ds.SetPassword(args[0], passwordHash); err != nil {
+		return err
+	}
+	fmt.Println("password reset")
+	return nil
+}
+
+func (a admin) listCaws(args []string) error {
+	flags := flag.NewFlagSet("list-caws", flag.ExitOnError)
+	page := flags.Int("page", 0, "page of caws, the oldest first")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("user id is required")
+	}
+	userID := flags.Arg(0)
+
+	ds := a.factory.CreateCawDataStore()
+	defer ds.Close()
+	// user as viewer sees own caws of every visibility
+	caws, err := ds.GetByUserID(userID, userID, *page, false)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tVISIBILITY\tEDITS\tMESSAGE")
+	for _, caw := range caws {
+		visibility := caw.Visibility
+		if visibility == "" {
+			visibility = models.VisibilityPublic
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", caw.ID.Hex(), caw.CreatedAt.Format(time.RFC3339),
+			visibility, caw.EditCount, strings.Join(strings.Fields(caw.Message), " "))
+	}
+	return tw.Flush()
+}
+
+func (a admin) purgeCaws(args []string) error {
+	if len(args) != 1 {
+		return errors.New("user id is required")
+	}
+	ds := a.factory.CreateAdminDataStore()
+	defer ds.Close()
+	removed, err := ds.PurgeCaws(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("caws removed: %d\n%s\n", removed, searchIndexNote)
+	return nil
+}
+
+func (a admin) reconcile() error {
+	report, err := reconciler.New(a.database, a.factory, 0, a.logger).Reconcile()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("users scanned: %d\nusers fixed: %d\ncaws scanned: %d\ncaws fixed: %d\ndrift: %d\n",
+		report.UsersScanned, report.UsersFixed, report.CawsScanned, report.CawsFixed, report.Drift)
+	return nil
+}
+
+func (a admin) ensureIndexes() error {
+	ds := a.factory.CreateAdminDataStore()
+	defer ds.Close()
+	if err := ds.EnsureIndexes(); err != nil {
+		return err
+	}
+	fmt.Println("indexes ensured")
+	return nil
+}
+
+func (a admin) stats() error {
+	ds := a.factory.CreateAdminDataStore()
+	defer ds.Close()
+	stats, err := ds.Stats()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLLECTION\tDOCUMENTS\tSIZE\tSTORAGE\tINDEXES")
+	for _, collection := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", collection.Name, collection.Count, collection.Size,
+			collection.StorageSize, strings.Join(collection.Indexes, ","))
+	}
+	return tw.Flush()
+}
+
+// readPassword reads password from CAWADMIN_PASSWORD or the first line of stdin
+func readPassword() (string, error) {
+	if password := os.Getenv("CAWADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// validationError joins field errors into one error, nil if there are none
+func validationError(fieldErrors []models.FieldError) error {
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message))
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,17 @@
+//go:build nosqlite
+// +build nosqlite
+
+package main
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/utils"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// openSQLite fails, cawadmin built with nosqlite tag supports only MongoDB and does not require cgo
+func openSQLite(appConfig *utils.AppConfig, database string, command string, args []string, logger *logrus.Logger) infrastructure.DataStoreFactory {
+	logger.Fatalf("Store %s is not supported, cawadmin was built with nosqlite tag", utils.StoreSQLite)
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build !nosqlite
+// +build !nosqlite
+
+package main
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/infrastructure/sqlite"
+	"Caw/UserService/utils"
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// openSQLite opens database file with provided name in sqlite_dir and returns its DataStoreFactory.
+// Commands working directly on the database are run here and nil is returned for them.
+// SQLite driver requires cgo, build with -tags nosqlite to leave it out of MongoDB only deployments.
+func openSQLite(appConfig *utils.AppConfig, database string, command string, args []string, logger *logrus.Logger) infrastructure.DataStoreFactory {
+	path := sqlite.Path(appConfig.SQLiteDir, database)
+	db, err := sqlite.Open(path)
+	if err != nil {
+		logger.Fatalf("Cannot open sqlite database %s. err: %s", path, err)
+	}
+
+	switch command {
+	case "repair-follows":
+		db.Close()
+		fmt.Println("sqlite store keeps follow relations unique, there is nothing to repair")
+		return nil
+	case "migrate":
+		migrate(func(dryRun bool) ([]infrastructure.MigrationResult, error) {
+			return sqlite.Migrate(db, logger, dryRun)
+		}, logger, args)
+		db.Close()
+		return nil
+	}
+	return sqlite.NewFactory(db, logger)
+}
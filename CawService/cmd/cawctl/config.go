@@ -0,0 +1,50 @@
+package main
+
+import (
+	"Caw/UserService/models"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// config is stored between cawctl runs
+type config struct {
+	Server string        `json:"server"`
+	Token  *models.Token `json:"token,omitempty"`
+
+	path string
+}
+
+// configPath returns CAWCTL_CONFIG or .cawctl.json in home directory
+func configPath() string {
+	if path := os.Getenv("CAWCTL_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cawctl.json")
+}
+
+// loadConfig reads stored config, missing file results in empty config
+func loadConfig() (*config, error) {
+	c := &config{path: configPath()}
+	js, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(js, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// save writes config readable only by current user, because it contains access token
+func (c *config) save() error {
+	js, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, js, 0600)
+}
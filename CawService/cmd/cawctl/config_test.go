@@ -0,0 +1,52 @@
+package main
+
+import (
+	"Caw/UserService/models"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cawctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cawctl.json")
+	os.Setenv("CAWCTL_CONFIG", path)
+	defer os.Unsetenv("CAWCTL_CONFIG")
+
+	missing, err := loadConfig()
+	assert.Nil(t, err, "missing config file results in empty config")
+	assert.Equal(t, "", missing.Server)
+	assert.Nil(t, missing.Token)
+
+	expiresAt := time.Now().Add(time.Hour).Round(time.Second)
+	missing.Server = "http://caw.io"
+	missing.Token = &models.Token{AccessToken: "token", Type: "Bearer", ExpiresAt: expiresAt}
+	assert.Nil(t, missing.save())
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "config with token is readable only by owner")
+
+	loaded, err := loadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "http://caw.io", loaded.Server)
+	if assert.NotNil(t, loaded.Token) {
+		assert.Equal(t, "token", loaded.Token.AccessToken)
+		assert.True(t, expiresAt.Equal(loaded.Token.ExpiresAt))
+	}
+
+	loaded.Token = nil
+	assert.Nil(t, loaded.save())
+	loggedOut, err := loadConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "http://caw.io", loggedOut.Server)
+	assert.Nil(t, loggedOut.Token, "token is removed by logout")
+}
@@ -0,0 +1,285 @@
+// Command cawctl calls Caw API of a running service.
+//
+// Usage:
+//
+//	cawctl [-server URL] [-o table|json] <command> [arguments]
+//
+// Token obtained by login is stored in ~/.cawctl.json or in file given by
+// CAWCTL_CONFIG and used by following commands until it expires.
+package main
+
+import (
+	"Caw/UserService/client"
+	"Caw/UserService/models"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const usage = `Usage: cawctl [-server URL] [-o table|json] <command> [arguments]
+
+Commands:
+  login <name>                    authenticate, password is read from CAW_PASSWORD or stdin
+  logout                          remove stored token
+  post [-visibility V] <message>  post caw
+  caws [-page N] [userID]         show caws of user, yourself by default
+  timeline [-n N]                 show the newest caws of users you follow
+  follow <userID>                 follow user
+  unfollow <userID>               unfollow user
+  followers [userID]              list followers of user, yourself by default
+  following [userID]              list users followed by user, yourself by default
+
+Flags:
+`
+
+const (
+	defaultServer  = "http://localhost:9090"
+	requestTimeout = 30 * time.Second
+)
+
+func main() {
+	server := flag.String("server", "", "URL of Caw service, stored server or "+defaultServer+" by default")
+	output := flag.String("o", outputTable, "output format: table or json")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 || (*output != outputTable && *output != outputJSON) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		fail(err)
+	}
+	if *server != "" {
+		config.Server = *server
+	}
+	if config.Server == "" {
+		config.Server = defaultServer
+	}
+
+	options := []client.Option{}
+	if config.Token != nil {
+		options = append(options, client.WithToken(*config.Token))
+	}
+	cli := cli{
+		client: client.New(config.Server, options...),
+		config: config,
+		out:    newPrinter(*output),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "login":
+		err = cli.login(ctx, args)
+	case "logout":
+		err = cli.logout()
+	case "post":
+		err = cli.post(ctx, args)
+	case "caws":
+		err = cli.caws(ctx, args)
+	case "timeline":
+		err = cli.timeline(ctx, args)
+	case "follow":
+		err = cli.follow(ctx, args, true)
+	case "unfollow":
+		err = cli.follow(ctx, args, false)
+	case "followers":
+		err = cli.follows(ctx, args, true)
+	case "following":
+		err = cli.follows(ctx, args, false)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	if problem, ok := err.(models.Problem); ok {
+		fmt.Fprintf(os.Stderr, "cawctl: %s (%s)\n", problem.Error(), problem.Code)
+		for _, fieldError := range problem.Errors {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", fieldError.Field, fieldError.Message)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "cawctl: %s\n", err)
+	}
+	os.Exit(1)
+}
+
+type cli struct {
+	client *client.Client
+	config *config
+	out    printer
+}
+
+func (c cli) login(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("login requires user name")
+	}
+	password := os.Getenv("CAW_PASSWORD")
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	token, err := c.client.Login(ctx, args[0], password)
+	if err != nil {
+		return err
+	}
+	c.config.Token = token
+	if err := c.config.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Logged in as %s, token expires at %s\n", args[0], token.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+func (c cli) logout() error {
+	c.config.Token = nil
+	return c.config.save()
+}
+
+func (c cli) post(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("post", flag.ExitOnError)
+	visibility := flags.String("visibility", models.VisibilityPublic, "public, followers or mentioned")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("post requires message")
+	}
+	userID, err := c.userID(nil)
+	if err != nil {
+		return err
+	}
+
+	caw, err := c.client.PostCaw(ctx, models.Caw{
+		UserID:     bson.ObjectIdHex(userID),
+		Message:    strings.Join(flags.Args(), " "),
+		Visibility: *visibility,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.caws([]models.Caw{*caw})
+}
+
+func (c cli) caws(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("caws", flag.ExitOnError)
+	page := flags.Int("page", 0, "page of caws, the newest first")
+	flags.Parse(args)
+	userID, err := c.userID(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	caws, err := c.client.GetUserCaws(ctx, userID, *page)
+	if err != nil {
+		return err
+	}
+	return c.out.caws(caws)
+}
+
+// timeline merges the newest caws of followed users, there is no timeline resource in the API.
+// Up to limit newest caws of every followed user are fetched, so none of merged newest caws is missed.
+func (c cli) timeline(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("timeline", flag.ExitOnError)
+	limit := flags.Int("n", 20, "number of caws")
+	flags.Parse(args)
+	userID, err := c.userID(nil)
+	if err != nil {
+		return err
+	}
+
+	following, err := c.client.Following(ctx, userID)
+	if err != nil {
+		return err
+	}
+	timeline := []models.Caw{}
+	for _, follow := range following {
+		fetched := 0
+		for page := 0; fetched < *limit; page++ {
+			caws, err := c.client.GetUserCaws(ctx, follow.UserID.Hex(), page)
+			if err != nil {
+				return err
+			}
+			if len(caws) == 0 {
+				break
+			}
+			timeline = append(timeline, caws...)
+			fetched += len(caws)
+		}
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i].CreatedAt.After(timeline[j].CreatedAt)
+	})
+	if len(timeline) > *limit {
+		timeline = timeline[:*limit]
+	}
+	return c.out.caws(timeline)
+}
+
+func (c cli) follow(ctx context.Context, args []string, follow bool) error {
+	if len(args) != 1 {
+		return errors.New("user id is required")
+	}
+	userID, err := c.userID(nil)
+	if err != nil {
+		return err
+	}
+	if follow {
+		return c.client.Follow(ctx, userID, args[0])
+	}
+	return c.client.Unfollow(ctx, userID, args[0])
+}
+
+func (c cli) follows(ctx context.Context, args []string, followers bool) error {
+	userID, err := c.userID(args)
+	if err != nil {
+		return err
+	}
+
+	var follows []models.Follow
+	if followers {
+		follows, err = c.client.Followers(ctx, userID)
+	} else {
+		follows, err = c.client.Following(ctx, userID)
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.follows(follows)
+}
+
+// userID returns user id given in args or id of logged in user
+func (c cli) userID(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	token := c.client.Token()
+	if token == nil {
+		return "", errors.New("not logged in, run cawctl login <name> first")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return "", errors.New("token has expired, run cawctl login <name> again")
+	}
+	return c.client.UserID()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"Caw/UserService/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	// maxMessageWidth truncates messages in table output
+	maxMessageWidth = 60
+)
+
+// printer writes command results in selected format
+type printer interface {
+	caws(caws []models.Caw) error
+	follows(follows []models.Follow) error
+}
+
+func newPrinter(format string) printer {
+	if format == outputJSON {
+		return jsonPrinter{w: os.Stdout}
+	}
+	return tablePrinter{w: os.Stdout}
+}
+
+type jsonPrinter struct {
+	w io.Writer
+}
+
+func (p jsonPrinter) caws(caws []models.Caw) error {
+	return p.write(caws)
+}
+
+func (p jsonPrinter) follows(follows []models.Follow) error {
+	return p.write(follows)
+}
+
+func (p jsonPrinter) write(v interface{}) error {
+	js, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n", js)
+	return err
+}
+
+type tablePrinter struct {
+	w io.Writer
+}
+
+func (p tablePrinter) caws(caws []models.Caw) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tCREATED\tVISIBILITY\tMESSAGE")
+	for _, caw := range caws {
+		visibility := caw.Visibility
+		if visibility == "" {
+			visibility = models.VisibilityPublic
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", caw.ID.Hex(), caw.UserName,
+			caw.CreatedAt.Local().Format(time.RFC822), visibility, shorten(caw.Message))
+	}
+	return tw.Flush()
+}
+
+func (p tablePrinter) follows(follows []models.Follow) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME")
+	for _, follow := range follows {
+		fmt.Fprintf(tw, "%s\t%s\n", follow.UserID.Hex(), follow.Name)
+	}
+	return tw.Flush()
+}
+
+// shorten keeps message in one table row
+func shorten(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	runes := []rune(message)
+	if len(runes) > maxMessageWidth {
+		return string(runes[:maxMessageWidth-1]) + "…"
+	}
+	return message
+}
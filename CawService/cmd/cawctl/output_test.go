@@ -0,0 +1,68 @@
+package main
+
+import (
+	"Caw/UserService/models"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestTablePrinterCaws(t *testing.T) {
+	createdAt := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	caws := []models.Caw{
+		{ID: bson.NewObjectId(), UserName: "alice", CreatedAt: createdAt, Message: "multi\nline   message"},
+		{ID: bson.NewObjectId(), UserName: "bob", CreatedAt: createdAt, Visibility: models.VisibilityFollowers,
+			Message: strings.Repeat("long ", 20)},
+	}
+
+	var out bytes.Buffer
+	err := tablePrinter{w: &out}.caws(caws)
+
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"ID", "AUTHOR", "CREATED", "VISIBILITY", "MESSAGE"}, strings.Fields(lines[0]))
+	assert.True(t, strings.HasPrefix(lines[1], caws[0].ID.Hex()+"  alice"), lines[1])
+	assert.Contains(t, lines[1], createdAt.Local().Format(time.RFC822))
+	assert.True(t, strings.HasSuffix(lines[1], "public      multi line message"), lines[1])
+	assert.Contains(t, lines[2], models.VisibilityFollowers)
+	assert.True(t, strings.HasSuffix(lines[2], "…"), lines[2])
+	// columns are aligned
+	assert.Equal(t, strings.Index(lines[0], "MESSAGE"), strings.Index(lines[1], "multi"))
+}
+
+func TestTablePrinterFollows(t *testing.T) {
+	follows := []models.Follow{{UserID: bson.NewObjectId(), Name: "alice"}}
+
+	var out bytes.Buffer
+	err := tablePrinter{w: &out}.follows(follows)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ID                        NAME\n"+follows[0].UserID.Hex()+"  alice\n", out.String())
+}
+
+func TestJSONPrinter(t *testing.T) {
+	caws := []models.Caw{{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "caw"}}
+
+	var out bytes.Buffer
+	err := jsonPrinter{w: &out}.caws(caws)
+
+	assert.Nil(t, err)
+	var printed []models.Caw
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, caws[0].ID, printed[0].ID)
+	assert.Equal(t, "caw", printed[0].Message)
+}
+
+func TestShorten(t *testing.T) {
+	assert.Equal(t, "short message", shorten(" short\tmessage\n"))
+	shortened := shorten(strings.Repeat("ž", maxMessageWidth+1))
+	assert.Equal(t, maxMessageWidth, len([]rune(shortened)))
+	assert.True(t, strings.HasSuffix(shortened, "…"))
+	assert.Equal(t, strings.Repeat("ž", maxMessageWidth), shorten(strings.Repeat("ž", maxMessageWidth)))
+}
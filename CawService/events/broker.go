@@ -0,0 +1,224 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	// EventCaw is published to author followers when new caw appears in their timeline
+	EventCaw = "caw"
+	// EventNotification is published to notification recipient
+	EventNotification = "notification"
+	// EventFollow is published to both users of new follow relation
+	EventFollow = "follow"
+	// EventReset is sent to subscriber resuming from event which is no longer kept,
+	// e.g. older than history or published before restart. Client has to reload its state.
+	EventReset = "reset"
+)
+
+// Policy decides what happens when subscriber does not keep up with published events
+type Policy string
+
+const (
+	// PolicyDisconnect closes slow subscription, client is expected to resume with last received event ID
+	PolicyDisconnect Policy = "disconnect"
+	// PolicyDrop drops events which do not fit into subscription buffer
+	PolicyDrop Policy = "drop"
+)
+
+// HashtagTopic returns recipient key of caws tagged with hashtag
+func HashtagTopic(hashtag string) string {
+	return "hashtag:" + hashtag
+}
+
+// RepliesTopic returns recipient key of replies to caw with cawID
+func RepliesTopic(cawID string) string {
+	return "replies:" + cawID
+}
+
+const (
+	historySize = 1024
+	bufferSize  = 64
+)
+
+// Event represents message published to subscribed users
+type Event struct {
+	ID         uint64
+	Type       string
+	Data       []byte
+	recipients map[string]bool
+}
+
+// Broker delivers published events to subscriptions of recipient users and keeps
+// recent events so that clients can resume from last received event ID.
+type Broker struct {
+	mu            sync.Mutex
+	lastID        uint64
+	history       []Event
+	subscriptions map[string]map[*Subscription]bool
+	policy        Policy
+	closed        bool
+	logger        *logrus.Logger
+}
+
+// NewBroker creates Broker with provided backpressure policy
+func NewBroker(policy Policy, logger *logrus.Logger) *Broker {
+	if policy != PolicyDrop {
+		policy = PolicyDisconnect
+	}
+	return &Broker{
+		// IDs continue from start time, so IDs given by clients before restart are recognized as older than history.
+		// Milliseconds are multiplied only by 1000 to keep IDs exact as JavaScript numbers.
+		lastID:        uint64(time.Now().UnixNano()/int64(time.Millisecond)) * 1000,
+		subscriptions: map[string]map[*Subscription]bool{},
+		policy:        policy,
+		logger:        logger,
+	}
+}
+
+// Publish sends event with JSON encoded payload to subscriptions of recipients.
+// Recipient is either user ID or topic key.
+func (b *Broker) Publish(eventType string, payload interface{}, recipients ...string) error {
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Data: data, recipients: map[string]bool{}}
+	for _, recipient := range recipients {
+		event.recipients[recipient] = true
+	}
+	b.history = append(b.history, event)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for recipient := range event.recipients {
+		for subscription := range b.subscriptions[recipient] {
+			select {
+			case subscription.events <- event:
+			default:
+				b.logger.Warnf("Subscription of user %s is full, applying %s policy", recipient, b.policy)
+				if b.policy == PolicyDisconnect {
+					b.unsubscribe(subscription)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe creates subscription for user and returns events published to user after
+// lastEventID which are still kept by Broker. When events after lastEventID are no longer
+// kept, the only returned event is EventReset.
+func (b *Broker) Subscribe(userID string, lastEventID uint64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscription := &Subscription{
+		userID: userID,
+		events: make(chan Event, bufferSize),
+		done:   make(chan struct{}),
+		broker: b,
+	}
+	if b.closed {
+		close(subscription.done)
+		return subscription, nil
+	}
+
+	var missed []Event
+	oldestID := b.lastID + 1
+	if len(b.history) > 0 {
+		oldestID = b.history[0].ID
+	}
+	if lastEventID > 0 && (lastEventID+1 < oldestID || lastEventID > b.lastID) {
+		missed = append(missed, Event{ID: b.lastID, Type: EventReset, Data: []byte("{}")})
+	} else {
+		for _, event := range b.history {
+			if event.ID > lastEventID && event.recipients[userID] {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	if b.subscriptions[userID] == nil {
+		b.subscriptions[userID] = map[*Subscription]bool{}
+	}
+	b.subscriptions[userID][subscription] = true
+	return subscription, missed
+}
+
+// HasSubscriptions returns true if any user is subscribed
+func (b *Broker) HasSubscriptions() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscriptions) > 0
+}
+
+// Close closes all subscriptions and stops accepting new events
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, subscriptions := range b.subscriptions {
+		for subscription := range subscriptions {
+			b.unsubscribe(subscription)
+		}
+	}
+}
+
+// unsubscribe has to be called with locked mutex
+func (b *Broker) unsubscribe(subscription *Subscription) {
+	subscriptions, ok := b.subscriptions[subscription.userID]
+	if !ok || !subscriptions[subscription] {
+		return
+	}
+
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(b.subscriptions, subscription.userID)
+	}
+	close(subscription.done)
+}
+
+// Subscription represents stream of events published to user
+type Subscription struct {
+	userID string
+	events chan Event
+	done   chan struct{}
+	broker *Broker
+}
+
+// Events returns channel of published events
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Done returns channel which is closed when subscription is closed by Broker
+// because of shutdown or backpressure policy
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close unsubscribes from Broker
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.unsubscribe(s)
+}
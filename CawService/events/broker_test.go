@@ -0,0 +1,134 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, subscription *Subscription) Event {
+	select {
+	case event := <-subscription.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Event was not received")
+	}
+	return Event{}
+}
+
+func TestBrokerDeliversEventsToRecipients(t *testing.T) {
+	broker := NewBroker(PolicyDisconnect, logrus.New())
+	bob, _ := broker.Subscribe("bob", 0)
+	alice, _ := broker.Subscribe("alice", 0)
+
+	err := broker.Publish(EventFollow, map[string]string{"name": "bob"}, "bob")
+	assert.Nil(t, err)
+
+	event := receive(t, bob)
+	assert.Equal(t, broker.lastID, event.ID)
+	assert.Equal(t, EventFollow, event.Type)
+	assert.Equal(t, `{"name":"bob"}`, string(event.Data))
+	assert.Equal(t, 0, len(alice.Events()))
+}
+
+func TestBrokerResumesFromLastEventID(t *testing.T) {
+	broker := NewBroker(PolicyDisconnect, logrus.New())
+	for i := 0; i < 3; i++ {
+		broker.Publish(EventCaw, i, "bob")
+		broker.Publish(EventCaw, i, "alice")
+	}
+
+	first := broker.history[0].ID
+
+	_, missed := broker.Subscribe("bob", first+2)
+	assert.Equal(t, 1, len(missed))
+	assert.Equal(t, first+4, missed[0].ID)
+
+	_, missed = broker.Subscribe("bob", 0)
+	assert.Equal(t, 3, len(missed))
+
+	_, missed = broker.Subscribe("bob", broker.lastID)
+	assert.Equal(t, 0, len(missed))
+}
+
+func TestBrokerResetsUnknownLastEventID(t *testing.T) {
+	broker := NewBroker(PolicyDisconnect, logrus.New())
+	for i := 0; i < historySize+2; i++ {
+		broker.Publish(EventCaw, i, "bob")
+	}
+
+	testCases := []struct {
+		Name        string
+		LastEventID uint64
+	}{
+		{"OlderThanHistoryTest", broker.history[0].ID - 2},
+		{"BeforeRestartTest", 100},
+		{"FromFutureTest", broker.lastID + 1},
+	}
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		_, missed := broker.Subscribe("bob", testCase.LastEventID)
+		assert.Equal(t, 1, len(missed))
+		assert.Equal(t, EventReset, missed[0].Type)
+		assert.Equal(t, broker.lastID, missed[0].ID)
+	}
+
+	// the oldest kept event directly follows last received event
+	_, missed := broker.Subscribe("bob", broker.history[0].ID-1)
+	assert.Equal(t, historySize, len(missed))
+}
+
+func TestBrokerBackpressurePolicy(t *testing.T) {
+	testCases := []struct {
+		Name                 string
+		Policy               Policy
+		ExpectedDisconnected bool
+	}{
+		{"DisconnectSlowSubscriptionTest", PolicyDisconnect, true},
+		{"DropEventsOfSlowSubscriptionTest", PolicyDrop, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		broker := NewBroker(testCase.Policy, logrus.New())
+		subscription, _ := broker.Subscribe("bob", 0)
+		for i := 0; i < bufferSize+1; i++ {
+			broker.Publish(EventCaw, i, "bob")
+		}
+
+		disconnected := false
+		select {
+		case <-subscription.Done():
+			disconnected = true
+		default:
+		}
+		assert.Equal(t, testCase.ExpectedDisconnected, disconnected)
+		assert.Equal(t, bufferSize, len(subscription.Events()))
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	broker := NewBroker(PolicyDisconnect, logrus.New())
+	subscription, _ := broker.Subscribe("bob", 0)
+	subscription.Close()
+	other, _ := broker.Subscribe("bob", 0)
+
+	broker.Close()
+	broker.Close()
+
+	select {
+	case <-other.Done():
+	default:
+		t.Fatal("Subscription was not closed")
+	}
+
+	afterClose, _ := broker.Subscribe("bob", 0)
+	select {
+	case <-afterClose.Done():
+	default:
+		t.Fatal("Subscription created after Close is not closed")
+	}
+	assert.Nil(t, broker.Publish(EventCaw, 1, "bob"))
+}
@@ -0,0 +1,186 @@
+package infrastructure
+
+import (
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CollectionStats describes documents and indexes of a collection
+type CollectionStats struct {
+	Name  string
+	Count int
+	// Size is total size of documents in bytes
+	Size int
+	// StorageSize is space allocated for documents in bytes
+	StorageSize int
+	Indexes     []string
+}
+
+// AdminDataStore provides operations used by operators working directly on the datastore
+type AdminDataStore interface {
+	SetSuspended(userID string, suspended bool) error
+	SetPassword(userID string, passwordHash string) error
+	RemoveFollows(userID string) (int, error)
+	PurgeCaws(userID string) (int, error)
+	EnsureIndexes() error
+	Stats() ([]CollectionStats, error)
+	Close()
+}
+
+type mgoAdminDataStore struct {
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
+}
+
+func (ds *mgoAdminDataStore) Close() {
+	ds.session.Close()
+}
+
+// SetSuspended suspends or restores account of user with userID. Suspended users cannot authenticate
+// and tokens issued to them before are rejected.
+func (ds *mgoAdminDataStore) SetSuspended(userID string, suspended bool) error {
+	return ds.updateUser(userID, bson.M{"$set": bson.M{"suspended": suspended}})
+}
+
+// SetPassword replaces password hash of user with userID
+func (ds *mgoAdminDataStore) SetPassword(userID string, passwordHash string) error {
+	return ds.updateUser(userID, bson.M{"$set": bson.M{"password": passwordHash}})
+}
+
+func (ds *mgoAdminDataStore) updateUser(userID string, update bson.M) error {
+	if !bson.IsObjectIdHex(userID) {
+		return ErrNotFound
+	}
+	err := ds.session.DB(ds.database).C(userCollection).UpdateId(bson.ObjectIdHex(userID), update)
+	if err == mgo.ErrNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		ds.logger.Error(err)
+	}
+	return err
+}
+
+// RemoveFollows removes follow relations of user with userID in both directions and
+// returns number of removed relations. Follow counters of followed users and followers
+// are decremented, counters changed concurrently are fixed by reconciliation.
+func (ds *mgoAdminDataStore) RemoveFollows(userID string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		return 0, ErrNotFound
+	}
+	id := bson.ObjectIdHex(userID)
+	relations := ds.session.DB(ds.database).C(followRelationCollection)
+	users := ds.session.DB(ds.database).C(userCollection)
+
+	var following, followers []bson.ObjectId
+	if err := relations.Find(bson.M{"follower.user_id": id}).Distinct("following.user_id", &following); err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	if err := relations.Find(bson.M{"following.user_id": id}).Distinct("follower.user_id", &followers); err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	if len(following) > 0 {
+		if _, err := users.UpdateAll(bson.M{"_id": bson.M{"$in": following}}, bson.M{"$inc": bson.M{"followers_count": -1}}); err != nil {
+			ds.logger.Error(err)
+			return 0, err
+		}
+	}
+	if len(followers) > 0 {
+		if _, err := users.UpdateAll(bson.M{"_id": bson.M{"$in": followers}}, bson.M{"$inc": bson.M{"following_count": -1}}); err != nil {
+			ds.logger.Error(err)
+			return 0, err
+		}
+	}
+	err := users.UpdateId(id, bson.M{"$set": bson.M{"followers_count": 0, "following_count": 0}})
+	if err != nil && err != mgo.ErrNotFound {
+		ds.logger.Error(err)
+		return 0, err
+	}
+
+	info, err := relations.RemoveAll(bson.M{"$or": []bson.M{
+		{"follower.user_id": id},
+		{"following.user_id": id},
+	}})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+// PurgeCaws removes all caws of user with userID with their revisions and returns number of removed caws
+func (ds *mgoAdminDataStore) PurgeCaws(userID string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		return 0, ErrNotFound
+	}
+	caws := ds.session.DB(ds.database).C(cawCollection)
+	var ids []bson.ObjectId
+	err := caws.Find(bson.M{"user_id": bson.ObjectIdHex(userID)}).Distinct("_id", &ids)
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if _, err = ds.session.DB(ds.database).C(cawRevisionCollection).RemoveAll(bson.M{"caw_id": bson.M{"$in": ids}}); err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	info, err := caws.RemoveAll(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+// EnsureIndexes creates indexes of all collections
+func (ds *mgoAdminDataStore) EnsureIndexes() error {
+	return ensureIndexes(ds.session.DB(ds.database), ds.logger)
+}
+
+// Stats returns statistics of all collections
+func (ds *mgoAdminDataStore) Stats() ([]CollectionStats, error) {
+	names := []string{
+		userCollection,
+		followRelationCollection,
+		cawCollection,
+		cawRevisionCollection,
+		notificationCollection,
+	}
+	var stats []CollectionStats
+	for _, name := range names {
+		var result struct {
+			Count       int `bson:"count"`
+			Size        int `bson:"size"`
+			StorageSize int `bson:"storageSize"`
+		}
+		if err := ds.session.DB(ds.database).Run(bson.D{{Name: "collStats", Value: name}}, &result); err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		indexes, err := ds.session.DB(ds.database).C(name).Indexes()
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+
+		collection := CollectionStats{
+			Name:        name,
+			Count:       result.Count,
+			Size:        result.Size,
+			StorageSize: result.StorageSize,
+		}
+		for _, index := range indexes {
+			collection.Indexes = append(collection.Indexes, index.Name)
+		}
+		stats = append(stats, collection)
+	}
+	return stats, nil
+}
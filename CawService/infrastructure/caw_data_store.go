@@ -10,55 +10,165 @@ import (
 )
 
 const (
-	cawCollection = "caw"
-	pageSize      = 10
+	cawCollection         = "caw"
+	cawRevisionCollection = "caw_revision"
+	pageSize              = 10
 )
 
 type CawDataStore interface {
 	Store(caw models.Caw) (*models.Caw, error)
-	GetByID(cawID string) (*models.Caw, error)
-	GetByUserID(userID string, page int) ([]models.Caw, error)
+	GetByID(cawID string, viewerID string) (*models.Caw, error)
+	GetByUserID(userID string, viewerID string, page int, newestFirst bool) ([]models.Caw, error)
+	GetByHashtag(hashtag string, viewerID string, page int) ([]models.Caw, error)
+	GetByMention(userID string, viewerID string, page int) ([]models.Caw, error)
+	ForEachPublic(fn func(caw models.Caw) error) error
+	Edit(cawID string, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error)
+	GetRevisions(cawID string) ([]models.CawRevision, error)
 	Delete(cawID string) error
 	Close()
 }
 
 type mgoCawDataStore struct {
-	session *mgo.Session
-	logger  *logrus.Logger
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
+}
+
+// cawIndexes are required by caw queries
+var cawIndexes = []mgo.Index{
+	{Key: []string{"user_id", "-created_at"}, Background: true},
+	{Key: []string{"hashtags", "-created_at"}, Background: true},
+	{Key: []string{"mentions.user_id", "-created_at"}, Background: true},
+	{Key: []string{"parent_id"}, Background: true, Sparse: true},
+}
+
+// cawRevisionIndexes keep one revision of each number per caw
+var cawRevisionIndexes = []mgo.Index{
+	{Key: []string{"caw_id", "revision"}, Unique: true, Background: true},
+}
+
+// ensureCawIndexes creates indexes required by caw queries
+func ensureCawIndexes(database *mgo.Database, logger *logrus.Logger) error {
+	if err := ensureCollectionIndexes(database, logger, cawCollection, cawIndexes); err != nil {
+		return err
+	}
+	return ensureCollectionIndexes(database, logger, cawRevisionCollection, cawRevisionIndexes)
 }
 
 func (ds *mgoCawDataStore) caw() *mgo.Collection {
-	return ds.session.DB(db).C(cawCollection)
+	return ds.session.DB(ds.database).C(cawCollection)
+}
+
+func (ds *mgoCawDataStore) cawRevision() *mgo.Collection {
+	return ds.session.DB(ds.database).C(cawRevisionCollection)
 }
 
 func (ds *mgoCawDataStore) Store(caw models.Caw) (*models.Caw, error) {
 	caw.ID = bson.NewObjectId()
 	caw.CreatedAt = time.Now()
+	caw.Hashtags = models.ExtractHashtags(caw.Message)
 	err := ds.caw().Insert(&caw)
 	if err != nil {
 		ds.logger.Error(err)
 		return nil, err
 	}
 
+	if caw.ParentID != "" {
+		// drift left by failed increment is fixed by counter reconciliation
+		err = ds.caw().UpdateId(caw.ParentID, bson.M{"$inc": bson.M{"replies_count": 1}})
+		if err != nil {
+			ds.logger.Errorf("Cannot increment replies count of caw %s. err: %s", caw.ParentID.Hex(), err)
+		}
+	}
 	return &caw, nil
 }
 
-func (ds *mgoCawDataStore) GetByUserID(userID string, page int) ([]models.Caw, error) {
+func (ds *mgoCawDataStore) followRelation() *mgo.Collection {
+	return ds.session.DB(ds.database).C(followRelationCollection)
+}
+
+// GetByUserID returns page of caws created by user with userID and visible to viewer,
+// the oldest first unless newestFirst is set
+func (ds *mgoCawDataStore) GetByUserID(userID string, viewerID string, page int, newestFirst bool) ([]models.Caw, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	visibility, err := ds.authorVisibilityFilter(bson.ObjectIdHex(userID), viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort := "created_at"
+	if newestFirst {
+		sort = "-created_at"
+	}
 	var storedCaws []models.Caw
-	err := ds.caw().
-		Find(bson.M{"user_id": bson.ObjectIdHex(userID)}).
-		Sort("created_at").
+	err = ds.caw().
+		Find(bson.M{"user_id": bson.ObjectIdHex(userID), "$or": visibility}).
+		Sort(sort).
+		Skip(page * pageSize).
+		Limit(pageSize).
+		All(&storedCaws)
+	if err != nil {
+		return nil, err
+	}
+
+	return storedCaws, nil
+}
+
+// GetByHashtag returns page of newest caws tagged with hashtag and visible to viewer
+func (ds *mgoCawDataStore) GetByHashtag(hashtag string, viewerID string, page int) ([]models.Caw, error) {
+	visibility, err := ds.visibilityFilter(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	storedCaws := []models.Caw{}
+	err = ds.caw().
+		Find(bson.M{"hashtags": models.NormalizeHashtag(hashtag), "$or": visibility}).
+		Sort("-created_at").
 		Skip(page * pageSize).
 		Limit(pageSize).
 		All(&storedCaws)
 	if err != nil {
+		ds.logger.Error(err)
 		return nil, err
 	}
 
 	return storedCaws, nil
 }
 
-func (ds *mgoCawDataStore) GetByID(cawID string) (*models.Caw, error) {
+// GetByMention returns page of newest caws mentioning user with userID and visible to viewer
+func (ds *mgoCawDataStore) GetByMention(userID string, viewerID string, page int) ([]models.Caw, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	visibility, err := ds.visibilityFilter(viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	storedCaws := []models.Caw{}
+	err = ds.caw().
+		Find(bson.M{"mentions.user_id": bson.ObjectIdHex(userID), "$or": visibility}).
+		Sort("-created_at").
+		Skip(page * pageSize).
+		Limit(pageSize).
+		All(&storedCaws)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return storedCaws, nil
+}
+
+// GetByID returns caw with cawID. ErrNotFound is returned also when caw is not visible to viewer
+func (ds *mgoCawDataStore) GetByID(cawID string, viewerID string) (*models.Caw, error) {
 	var caw models.Caw
 	if !bson.IsObjectIdHex(cawID) {
 		ds.logger.Error("Caw Id is not mongo ObjectId")
@@ -75,17 +185,221 @@ func (ds *mgoCawDataStore) GetByID(cawID string) (*models.Caw, error) {
 		ds.logger.Error(err)
 		return nil, err
 	}
+
+	isFollower := false
+	if caw.Visibility == models.VisibilityFollowers {
+		isFollower, err = ds.isFollower(viewerID, caw.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !caw.IsVisibleTo(viewerID, isFollower) {
+		return nil, ErrNotFound
+	}
+	return &caw, nil
+}
+
+// ForEachPublic calls fn for every public caw until fn returns error
+func (ds *mgoCawDataStore) ForEachPublic(fn func(caw models.Caw) error) error {
+	iter := ds.caw().
+		Find(bson.M{"visibility": bson.M{"$nin": []string{models.VisibilityFollowers, models.VisibilityMentioned}}}).
+		Iter()
+	var caw models.Caw
+	for iter.Next(&caw) {
+		if err := fn(caw); err != nil {
+			iter.Close()
+			return err
+		}
+		caw = models.Caw{}
+	}
+	if err := iter.Close(); err != nil {
+		ds.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Edit replaces message of caw with cawID and keeps replaced content as revision.
+// ErrEditLimit is returned when caw was already edited maxEdits times and
+// ErrEditConflict when caw was edited concurrently.
+func (ds *mgoCawDataStore) Edit(cawID string, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error) {
+	if !bson.IsObjectIdHex(cawID) {
+		ds.logger.Error("Caw Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	var caw models.Caw
+	err := ds.caw().FindId(bson.ObjectIdHex(cawID)).One(&caw)
+	if err == mgo.ErrNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	if caw.EditCount >= maxEdits {
+		return nil, ErrEditLimit
+	}
+
+	editedAt := time.Now()
+	revision := models.NewCawRevision(caw, editedAt)
+	revision.ID = bson.NewObjectId()
+	if err = ds.cawRevision().Insert(&revision); err != nil {
+		if mgo.IsDup(err) {
+			return nil, ErrEditConflict
+		}
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	caw.Message = message
+	caw.Mentions = mentions
+	caw.Hashtags = models.ExtractHashtags(message)
+	caw.EditedAt = &editedAt
+	caw.EditCount++
+	// caws stored before editing existed have no edit count
+	editCount := interface{}(revision.Revision)
+	if revision.Revision == 0 {
+		editCount = bson.M{"$in": []interface{}{0, nil}}
+	}
+	err = ds.caw().Update(
+		bson.M{"_id": caw.ID, "edit_count": editCount},
+		bson.M{"$set": bson.M{
+			"message":    caw.Message,
+			"mentions":   caw.Mentions,
+			"hashtags":   caw.Hashtags,
+			"edited_at":  caw.EditedAt,
+			"edit_count": caw.EditCount,
+		}})
+	if err != nil {
+		if removeErr := ds.cawRevision().RemoveId(revision.ID); removeErr != nil {
+			ds.logger.Error(removeErr)
+		}
+		if err == mgo.ErrNotFound {
+			return nil, ErrEditConflict
+		}
+		ds.logger.Error(err)
+		return nil, err
+	}
 	return &caw, nil
 }
 
+// GetRevisions returns replaced revisions of caw with cawID, the oldest first
+func (ds *mgoCawDataStore) GetRevisions(cawID string) ([]models.CawRevision, error) {
+	if !bson.IsObjectIdHex(cawID) {
+		ds.logger.Error("Caw Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	revisions := []models.CawRevision{}
+	err := ds.cawRevision().
+		Find(bson.M{"caw_id": bson.ObjectIdHex(cawID)}).
+		Sort("revision").
+		All(&revisions)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return revisions, nil
+}
+
 func (ds *mgoCawDataStore) Delete(cawID string) error {
 	err := ds.caw().RemoveId(bson.ObjectIdHex(cawID))
 	if err != nil {
 		ds.logger.Error(err)
+		return err
+	}
+	if _, err = ds.cawRevision().RemoveAll(bson.M{"caw_id": bson.ObjectIdHex(cawID)}); err != nil {
+		ds.logger.Error(err)
 	}
 	return err
 }
 
+// visibilityFilter returns $or conditions matching caws visible to viewer. Whole list of users
+// followed by viewer is loaded into $in condition, so cost grows with number of followed users.
+// Use authorVisibilityFilter when author of caws is known.
+func (ds *mgoCawDataStore) visibilityFilter(viewerID string) ([]bson.M, error) {
+	filter := []bson.M{
+		{"visibility": bson.M{"$nin": []string{models.VisibilityFollowers, models.VisibilityMentioned}}},
+	}
+	if !bson.IsObjectIdHex(viewerID) {
+		return filter, nil
+	}
+
+	viewer := bson.ObjectIdHex(viewerID)
+	following, err := ds.followingIDs(viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(filter,
+		bson.M{"user_id": viewer},
+		bson.M{"visibility": models.VisibilityFollowers, "user_id": bson.M{"$in": following}},
+		bson.M{"visibility": models.VisibilityMentioned, "mentions.user_id": viewer},
+	), nil
+}
+
+// authorVisibilityFilter returns $or conditions matching caws of author visible to viewer,
+// following of the author is checked by single lookup of follow relation
+func (ds *mgoCawDataStore) authorVisibilityFilter(authorID bson.ObjectId, viewerID string) ([]bson.M, error) {
+	filter := []bson.M{
+		{"visibility": bson.M{"$nin": []string{models.VisibilityFollowers, models.VisibilityMentioned}}},
+	}
+	if !bson.IsObjectIdHex(viewerID) {
+		return filter, nil
+	}
+
+	viewer := bson.ObjectIdHex(viewerID)
+	if viewer == authorID {
+		return []bson.M{{}}, nil
+	}
+
+	isFollower, err := ds.isFollower(viewerID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if isFollower {
+		filter = append(filter, bson.M{"visibility": models.VisibilityFollowers})
+	}
+
+	return append(filter, bson.M{"visibility": models.VisibilityMentioned, "mentions.user_id": viewer}), nil
+}
+
+// followingIDs returns IDs of users followed by user with provided ID
+func (ds *mgoCawDataStore) followingIDs(followerID bson.ObjectId) ([]bson.ObjectId, error) {
+	var queryResult = []struct {
+		Following models.Follow `bson:"following"`
+	}{}
+	err := ds.followRelation().
+		Find(bson.M{"follower.user_id": followerID}).
+		Select(bson.M{"following.user_id": true}).All(&queryResult)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	following := []bson.ObjectId{}
+	for _, f := range queryResult {
+		following = append(following, f.Following.UserID)
+	}
+	return following, nil
+}
+
+func (ds *mgoCawDataStore) isFollower(viewerID string, authorID bson.ObjectId) (bool, error) {
+	if !bson.IsObjectIdHex(viewerID) {
+		return false, nil
+	}
+
+	count, err := ds.followRelation().
+		Find(bson.M{"follower.user_id": bson.ObjectIdHex(viewerID), "following.user_id": authorID}).
+		Count()
+	if err != nil {
+		ds.logger.Error(err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (ds *mgoCawDataStore) Close() {
 	ds.session.Clone()
 }
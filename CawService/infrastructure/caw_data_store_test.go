@@ -9,13 +9,13 @@ import (
 )
 
 func DropCawCollection(session *mgo.Session) {
-	session.DB(db).C(cawCollection).DropCollection()
+	session.DB(DefaultDatabase).C(cawCollection).DropCollection()
 }
 
 func TestStoreCaw(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	cawDataStore := mgoCawDataStore{session.Clone(), logger}
+	cawDataStore := mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
 	defer cawDataStore.Close()
 
 	caw := models.Caw{
@@ -28,6 +28,26 @@ func TestStoreCaw(t *testing.T) {
 	ValidateStore(t, err, storedCaw)
 }
 
+func TestStoreReplyIncrementsRepliesCount(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	parent, err := cawDataStore.Store(models.Caw{UserID: bson.NewObjectId(), Message: "parent"})
+	ValidateStore(t, err, parent)
+	reply, err := cawDataStore.Store(models.Caw{UserID: bson.NewObjectId(), ParentID: parent.ID, Message: "reply"})
+	ValidateStore(t, err, reply)
+
+	stored, err := cawDataStore.GetByID(parent.ID.Hex(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.RepliesCount != 1 {
+		t.Fatalf("Replies count of parent should be incremented, given %d", stored.RepliesCount)
+	}
+}
+
 func ValidateStore(t *testing.T, err error, storedCaw *models.Caw) {
 	if err != nil {
 		t.Fatalf("Store Caw error: ", err)
@@ -44,7 +64,7 @@ func ValidateStore(t *testing.T, err error, storedCaw *models.Caw) {
 func TestGetByUserIDCaw(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	cawDataStore := &mgoCawDataStore{session.Clone(), logger}
+	cawDataStore := &mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
 	defer cawDataStore.Close()
 
 	userID := bson.NewObjectId()
@@ -107,7 +127,7 @@ func TestGetByUserIDCaw(t *testing.T) {
 		}
 
 		for page := 0; page < testCase.ExpectedPageCount; page++ {
-			storedCaws, err := cawDataStore.GetByUserID(userID.Hex(), page)
+			storedCaws, err := cawDataStore.GetByUserID(userID.Hex(), userID.Hex(), page, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -131,7 +151,7 @@ func TestGetByUserIDCaw(t *testing.T) {
 
 			//check if GetByUserID does not returns more pages
 			if page+1 == testCase.ExpectedPageCount {
-				storedCaws, err := cawDataStore.GetByUserID(userID.Hex(), page+1)
+				storedCaws, err := cawDataStore.GetByUserID(userID.Hex(), userID.Hex(), page+1, false)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -142,3 +162,217 @@ func TestGetByUserIDCaw(t *testing.T) {
 		}
 	}
 }
+
+func TestGetByIDCawVisibility(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := &mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	authorID := bson.NewObjectId()
+	followerID := bson.NewObjectId()
+	mentionedID := bson.NewObjectId()
+	strangerID := bson.NewObjectId()
+
+	err := session.DB(DefaultDatabase).C(followRelationCollection).Insert(&models.FollowRelation{
+		Follower:  models.Follow{UserID: followerID, Name: "follower"},
+		Following: models.Follow{UserID: authorID, Name: "author"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Name            string
+		Visibility      string
+		ViewerID        bson.ObjectId
+		ExpectedVisible bool
+	}{
+		{"PublicCawVisibleToStrangerTest", models.VisibilityPublic, strangerID, true},
+		{"FollowersCawVisibleToAuthorTest", models.VisibilityFollowers, authorID, true},
+		{"FollowersCawVisibleToFollowerTest", models.VisibilityFollowers, followerID, true},
+		{"FollowersCawHiddenFromStrangerTest", models.VisibilityFollowers, strangerID, false},
+		{"MentionedCawVisibleToMentionedTest", models.VisibilityMentioned, mentionedID, true},
+		{"MentionedCawHiddenFromFollowerTest", models.VisibilityMentioned, followerID, false},
+		{"MentionedCawVisibleToAuthorTest", models.VisibilityMentioned, authorID, true},
+		{"PublicCawVisibleToFollowerTest", models.VisibilityPublic, followerID, true},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		storedCaw, err := cawDataStore.Store(models.Caw{
+			UserID:     authorID,
+			Message:    "message",
+			Visibility: testCase.Visibility,
+			Mentions:   []models.Mention{{UserID: mentionedID, Name: "mentioned"}},
+		})
+		ValidateStore(t, err, storedCaw)
+
+		caw, err := cawDataStore.GetByID(storedCaw.ID.Hex(), testCase.ViewerID.Hex())
+		if testCase.ExpectedVisible && (err != nil || caw == nil) {
+			t.Fatalf("Caw should be visible. err: %v", err)
+		}
+		if !testCase.ExpectedVisible && err != ErrNotFound {
+			t.Fatalf("Expected ErrNotFound, given %v", err)
+		}
+
+		caws, err := cawDataStore.GetByUserID(authorID.Hex(), testCase.ViewerID.Hex(), 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (len(caws) != 0) != testCase.ExpectedVisible {
+			t.Fatalf("GetByUserID returned %d caws, expected visible %t", len(caws), testCase.ExpectedVisible)
+		}
+		DropCawCollection(session)
+	}
+}
+
+func TestGetByHashtagCaw(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := &mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	userID := bson.NewObjectId()
+	messages := []string{"#Go is fun", "learning ＃ｇｏ", "#rust"}
+	for _, message := range messages {
+		storedCaw, err := cawDataStore.Store(models.Caw{UserID: userID, Message: message})
+		ValidateStore(t, err, storedCaw)
+	}
+
+	caws, err := cawDataStore.GetByHashtag("#GO", userID.Hex(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caws) != 2 {
+		t.Fatalf("Caws count are different expected %d, given %d", 2, len(caws))
+	}
+	if caws[0].Message != messages[1] {
+		t.Fatalf("Newest caw should be first. Expected %s, given %s", messages[1], caws[0].Message)
+	}
+	DropCawCollection(session)
+}
+
+func TestGetByMentionCaw(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := &mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	userID := bson.NewObjectId()
+	mentionedID := bson.NewObjectId()
+	caws := []models.Caw{
+		{UserID: userID, Message: "hi @bob", Mentions: []models.Mention{{UserID: mentionedID, Name: "bob"}}},
+		{UserID: userID, Message: "hi all"},
+	}
+	for _, caw := range caws {
+		storedCaw, err := cawDataStore.Store(caw)
+		ValidateStore(t, err, storedCaw)
+	}
+
+	mentions, err := cawDataStore.GetByMention(mentionedID.Hex(), mentionedID.Hex(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 {
+		t.Fatalf("Caws count are different expected %d, given %d", 1, len(mentions))
+	}
+	DropCawCollection(session)
+}
+
+func TestForEachPublicCaw(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := &mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	userID := bson.NewObjectId()
+	caws := []models.Caw{
+		{UserID: userID, Message: "public", Visibility: models.VisibilityPublic},
+		{UserID: userID, Message: "legacy"},
+		{UserID: userID, Message: "followers", Visibility: models.VisibilityFollowers},
+	}
+	for _, caw := range caws {
+		storedCaw, err := cawDataStore.Store(caw)
+		ValidateStore(t, err, storedCaw)
+	}
+
+	var messages []string
+	err := cawDataStore.ForEachPublic(func(caw models.Caw) error {
+		messages = append(messages, caw.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Caws count are different expected %d, given %d", 2, len(messages))
+	}
+	DropCawCollection(session)
+}
+
+func TestEditCaw(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	ensureCawIndexes(session.DB(DefaultDatabase), logger)
+	cawDataStore := mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	storedCaw, err := cawDataStore.Store(models.Caw{UserID: bson.NewObjectId(), Message: "first #go"})
+	ValidateStore(t, err, storedCaw)
+
+	editedCaw, err := cawDataStore.Edit(storedCaw.ID.Hex(), "second #golang", nil, 2)
+	if err != nil {
+		t.Fatal("Edit err", err)
+	}
+	if editedCaw.EditCount != 1 || editedCaw.EditedAt == nil || editedCaw.Hashtags[0] != "golang" {
+		t.Fatalf("Edited caw is wrong %+v", *editedCaw)
+	}
+	if _, err = cawDataStore.Edit(storedCaw.ID.Hex(), "third", nil, 2); err != nil {
+		t.Fatal("Edit err", err)
+	}
+	if _, err = cawDataStore.Edit(storedCaw.ID.Hex(), "fourth", nil, 2); err != ErrEditLimit {
+		t.Fatalf("Edit over limit should return ErrEditLimit, given %v", err)
+	}
+
+	caw, err := cawDataStore.GetByID(storedCaw.ID.Hex(), "")
+	if err != nil {
+		t.Fatal("GetByID err", err)
+	}
+	if caw.Message != "third" || caw.EditCount != 2 {
+		t.Fatalf("Stored caw is wrong %+v", *caw)
+	}
+
+	revisions, err := cawDataStore.GetRevisions(storedCaw.ID.Hex())
+	if err != nil {
+		t.Fatal("GetRevisions err", err)
+	}
+	if len(revisions) != 2 || revisions[0].Message != "first #go" || revisions[1].Message != "second #golang" {
+		t.Fatalf("Revisions are wrong %+v", revisions)
+	}
+	if !revisions[1].CreatedAt.Equal(revisions[0].ReplacedAt) {
+		t.Fatalf("Revision should be created when previous one was replaced %+v", revisions)
+	}
+}
+
+func TestEditCawStoredBeforeEditing(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	cawDataStore := mgoCawDataStore{session.Clone(), DefaultDatabase, logger}
+	defer cawDataStore.Close()
+
+	// caw stored before edit count existed
+	cawID := bson.NewObjectId()
+	err := session.DB(DefaultDatabase).C(cawCollection).Insert(bson.M{"_id": cawID, "user_id": bson.NewObjectId(), "message": "old caw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	editedCaw, err := cawDataStore.Edit(cawID.Hex(), "edited caw", nil, 2)
+	if err != nil {
+		t.Fatal("Edit err", err)
+	}
+	if editedCaw.EditCount != 1 || editedCaw.Message != "edited caw" {
+		t.Fatalf("Edited caw is wrong %+v", *editedCaw)
+	}
+}
@@ -0,0 +1,174 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ReconcileResult describes one reconciled batch of documents
+type ReconcileResult struct {
+	// LastID is ID of the last scanned document, next batch starts after it
+	LastID  string
+	Scanned int
+	Fixed   int
+	// Drift is sum of absolute differences between stored and recomputed counters
+	Drift int
+	Done  bool
+}
+
+// CounterDataStore recomputes denormalized counters from source collections.
+// Caw LikeCount and RecawCount are not recomputed as long as likes and recaws
+// are not stored in their own collections.
+type CounterDataStore interface {
+	ReconcileUsers(afterID string, batchSize int) (*ReconcileResult, error)
+	ReconcileCaws(afterID string, batchSize int) (*ReconcileResult, error)
+	Close()
+}
+
+type mgoCounterDataStore struct {
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
+}
+
+func (ds *mgoCounterDataStore) Close() {
+	ds.session.Close()
+}
+
+func (ds *mgoCounterDataStore) user() *mgo.Collection {
+	return ds.session.DB(ds.database).C(userCollection)
+}
+
+func (ds *mgoCounterDataStore) caw() *mgo.Collection {
+	return ds.session.DB(ds.database).C(cawCollection)
+}
+
+func (ds *mgoCounterDataStore) followRelation() *mgo.Collection {
+	return ds.session.DB(ds.database).C(followRelationCollection)
+}
+
+func afterIDQuery(afterID string) bson.M {
+	if !bson.IsObjectIdHex(afterID) {
+		return bson.M{}
+	}
+	return bson.M{"_id": bson.M{"$gt": bson.ObjectIdHex(afterID)}}
+}
+
+// storedCounter matches counter with value read by reconciliation, counters
+// of documents stored before the counter existed are missing
+func storedCounter(value int) interface{} {
+	if value == 0 {
+		return bson.M{"$in": []interface{}{0, nil}}
+	}
+	return value
+}
+
+func drift(stored, actual int) int {
+	if stored > actual {
+		return stored - actual
+	}
+	return actual - stored
+}
+
+// ReconcileUsers recomputes follow counters of batch of users with ID greater than afterID
+func (ds *mgoCounterDataStore) ReconcileUsers(afterID string, batchSize int) (*ReconcileResult, error) {
+	var users []models.User
+	err := ds.user().
+		Find(afterIDQuery(afterID)).
+		Select(bson.M{"followers_count": true, "following_count": true}).
+		Sort("_id").
+		Limit(batchSize).
+		All(&users)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	result := &ReconcileResult{LastID: afterID, Scanned: len(users), Done: len(users) < batchSize}
+	for _, user := range users {
+		result.LastID = user.ID.Hex()
+		followers, err := ds.followRelation().Find(bson.M{"following.user_id": user.ID}).Count()
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		following, err := ds.followRelation().Find(bson.M{"follower.user_id": user.ID}).Count()
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+
+		userDrift := drift(int(user.FollowersCount), followers) + drift(int(user.FollowingCount), following)
+		if userDrift == 0 {
+			continue
+		}
+		ds.logger.Warnf("Fixing drifted counters of user %s. followers_count: %d -> %d, following_count: %d -> %d",
+			user.ID.Hex(), user.FollowersCount, followers, user.FollowingCount, following)
+		// counters changed since they were read are left to the next run
+		err = ds.user().Update(
+			bson.M{
+				"_id":             user.ID,
+				"followers_count": storedCounter(int(user.FollowersCount)),
+				"following_count": storedCounter(int(user.FollowingCount)),
+			},
+			bson.M{"$set": bson.M{"followers_count": followers, "following_count": following}})
+		if err == mgo.ErrNotFound {
+			ds.logger.Warnf("Counters of user %s changed during reconciliation", user.ID.Hex())
+			continue
+		} else if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		result.Fixed++
+		result.Drift += userDrift
+	}
+	return result, nil
+}
+
+// ReconcileCaws recomputes replies counters of batch of caws with ID greater than afterID
+func (ds *mgoCounterDataStore) ReconcileCaws(afterID string, batchSize int) (*ReconcileResult, error) {
+	var caws []models.Caw
+	err := ds.caw().
+		Find(afterIDQuery(afterID)).
+		Select(bson.M{"replies_count": true}).
+		Sort("_id").
+		Limit(batchSize).
+		All(&caws)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	result := &ReconcileResult{LastID: afterID, Scanned: len(caws), Done: len(caws) < batchSize}
+	for _, caw := range caws {
+		result.LastID = caw.ID.Hex()
+		replies, err := ds.caw().Find(bson.M{"parent_id": caw.ID}).Count()
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+
+		cawDrift := drift(caw.RepliesCount, replies)
+		if cawDrift == 0 {
+			continue
+		}
+		ds.logger.Warnf("Fixing drifted counters of caw %s. replies_count: %d -> %d", caw.ID.Hex(), caw.RepliesCount, replies)
+		// counter changed since it was read is left to the next run
+		err = ds.caw().Update(
+			bson.M{"_id": caw.ID, "replies_count": storedCounter(caw.RepliesCount)},
+			bson.M{"$set": bson.M{"replies_count": replies}})
+		if err == mgo.ErrNotFound {
+			ds.logger.Warnf("Counters of caw %s changed during reconciliation", caw.ID.Hex())
+			continue
+		} else if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		result.Fixed++
+		result.Drift += cawDrift
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestReconcileCounters(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+	counterDataStore := &mgoCounterDataStore{session: session.Clone(), database: DefaultDatabase, logger: logger}
+	defer counterDataStore.Close()
+
+	var users []*models.User
+	for _, name := range []string{"first", "second", "third"} {
+		user, err := userDataStore.StoreUser(models.User{Name: name, Email: name + "@email.com", FollowersCount: 7})
+		if err != nil {
+			t.Fatal("StoreUser err", err)
+		}
+		users = append(users, user)
+	}
+	if err := userDataStore.AddFollowingUser(users[0].ID.Hex(), users[1].ID.Hex()); err != nil {
+		t.Fatal("AddFollowingUser err", err)
+	}
+
+	result, err := counterDataStore.ReconcileUsers("", 2)
+	if err != nil {
+		t.Fatal("ReconcileUsers err", err)
+	}
+	if result.Scanned != 2 || result.Done || result.LastID != users[1].ID.Hex() {
+		t.Fatalf("First batch is wrong %+v", *result)
+	}
+	result, err = counterDataStore.ReconcileUsers(result.LastID, 2)
+	if err != nil {
+		t.Fatal("ReconcileUsers err", err)
+	}
+	if result.Scanned != 1 || !result.Done || result.Fixed != 1 || result.Drift != 7 {
+		t.Fatalf("Last batch is wrong %+v", *result)
+	}
+
+	storedUser, err := userDataStore.GetUser(users[1].ID.Hex())
+	if err != nil {
+		t.Fatal("GetUser err", err)
+	}
+	if storedUser.FollowersCount != 1 {
+		t.Fatalf("Followers count should be recomputed, given %d", storedUser.FollowersCount)
+	}
+
+	parent := models.Caw{ID: bson.NewObjectId(), UserID: users[0].ID, Message: "parent", RepliesCount: 3}
+	reply := models.Caw{ID: bson.NewObjectId(), UserID: users[1].ID, Message: "reply", ParentID: parent.ID}
+	for _, caw := range []models.Caw{parent, reply} {
+		if err = counterDataStore.caw().Insert(caw); err != nil {
+			t.Fatal("Insert err", err)
+		}
+	}
+
+	result, err = counterDataStore.ReconcileCaws("", 10)
+	if err != nil {
+		t.Fatal("ReconcileCaws err", err)
+	}
+	expectedResult := ReconcileResult{LastID: reply.ID.Hex(), Scanned: 2, Fixed: 1, Drift: 2, Done: true}
+	if *result != expectedResult {
+		t.Fatalf("Results are different expected %+v, given %+v", expectedResult, *result)
+	}
+}
@@ -8,32 +8,64 @@ import (
 type DataStoreFactory interface {
 	CreateUserDataStore() UserDataStore
 	CreateCawDataStore() CawDataStore
+	CreateNotificationDataStore() NotificationDataStore
+	CreateCounterDataStore() CounterDataStore
+	CreateAdminDataStore() AdminDataStore
 	Close()
 }
 
 type mgoDataStoreFactory struct {
-	session *mgo.Session
-	logger  *logrus.Logger
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
 }
 
-func NewFactory(session *mgo.Session, logger *logrus.Logger) DataStoreFactory {
+// NewFactory creates DataStoreFactory of database with provided name. Indexes are created by Migrate.
+func NewFactory(session *mgo.Session, database string, logger *logrus.Logger) DataStoreFactory {
 	return &mgoDataStoreFactory{
-		session: session,
-		logger:  logger,
+		session:  session,
+		database: database,
+		logger:   logger,
 	}
 }
 
 func (f mgoDataStoreFactory) CreateUserDataStore() UserDataStore {
 	return &mgoUserDataStore{
-		session: f.session.Clone(),
-		logger:  f.logger,
+		session:  f.session.Clone(),
+		database: f.database,
+		logger:   f.logger,
 	}
 }
 
 func (f mgoDataStoreFactory) CreateCawDataStore() CawDataStore {
 	return &mgoCawDataStore{
-		session: f.session.Clone(),
-		logger:  f.logger,
+		session:  f.session.Clone(),
+		database: f.database,
+		logger:   f.logger,
+	}
+}
+
+func (f mgoDataStoreFactory) CreateNotificationDataStore() NotificationDataStore {
+	return &mgoNotificationDataStore{
+		session:  f.session.Clone(),
+		database: f.database,
+		logger:   f.logger,
+	}
+}
+
+func (f mgoDataStoreFactory) CreateCounterDataStore() CounterDataStore {
+	return &mgoCounterDataStore{
+		session:  f.session.Clone(),
+		database: f.database,
+		logger:   f.logger,
+	}
+}
+
+func (f mgoDataStoreFactory) CreateAdminDataStore() AdminDataStore {
+	return &mgoAdminDataStore{
+		session:  f.session.Clone(),
+		database: f.database,
+		logger:   f.logger,
 	}
 }
 
@@ -2,11 +2,19 @@ package infrastructure
 
 import "errors"
 
-const (
-	db = "test"
-)
+// DefaultDatabase is used when database name is not configured
+const DefaultDatabase = "test"
+
+// TenantDatabase returns name of isolated database of tenant
+func TenantDatabase(database string, tenant string) string {
+	return database + "_" + tenant
+}
 
 var (
-	ErrNotFound   = errors.New("Not Found")
-	ErrUserExists = errors.New("User Exists")
+	ErrNotFound     = errors.New("Not Found")
+	ErrUserExists   = errors.New("User Exists")
+	ErrFollowExists = errors.New("Follow Exists")
+	ErrSelfFollow   = errors.New("Self Follow")
+	ErrEditLimit    = errors.New("Edit Limit")
+	ErrEditConflict = errors.New("Edit Conflict")
 )
@@ -0,0 +1,354 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	migrationCollection = "migration"
+)
+
+// Migration is a versioned change of schema or data. Applied migrations are recorded
+// in migration collection, so every migration runs once per database.
+type Migration struct {
+	Version     int
+	Description string
+	steps       []migrationStep
+}
+
+// MigrationResult describes migration applied by Migrate or pending in dry run
+type MigrationResult struct {
+	Version     int
+	Description string
+	// Changes made by migration steps, in dry run changes which would be made
+	Changes []string
+	// Destructive migration deletes data, it is not applied on startup of the service
+	Destructive bool
+}
+
+// migrationRecord is stored in migration collection for every applied migration
+type migrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// migrationStep is a part of migration. Steps have to be idempotent, because
+// migration interrupted part way is applied again from its first step.
+type migrationStep interface {
+	// plan returns changes apply would make, empty if there is nothing to change
+	plan(database *mgo.Database) ([]string, error)
+	apply(database *mgo.Database, logger *logrus.Logger) error
+	// destructive steps delete data when their plan is not empty
+	destructive() bool
+}
+
+// migrations are applied in order of versions, new migrations are appended with the next version
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create unique index of user names and emails",
+		steps: []migrationStep{
+			indexStep{collection: userCollection, indexes: userIndexes},
+		},
+	},
+	{
+		Version:     2,
+		Description: "remove duplicated follow relations and create follow relation indexes",
+		steps: []migrationStep{
+			repairFollowsStep{},
+			indexStep{collection: followRelationCollection, indexes: followRelationIndexes},
+		},
+	},
+	{
+		Version:     3,
+		Description: "create caw, caw revision and notification indexes",
+		steps: []migrationStep{
+			indexStep{collection: cawCollection, indexes: cawIndexes},
+			indexStep{collection: cawRevisionCollection, indexes: cawRevisionIndexes},
+			indexStep{collection: notificationCollection, indexes: notificationIndexes},
+		},
+	},
+	{
+		Version:     4,
+		Description: "backfill edit count and visibility of caws created before editing and visibility",
+		steps: []migrationStep{
+			backfillStep{collection: cawCollection, field: "edit_count", value: 0},
+			backfillStep{collection: cawCollection, field: "visibility", value: models.VisibilityPublic},
+		},
+	},
+	{
+		Version:     5,
+		Description: "recreate indexes created with outdated options",
+		steps: []migrationStep{
+			indexStep{collection: userCollection, indexes: userIndexes},
+			indexStep{collection: followRelationCollection, indexes: followRelationIndexes},
+			indexStep{collection: cawCollection, indexes: cawIndexes},
+			indexStep{collection: cawRevisionCollection, indexes: cawRevisionIndexes},
+			indexStep{collection: notificationCollection, indexes: notificationIndexes},
+		},
+	},
+}
+
+// Migrate applies migrations not yet recorded in the database. In dry run nothing is
+// changed and returned results describe pending migrations.
+func Migrate(database *mgo.Database, logger *logrus.Logger, dryRun bool) ([]MigrationResult, error) {
+	applied, err := appliedMigrations(database)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	results := []MigrationResult{}
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		result := MigrationResult{Version: migration.Version, Description: migration.Description}
+		for _, step := range migration.steps {
+			changes, err := step.plan(database)
+			if err != nil {
+				logger.Errorf("Cannot plan migration %d. err: %s", migration.Version, err)
+				return results, err
+			}
+			result.Changes = append(result.Changes, changes...)
+			result.Destructive = result.Destructive || step.destructive() && len(changes) > 0
+			if dryRun || len(changes) == 0 {
+				continue
+			}
+			if err := step.apply(database, logger); err != nil {
+				logger.Errorf("Cannot apply migration %d. err: %s", migration.Version, err)
+				return results, err
+			}
+		}
+
+		if !dryRun {
+			err := database.C(migrationCollection).Insert(migrationRecord{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			})
+			// migration applied concurrently by another instance is already recorded
+			if err != nil && !mgo.IsDup(err) {
+				logger.Error(err)
+				return results, err
+			}
+			logger.Infof("Applied migration %d: %s", migration.Version, migration.Description)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// appliedMigrations returns versions recorded in migration collection
+func appliedMigrations(database *mgo.Database) (map[int]bool, error) {
+	var records []migrationRecord
+	if err := database.C(migrationCollection).Find(nil).All(&records); err != nil {
+		return nil, err
+	}
+	applied := map[int]bool{}
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+	return applied, nil
+}
+
+// ensureIndexes creates indexes of all collections
+func ensureIndexes(database *mgo.Database, logger *logrus.Logger) error {
+	ensures := []func(database *mgo.Database, logger *logrus.Logger) error{
+		ensureUserIndexes,
+		ensureFollowRelationIndexes,
+		ensureCawIndexes,
+		ensureNotificationIndexes,
+	}
+	for _, ensure := range ensures {
+		if err := ensure(database, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureCollectionIndexes creates indexes of collection which do not exist yet. Existing
+// index with the same key but different options is dropped and created again.
+func ensureCollectionIndexes(database *mgo.Database, logger *logrus.Logger, collection string, indexes []mgo.Index) error {
+	if err := dropConflictingIndexes(database, logger, collection, indexes); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := database.C(collection).EnsureIndex(index); err != nil {
+			logger.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// dropConflictingIndexes drops existing indexes of collection with the same key as one of
+// indexes but different options, because such index cannot be created next to them
+func dropConflictingIndexes(database *mgo.Database, logger *logrus.Logger, collection string, indexes []mgo.Index) error {
+	_, conflicting, err := diffIndexes(database, collection, indexes)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	for _, index := range conflicting {
+		if err := database.C(collection).DropIndexName(index.Name); err != nil {
+			logger.Error(err)
+			return err
+		}
+		logger.Infof("Dropped index %s of %s with outdated options", index.Name, collection)
+	}
+	return nil
+}
+
+// diffIndexes returns indexes missing in collection and existing indexes of collection
+// with the same key as one of indexes but different unique or sparse option
+func diffIndexes(database *mgo.Database, collection string, indexes []mgo.Index) (missing []mgo.Index, conflicting []mgo.Index, err error) {
+	existing, err := database.C(collection).Indexes()
+	// listing indexes of collection which does not exist yet fails on some servers
+	if err != nil {
+		if names, namesErr := database.CollectionNames(); namesErr == nil && !contains(names, collection) {
+			existing, err = nil, nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byKey := map[string]mgo.Index{}
+	for _, index := range existing {
+		byKey[strings.Join(index.Key, ",")] = index
+	}
+	for _, index := range indexes {
+		current, ok := byKey[strings.Join(index.Key, ",")]
+		if !ok {
+			missing = append(missing, index)
+		} else if current.Unique != index.Unique || current.Sparse != index.Sparse {
+			missing = append(missing, index)
+			conflicting = append(conflicting, current)
+		}
+	}
+	return missing, conflicting, nil
+}
+
+// indexStep creates indexes of collection and recreates indexes with outdated options
+type indexStep struct {
+	collection string
+	indexes    []mgo.Index
+}
+
+func (s indexStep) plan(database *mgo.Database) ([]string, error) {
+	missing, conflicting, err := diffIndexes(database, s.collection, s.indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []string{}
+	for _, index := range conflicting {
+		changes = append(changes, fmt.Sprintf("drop index %s(%s) with outdated options", s.collection, strings.Join(index.Key, ", ")))
+	}
+	for _, index := range missing {
+		changes = append(changes, fmt.Sprintf("create index %s(%s)%s", s.collection, strings.Join(index.Key, ", "), indexOptions(index)))
+	}
+	return changes, nil
+}
+
+func (s indexStep) apply(database *mgo.Database, logger *logrus.Logger) error {
+	return ensureCollectionIndexes(database, logger, s.collection, s.indexes)
+}
+
+func (s indexStep) destructive() bool {
+	return false
+}
+
+// indexOptions describes options of index which have to match existing index
+func indexOptions(index mgo.Index) string {
+	var options []string
+	if index.Unique {
+		options = append(options, "unique")
+	}
+	if index.Sparse {
+		options = append(options, "sparse")
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return " " + strings.Join(options, ", ")
+}
+
+// backfillStep sets value of field on documents of collection which do not have the field
+type backfillStep struct {
+	collection string
+	field      string
+	value      interface{}
+}
+
+func (s backfillStep) missing() bson.M {
+	return bson.M{s.field: bson.M{"$exists": false}}
+}
+
+func (s backfillStep) plan(database *mgo.Database) ([]string, error) {
+	count, err := database.C(s.collection).Find(s.missing()).Count()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("set %s.%s to %v in %d documents", s.collection, s.field, s.value, count)}, nil
+}
+
+func (s backfillStep) apply(database *mgo.Database, logger *logrus.Logger) error {
+	info, err := database.C(s.collection).UpdateAll(s.missing(), bson.M{"$set": bson.M{s.field: s.value}})
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	logger.Infof("Backfilled %s.%s in %d documents", s.collection, s.field, info.Updated)
+	return nil
+}
+
+func (s backfillStep) destructive() bool {
+	return false
+}
+
+// repairFollowsStep removes duplicated and self follow relations, so unique index can be created
+type repairFollowsStep struct{}
+
+func (s repairFollowsStep) plan(database *mgo.Database) ([]string, error) {
+	// there is nothing to repair in database without follow relations
+	count, err := database.C(followRelationCollection).Count()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("remove duplicated and self follow relations of %d relations, recompute follow counters", count)}, nil
+}
+
+func (s repairFollowsStep) apply(database *mgo.Database, logger *logrus.Logger) error {
+	report, err := RepairFollowRelations(database, logger)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Repaired follow relations. duplicates removed: %d, self follows removed: %d, users updated: %d",
+		report.DuplicatesRemoved, report.SelfFollowsRemoved, report.UsersUpdated)
+	return nil
+}
+
+func (s repairFollowsStep) destructive() bool {
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+	"strings"
+	"testing"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMigrate(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+
+	// caw stored before editing and visibility existed
+	cawID := bson.NewObjectId()
+	err := session.DB(DefaultDatabase).C(cawCollection).Insert(bson.M{"_id": cawID, "user_id": bson.NewObjectId(), "message": "old caw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := Migrate(session.DB(DefaultDatabase), logger, true)
+	if err != nil || len(pending) != len(migrations) {
+		t.Fatalf("Dry run expected %d pending migrations, given %d, err: %v", len(migrations), len(pending), err)
+	}
+	for _, migration := range pending {
+		if migration.Destructive {
+			t.Fatalf("Migration %d cannot delete data of database without follow relations", migration.Version)
+		}
+	}
+	count, err := session.DB(DefaultDatabase).C(migrationCollection).Count()
+	if err != nil || count != 0 {
+		t.Fatalf("Dry run must not record migrations, given %d, err: %v", count, err)
+	}
+
+	applied, err := Migrate(session.DB(DefaultDatabase), logger, false)
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Expected %d applied migrations, given %d, err: %v", len(migrations), len(applied), err)
+	}
+	var caw models.Caw
+	if err = session.DB(DefaultDatabase).C(cawCollection).FindId(cawID).One(&caw); err != nil {
+		t.Fatal(err)
+	}
+	if caw.Visibility != models.VisibilityPublic {
+		t.Fatalf("Visibility expected to be backfilled, given %q", caw.Visibility)
+	}
+	missing, err := session.DB(DefaultDatabase).C(cawCollection).Find(bson.M{"edit_count": bson.M{"$exists": false}}).Count()
+	if err != nil || missing != 0 {
+		t.Fatalf("Edit count expected to be backfilled, missing in %d caws, err: %v", missing, err)
+	}
+
+	pending, err = Migrate(session.DB(DefaultDatabase), logger, true)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Expected no pending migrations, given %d, err: %v", len(pending), err)
+	}
+}
+
+func TestMigrateRecreatesIndexWithOutdatedOptions(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	database := session.DB(DefaultDatabase)
+
+	// follow relation index created before it was unique
+	err := database.C(followRelationCollection).EnsureIndex(mgo.Index{Key: []string{"follower.user_id", "following.user_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := Migrate(database, logger, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recreated := false
+	for _, change := range pending[len(pending)-1].Changes {
+		recreated = recreated || strings.HasPrefix(change, "drop index "+followRelationCollection)
+	}
+	if !recreated {
+		t.Fatalf("Expected index of follow relations to be recreated, given %v", pending[len(pending)-1].Changes)
+	}
+
+	if _, err = Migrate(database, logger, false); err != nil {
+		t.Fatal(err)
+	}
+	indexes, err := database.C(followRelationCollection).Indexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range indexes {
+		if strings.Join(index.Key, ",") == "follower.user_id,following.user_id" && !index.Unique {
+			t.Fatalf("Expected unique follow relation index, given %+v", index)
+		}
+	}
+}
+
+func TestMigrateMarksFollowRepairDestructive(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	database := session.DB(DefaultDatabase)
+
+	relation := models.FollowRelation{
+		Follower:  models.Follow{UserID: bson.NewObjectId(), Name: "follower"},
+		Following: models.Follow{UserID: bson.NewObjectId(), Name: "following"},
+	}
+	if err := database.C(followRelationCollection).Insert(relation, relation); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := Migrate(database, logger, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range pending {
+		if migration.Destructive != (migration.Version == 2) {
+			t.Fatalf("Only repair of follow relations expected to be destructive, given migration %d destructive: %v",
+				migration.Version, migration.Destructive)
+		}
+	}
+}
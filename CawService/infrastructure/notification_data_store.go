@@ -0,0 +1,230 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	notificationCollection = "notification"
+)
+
+// NotificationDataStore represents data access layer for user notifications
+type NotificationDataStore interface {
+	Store(notification models.Notification) (*models.Notification, error)
+	GetByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error)
+	GetGroupedByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error)
+	CountUnread(userID string) (int, error)
+	MarkAsRead(userID string, notificationIDs []string) (int, error)
+	MarkAllAsRead(userID string) (int, error)
+	DeleteByUserID(userID string) (int, error)
+	Close()
+}
+
+// mgoNotificationDataStore implements NotificationDataStore and provides access to mongodb store
+type mgoNotificationDataStore struct {
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
+}
+
+// notificationIndexes are required by notification queries
+var notificationIndexes = []mgo.Index{
+	{Key: []string{"user_id", "read", "-created_at"}, Background: true},
+}
+
+// ensureNotificationIndexes creates indexes required by notification queries
+func ensureNotificationIndexes(database *mgo.Database, logger *logrus.Logger) error {
+	return ensureCollectionIndexes(database, logger, notificationCollection, notificationIndexes)
+}
+
+func (ds *mgoNotificationDataStore) notification() *mgo.Collection {
+	return ds.session.DB(ds.database).C(notificationCollection)
+}
+
+// Store persists provided notification as unread
+func (ds *mgoNotificationDataStore) Store(notification models.Notification) (*models.Notification, error) {
+	notification.ID = bson.NewObjectId()
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+	err := ds.notification().Insert(&notification)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// GetByUserID returns page of newest notifications of user with provided ID
+func (ds *mgoNotificationDataStore) GetByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	query := bson.M{"user_id": bson.ObjectIdHex(userID)}
+	if unreadOnly {
+		query["read"] = false
+	}
+
+	notifications := []models.Notification{}
+	err := ds.notification().
+		Find(query).
+		Sort("-created_at").
+		Skip(page * pageSize).
+		Limit(pageSize).
+		All(&notifications)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// GetGroupedByUserID returns all notifications of page of notification groups of user with provided ID,
+// so groups built by models.GroupNotifications are not split across pages. Groups are notifications
+// with the same type and caw, the group with the newest notification is the first.
+func (ds *mgoNotificationDataStore) GetGroupedByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	query := bson.M{"user_id": bson.ObjectIdHex(userID)}
+	if unreadOnly {
+		query["read"] = false
+	}
+
+	var groups []struct {
+		Key struct {
+			Type  string        `bson:"type"`
+			CawID bson.ObjectId `bson:"caw_id,omitempty"`
+		} `bson:"_id"`
+	}
+	err := ds.notification().Pipe([]bson.M{
+		{"$match": query},
+		{"$group": bson.M{
+			"_id":    bson.M{"type": "$type", "caw_id": "$caw_id"},
+			"latest": bson.M{"$max": "$created_at"},
+			"last":   bson.M{"$max": "$_id"},
+		}},
+		{"$sort": bson.D{{Name: "latest", Value: -1}, {Name: "last", Value: -1}}},
+		{"$skip": page * pageSize},
+		{"$limit": pageSize},
+	}).All(&groups)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	notifications := []models.Notification{}
+	if len(groups) == 0 {
+		return notifications, nil
+	}
+	keys := []bson.M{}
+	for _, group := range groups {
+		key := bson.M{"type": group.Key.Type, "caw_id": nil}
+		if group.Key.CawID != "" {
+			key["caw_id"] = group.Key.CawID
+		}
+		keys = append(keys, key)
+	}
+	query["$or"] = keys
+	if err = ds.notification().Find(query).Sort("-created_at").All(&notifications); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// CountUnread returns number of unread notifications of user with provided ID
+func (ds *mgoNotificationDataStore) CountUnread(userID string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return 0, ErrNotFound
+	}
+
+	count, err := ds.notification().
+		Find(bson.M{"user_id": bson.ObjectIdHex(userID), "read": false}).
+		Count()
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkAsRead marks provided notifications of user as read and returns number of found notifications,
+// including notifications which were already read, so marking is idempotent
+func (ds *mgoNotificationDataStore) MarkAsRead(userID string, notificationIDs []string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return 0, ErrNotFound
+	}
+
+	ids := []bson.ObjectId{}
+	for _, notificationID := range notificationIDs {
+		if !bson.IsObjectIdHex(notificationID) {
+			ds.logger.Error("Notification Id is not mongo ObjectId")
+			return 0, ErrNotFound
+		}
+		ids = append(ids, bson.ObjectIdHex(notificationID))
+	}
+
+	info, err := ds.notification().UpdateAll(
+		bson.M{"user_id": bson.ObjectIdHex(userID), "_id": bson.M{"$in": ids}},
+		bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return info.Matched, nil
+}
+
+// MarkAllAsRead marks all notifications of user as read and returns number of updated notifications
+func (ds *mgoNotificationDataStore) MarkAllAsRead(userID string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return 0, ErrNotFound
+	}
+
+	info, err := ds.notification().UpdateAll(
+		bson.M{"user_id": bson.ObjectIdHex(userID), "read": false},
+		bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return info.Updated, nil
+}
+
+// DeleteByUserID removes notifications sent to user with provided ID and notifications
+// about actions of the user and returns number of removed notifications
+func (ds *mgoNotificationDataStore) DeleteByUserID(userID string) (int, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return 0, ErrNotFound
+	}
+
+	id := bson.ObjectIdHex(userID)
+	info, err := ds.notification().RemoveAll(bson.M{"$or": []bson.M{
+		{"user_id": id},
+		{"actor.user_id": id},
+	}})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+func (ds *mgoNotificationDataStore) Close() {
+	ds.session.Close()
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestNotificationReadState(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	notificationDataStore := &mgoNotificationDataStore{session.Clone(), DefaultDatabase, logger}
+	defer notificationDataStore.Close()
+
+	userID := bson.NewObjectId()
+	var storedIDs []string
+	for i := 0; i < 3; i++ {
+		stored, err := notificationDataStore.Store(models.Notification{
+			UserID: userID,
+			Type:   models.NotificationFollow,
+			Actor:  models.Follow{UserID: bson.NewObjectId(), Name: "follower"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		storedIDs = append(storedIDs, stored.ID.Hex())
+	}
+
+	unread, err := notificationDataStore.CountUnread(userID.Hex())
+	if err != nil || unread != 3 {
+		t.Fatalf("Unread count expected 3, given %d, err: %v", unread, err)
+	}
+
+	updated, err := notificationDataStore.MarkAsRead(userID.Hex(), storedIDs[:1])
+	if err != nil || updated != 1 {
+		t.Fatalf("MarkAsRead updated %d notifications expected 1, err: %v", updated, err)
+	}
+
+	notifications, err := notificationDataStore.GetByUserID(userID.Hex(), true, 0)
+	if err != nil || len(notifications) != 2 {
+		t.Fatalf("Unread notifications expected 2, given %d, err: %v", len(notifications), err)
+	}
+
+	found, err := notificationDataStore.MarkAsRead(userID.Hex(), storedIDs[:1])
+	if err != nil || found != 1 {
+		t.Fatalf("MarkAsRead found %d already read notifications expected 1, err: %v", found, err)
+	}
+
+	updated, err = notificationDataStore.MarkAllAsRead(userID.Hex())
+	if err != nil || updated != 2 {
+		t.Fatalf("MarkAllAsRead updated %d notifications expected 2, err: %v", updated, err)
+	}
+
+	unread, err = notificationDataStore.CountUnread(userID.Hex())
+	if err != nil || unread != 0 {
+		t.Fatalf("Unread count expected 0, given %d, err: %v", unread, err)
+	}
+}
+
+func TestDeleteNotificationsByUserID(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	notificationDataStore := &mgoNotificationDataStore{session.Clone(), DefaultDatabase, logger}
+	defer notificationDataStore.Close()
+
+	userID := bson.NewObjectId()
+	otherID := bson.NewObjectId()
+	notifications := []models.Notification{
+		{UserID: userID, Type: models.NotificationFollow, Actor: models.Follow{UserID: otherID, Name: "other"}},
+		{UserID: otherID, Type: models.NotificationFollow, Actor: models.Follow{UserID: userID, Name: "user"}},
+		{UserID: otherID, Type: models.NotificationLike, Actor: models.Follow{UserID: bson.NewObjectId(), Name: "third"}},
+	}
+	for _, notification := range notifications {
+		if _, err := notificationDataStore.Store(notification); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := notificationDataStore.DeleteByUserID(userID.Hex())
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 removed notifications, given %d, err: %v", removed, err)
+	}
+	remaining, err := notificationDataStore.GetByUserID(otherID.Hex(), false, 0)
+	if err != nil || len(remaining) != 1 || remaining[0].Type != models.NotificationLike {
+		t.Fatalf("Expected only notification not related to user, given %v, err: %v", remaining, err)
+	}
+}
+
+func TestGetGroupedNotificationsKeepsGroupsWhole(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	notificationDataStore := &mgoNotificationDataStore{session.Clone(), DefaultDatabase, logger}
+	defer notificationDataStore.Close()
+
+	userID := bson.NewObjectId()
+	// one follow group larger than page and a like group per caw stored after it
+	for i := 0; i < pageSize+2; i++ {
+		if _, err := notificationDataStore.Store(models.Notification{UserID: userID, Type: models.NotificationFollow,
+			Actor: models.Follow{UserID: bson.NewObjectId(), Name: "follower"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cawIDs := []bson.ObjectId{}
+	for i := 0; i < pageSize; i++ {
+		cawIDs = append(cawIDs, bson.NewObjectId())
+		if _, err := notificationDataStore.Store(models.Notification{UserID: userID, Type: models.NotificationLike,
+			Actor: models.Follow{UserID: bson.NewObjectId(), Name: "liker"}, CawID: cawIDs[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications, err := notificationDataStore.GetGroupedByUserID(userID.Hex(), false, 0)
+	if err != nil || len(notifications) != pageSize {
+		t.Fatalf("Expected %d like notifications on first page, given %d, err: %v", pageSize, len(notifications), err)
+	}
+	if groups := models.GroupNotifications(notifications); len(groups) != pageSize || groups[0].CawID != cawIDs[pageSize-1] {
+		t.Fatalf("Expected %d groups with the newest first, given %+v", pageSize, groups)
+	}
+
+	notifications, err = notificationDataStore.GetGroupedByUserID(userID.Hex(), false, 1)
+	if err != nil || len(notifications) != pageSize+2 {
+		t.Fatalf("Expected whole follow group on second page, given %d, err: %v", len(notifications), err)
+	}
+	if groups := models.GroupNotifications(notifications); len(groups) != 1 || groups[0].Count != pageSize+2 {
+		t.Fatalf("Expected single follow group, given %+v", groups)
+	}
+}
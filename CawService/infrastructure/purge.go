@@ -0,0 +1,41 @@
+package infrastructure
+
+// PurgeReport summarizes data removed by PurgeUser
+type PurgeReport struct {
+	FollowsRemoved       int
+	CawsRemoved          int
+	NotificationsRemoved int
+}
+
+// PurgeUser deletes user with userID together with follow relations, caws and their revisions
+// and notifications of the user. Follow counters of followed users and followers are updated.
+// ErrNotFound is returned if user does not exist.
+func PurgeUser(factory DataStoreFactory, userID string) (*PurgeReport, error) {
+	users := factory.CreateUserDataStore()
+	defer users.Close()
+	if _, err := users.GetUser(userID); err != nil {
+		return nil, err
+	}
+
+	var report PurgeReport
+	var err error
+	admin := factory.CreateAdminDataStore()
+	defer admin.Close()
+	if report.FollowsRemoved, err = admin.RemoveFollows(userID); err != nil {
+		return nil, err
+	}
+	if report.CawsRemoved, err = admin.PurgeCaws(userID); err != nil {
+		return nil, err
+	}
+
+	notifications := factory.CreateNotificationDataStore()
+	defer notifications.Close()
+	if report.NotificationsRemoved, err = notifications.DeleteByUserID(userID); err != nil {
+		return nil, err
+	}
+
+	if err = users.DeleteUser(userID); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
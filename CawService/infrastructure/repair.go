@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"Caw/UserService/models"
+
+	"github.com/Sirupsen/logrus"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// FollowRepairReport summarizes changes done by RepairFollowRelations
+type FollowRepairReport struct {
+	DuplicatesRemoved  int
+	SelfFollowsRemoved int
+	UsersUpdated       int
+}
+
+// RepairFollowRelations removes duplicated and self follow relations, recomputes
+// follow counters of all users and creates unique follow relation index
+func RepairFollowRelations(database *mgo.Database, logger *logrus.Logger) (*FollowRepairReport, error) {
+	var report FollowRepairReport
+	relations := database.C(followRelationCollection)
+
+	var duplicates []struct {
+		IDs []bson.ObjectId `bson:"ids"`
+	}
+	err := relations.Pipe([]bson.M{
+		{"$group": bson.M{
+			"_id":   bson.M{"follower": "$follower.user_id", "following": "$following.user_id"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}).AllowDiskUse().All(&duplicates)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	for _, duplicate := range duplicates {
+		// the first relation is kept
+		info, err := relations.RemoveAll(bson.M{"_id": bson.M{"$in": duplicate.IDs[1:]}})
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		report.DuplicatesRemoved += info.Removed
+	}
+
+	var selfFollows []struct {
+		ID bson.ObjectId `bson:"_id"`
+	}
+	err = relations.Pipe([]bson.M{
+		{"$project": bson.M{"self": bson.M{"$eq": []string{"$follower.user_id", "$following.user_id"}}}},
+		{"$match": bson.M{"self": true}},
+	}).All(&selfFollows)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	for _, selfFollow := range selfFollows {
+		if err = relations.RemoveId(selfFollow.ID); err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		report.SelfFollowsRemoved++
+	}
+
+	users := database.C(userCollection)
+	iter := users.Find(nil).Select(bson.M{"followers_count": true, "following_count": true}).Iter()
+	var user models.User
+	for iter.Next(&user) {
+		followers, err := relations.Find(bson.M{"following.user_id": user.ID}).Count()
+		if err != nil {
+			iter.Close()
+			logger.Error(err)
+			return nil, err
+		}
+		following, err := relations.Find(bson.M{"follower.user_id": user.ID}).Count()
+		if err != nil {
+			iter.Close()
+			logger.Error(err)
+			return nil, err
+		}
+
+		if user.FollowersCount != uint64(followers) || user.FollowingCount != uint64(following) {
+			err = users.UpdateId(user.ID, bson.M{"$set": bson.M{"followers_count": followers, "following_count": following}})
+			if err != nil {
+				iter.Close()
+				logger.Error(err)
+				return nil, err
+			}
+			report.UsersUpdated++
+		}
+		user = models.User{}
+	}
+	if err = iter.Close(); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	ensureFollowRelationIndexes(database, logger)
+	return &report, nil
+}
@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"database/sql"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// tables are reported by Stats
+var tables = []string{"users", "follow", "caw", "caw_mention", "caw_hashtag", "caw_revision", "notification"}
+
+// sqliteAdminDataStore implements AdminDataStore on SQLite database
+type sqliteAdminDataStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// Close does nothing, database is shared by all stores and closed by factory
+func (ds *sqliteAdminDataStore) Close() {
+}
+
+// SetSuspended suspends or restores account of user with userID. Suspended users cannot authenticate
+// and tokens issued to them before are rejected.
+func (ds *sqliteAdminDataStore) SetSuspended(userID string, suspended bool) error {
+	return ds.updateUser("suspended = ?", suspended, userID)
+}
+
+// SetPassword replaces password hash of user with userID
+func (ds *sqliteAdminDataStore) SetPassword(userID string, passwordHash string) error {
+	return ds.updateUser("password = ?", passwordHash, userID)
+}
+
+func (ds *sqliteAdminDataStore) updateUser(set string, value interface{}, userID string) error {
+	result, err := ds.db.Exec("UPDATE users SET "+set+" WHERE id = ?", value, userID)
+	if err != nil {
+		ds.logger.Error(err)
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		ds.logger.Error(err)
+		return err
+	} else if updated == 0 {
+		return infrastructure.ErrNotFound
+	}
+	return nil
+}
+
+// RemoveFollows removes follow relations of user with userID in both directions and
+// returns number of removed relations. Follow counters are updated in the same transaction.
+func (ds *sqliteAdminDataStore) RemoveFollows(userID string) (int, error) {
+	var removed int64
+	err := inTx(ds.db, func(tx *sql.Tx) error {
+		statements := []string{
+			`UPDATE users SET followers_count = followers_count - 1
+				WHERE id IN (SELECT following_id FROM follow WHERE follower_id = ?)`,
+			`UPDATE users SET following_count = following_count - 1
+				WHERE id IN (SELECT follower_id FROM follow WHERE following_id = ?)`,
+			"UPDATE users SET followers_count = 0, following_count = 0 WHERE id = ?",
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement, userID); err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec("DELETE FROM follow WHERE follower_id = ? OR following_id = ?", userID, userID)
+		if err != nil {
+			return err
+		}
+		removed, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return int(removed), nil
+}
+
+// PurgeCaws removes all caws of user with userID with their revisions and returns number of removed caws
+func (ds *sqliteAdminDataStore) PurgeCaws(userID string) (int, error) {
+	result, err := ds.db.Exec("DELETE FROM caw WHERE user_id = ?", userID)
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return int(removed), nil
+}
+
+// EnsureIndexes applies pending migrations, indexes are created by migrations with tables
+func (ds *sqliteAdminDataStore) EnsureIndexes() error {
+	_, err := Migrate(ds.db, ds.logger, false)
+	return err
+}
+
+// Stats returns row counts and indexes of all tables. Size and StorageSize are
+// not reported, SQLite does not track space used by a table.
+func (ds *sqliteAdminDataStore) Stats() ([]infrastructure.CollectionStats, error) {
+	var stats []infrastructure.CollectionStats
+	for _, table := range tables {
+		collection := infrastructure.CollectionStats{Name: table}
+		if err := ds.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&collection.Count); err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+
+		rows, err := ds.db.Query("SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? ORDER BY name", table)
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		for rows.Next() {
+			var index string
+			if err := rows.Scan(&index); err != nil {
+				rows.Close()
+				ds.logger.Error(err)
+				return nil, err
+			}
+			collection.Indexes = append(collection.Indexes, index)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		stats = append(stats, collection)
+	}
+	return stats, nil
+}
@@ -0,0 +1,341 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const cawColumns = "c.id, c.user_id, c.user_name, c.parent_id, c.message, c.created_at, c.like_count, " +
+	"c.recaw_count, c.replies_count, c.visibility, c.mentions, c.hashtags, c.edited_at, c.edit_count"
+
+// sqliteCawDataStore implements CawDataStore on SQLite database. Mentions and hashtags
+// are stored with caw in JSON and in caw_mention and caw_hashtag tables used by queries.
+type sqliteCawDataStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// Close does nothing, database is shared by all stores and closed by factory
+func (ds *sqliteCawDataStore) Close() {
+}
+
+func scanCaw(row scanner) (*models.Caw, error) {
+	var caw models.Caw
+	var id, userID, mentions, hashtags string
+	var parentID sql.NullString
+	var createdAt int64
+	var editedAt sql.NullInt64
+	err := row.Scan(&id, &userID, &caw.UserName, &parentID, &caw.Message, &createdAt, &caw.LikeCount,
+		&caw.RecawCount, &caw.RepliesCount, &caw.Visibility, &mentions, &hashtags, &editedAt, &caw.EditCount)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(mentions), &caw.Mentions); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(hashtags), &caw.Hashtags); err != nil {
+		return nil, err
+	}
+	caw.ID = objectID(id)
+	caw.UserID = objectID(userID)
+	caw.ParentID = objectID(parentID.String)
+	caw.CreatedAt = fromMillis(createdAt)
+	if editedAt.Valid {
+		at := fromMillis(editedAt.Int64)
+		caw.EditedAt = &at
+	}
+	return &caw, nil
+}
+
+// visibleTo returns condition matching caws c visible to viewer with its arguments
+func visibleTo(viewerID string) (string, []interface{}) {
+	condition := `(c.visibility NOT IN ('` + models.VisibilityFollowers + `', '` + models.VisibilityMentioned + `')
+		OR c.user_id = ?
+		OR (c.visibility = '` + models.VisibilityFollowers + `' AND EXISTS
+			(SELECT 1 FROM follow f WHERE f.follower_id = ? AND f.following_id = c.user_id))
+		OR (c.visibility = '` + models.VisibilityMentioned + `' AND EXISTS
+			(SELECT 1 FROM caw_mention m WHERE m.caw_id = c.id AND m.user_id = ?)))`
+	return condition, []interface{}{viewerID, viewerID, viewerID}
+}
+
+// queryCaws returns caws matching condition, args are arguments of condition
+func (ds *sqliteCawDataStore) queryCaws(condition string, args []interface{}, order string, limit int, offset int) ([]models.Caw, error) {
+	rows, err := ds.db.Query("SELECT "+cawColumns+" FROM caw c WHERE "+condition+
+		" ORDER BY "+order+" LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	caws := []models.Caw{}
+	for rows.Next() {
+		caw, err := scanCaw(rows)
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		caws = append(caws, *caw)
+	}
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return caws, nil
+}
+
+// visibleCaws returns page of caws matching condition and visible to viewer
+func (ds *sqliteCawDataStore) visibleCaws(condition string, arg string, viewerID string, order string, page int) ([]models.Caw, error) {
+	visibility, visibilityArgs := visibleTo(viewerID)
+	return ds.queryCaws(condition+" AND "+visibility, append([]interface{}{arg}, visibilityArgs...),
+		order, pageSize, page*pageSize)
+}
+
+func (ds *sqliteCawDataStore) Store(caw models.Caw) (*models.Caw, error) {
+	caw.ID = bson.NewObjectId()
+	caw.CreatedAt = time.Now()
+	caw.Hashtags = models.ExtractHashtags(caw.Message)
+	mentions, hashtags, err := marshalTags(caw.Mentions, caw.Hashtags)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	err = inTx(ds.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO caw (id, user_id, user_name, parent_id, message, created_at, like_count,
+			recaw_count, replies_count, visibility, mentions, hashtags, edit_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			caw.ID.Hex(), caw.UserID.Hex(), caw.UserName, nullID(caw.ParentID), caw.Message, toMillis(caw.CreatedAt),
+			caw.LikeCount, caw.RecawCount, caw.RepliesCount, caw.Visibility, mentions, hashtags, caw.EditCount)
+		if err != nil {
+			return err
+		}
+		if caw.ParentID != "" {
+			_, err = tx.Exec("UPDATE caw SET replies_count = replies_count + 1 WHERE id = ?", caw.ParentID.Hex())
+			if err != nil {
+				return err
+			}
+		}
+		return storeTags(tx, caw)
+	})
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return &caw, nil
+}
+
+func marshalTags(mentions []models.Mention, hashtags []string) (string, string, error) {
+	jMentions, err := json.Marshal(mentions)
+	if err != nil {
+		return "", "", err
+	}
+	jHashtags, err := json.Marshal(hashtags)
+	return string(jMentions), string(jHashtags), err
+}
+
+// storeTags replaces rows of caw_mention and caw_hashtag of caw
+func storeTags(tx *sql.Tx, caw models.Caw) error {
+	if _, err := tx.Exec("DELETE FROM caw_mention WHERE caw_id = ?", caw.ID.Hex()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM caw_hashtag WHERE caw_id = ?", caw.ID.Hex()); err != nil {
+		return err
+	}
+	for _, mention := range caw.Mentions {
+		_, err := tx.Exec("INSERT OR IGNORE INTO caw_mention (caw_id, user_id) VALUES (?, ?)", caw.ID.Hex(), mention.UserID.Hex())
+		if err != nil {
+			return err
+		}
+	}
+	for _, hashtag := range caw.Hashtags {
+		_, err := tx.Exec("INSERT OR IGNORE INTO caw_hashtag (caw_id, hashtag) VALUES (?, ?)", caw.ID.Hex(), hashtag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByUserID returns page of caws created by user with userID and visible to viewer,
+// the oldest first unless newestFirst is set
+func (ds *sqliteCawDataStore) GetByUserID(userID string, viewerID string, page int, newestFirst bool) ([]models.Caw, error) {
+	order := "c.created_at, c.rowid"
+	if newestFirst {
+		order = "c.created_at DESC, c.rowid DESC"
+	}
+	return ds.visibleCaws("c.user_id = ?", userID, viewerID, order, page)
+}
+
+// GetByHashtag returns page of newest caws tagged with hashtag and visible to viewer
+func (ds *sqliteCawDataStore) GetByHashtag(hashtag string, viewerID string, page int) ([]models.Caw, error) {
+	return ds.visibleCaws("c.id IN (SELECT caw_id FROM caw_hashtag WHERE hashtag = ?)",
+		models.NormalizeHashtag(hashtag), viewerID, "c.created_at DESC, c.rowid DESC", page)
+}
+
+// GetByMention returns page of newest caws mentioning user with userID and visible to viewer
+func (ds *sqliteCawDataStore) GetByMention(userID string, viewerID string, page int) ([]models.Caw, error) {
+	return ds.visibleCaws("c.id IN (SELECT caw_id FROM caw_mention WHERE user_id = ?)",
+		userID, viewerID, "c.created_at DESC, c.rowid DESC", page)
+}
+
+// GetByID returns caw with cawID. ErrNotFound is returned also when caw is not visible to viewer
+func (ds *sqliteCawDataStore) GetByID(cawID string, viewerID string) (*models.Caw, error) {
+	caws, err := ds.visibleCaws("c.id = ?", cawID, viewerID, "c.id", 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(caws) == 0 {
+		return nil, infrastructure.ErrNotFound
+	}
+	return &caws[0], nil
+}
+
+// ForEachPublic calls fn for every public caw until fn returns error. Caws are read
+// in batches, so fn can use the database.
+func (ds *sqliteCawDataStore) ForEachPublic(fn func(caw models.Caw) error) error {
+	afterID := ""
+	for {
+		caws, err := ds.queryCaws("c.id > ? AND c.visibility NOT IN (?, ?)",
+			[]interface{}{afterID, models.VisibilityFollowers, models.VisibilityMentioned}, "c.id", batchSize, 0)
+		if err != nil {
+			return err
+		}
+		for _, caw := range caws {
+			if err := fn(caw); err != nil {
+				return err
+			}
+			afterID = caw.ID.Hex()
+		}
+		if len(caws) < batchSize {
+			return nil
+		}
+	}
+}
+
+// Edit replaces message of caw with cawID and keeps replaced content as revision in one transaction.
+// ErrEditLimit is returned when caw was already edited maxEdits times and
+// ErrEditConflict when caw was edited concurrently.
+func (ds *sqliteCawDataStore) Edit(cawID string, message string, mentions []models.Mention, maxEdits int) (*models.Caw, error) {
+	var caw *models.Caw
+	err := inTx(ds.db, func(tx *sql.Tx) error {
+		var err error
+		caw, err = scanCaw(tx.QueryRow("SELECT "+cawColumns+" FROM caw c WHERE c.id = ?", cawID))
+		if err == sql.ErrNoRows {
+			return infrastructure.ErrNotFound
+		} else if err != nil {
+			return err
+		}
+		if caw.EditCount >= maxEdits {
+			return infrastructure.ErrEditLimit
+		}
+
+		editedAt := time.Now()
+		revision := models.NewCawRevision(*caw, editedAt)
+		revisionMentions, revisionHashtags, err := marshalTags(revision.Mentions, revision.Hashtags)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO caw_revision (id, caw_id, revision, message, mentions, hashtags, created_at, replaced_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			bson.NewObjectId().Hex(), cawID, revision.Revision, revision.Message, revisionMentions, revisionHashtags,
+			toMillis(revision.CreatedAt), toMillis(revision.ReplacedAt))
+		if isConstraint(err, sqlite3.ErrConstraintUnique) {
+			return infrastructure.ErrEditConflict
+		} else if err != nil {
+			return err
+		}
+
+		caw.Message = message
+		caw.Mentions = mentions
+		caw.Hashtags = models.ExtractHashtags(message)
+		caw.EditedAt = &editedAt
+		caw.EditCount++
+		jMentions, jHashtags, err := marshalTags(caw.Mentions, caw.Hashtags)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`UPDATE caw SET message = ?, mentions = ?, hashtags = ?, edited_at = ?, edit_count = ?
+			WHERE id = ? AND edit_count = ?`,
+			caw.Message, jMentions, jHashtags, toMillis(editedAt), caw.EditCount, cawID, revision.Revision)
+		if err != nil {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil {
+			return err
+		} else if updated == 0 {
+			return infrastructure.ErrEditConflict
+		}
+		return storeTags(tx, *caw)
+	})
+	switch err {
+	case nil:
+		return caw, nil
+	case infrastructure.ErrNotFound, infrastructure.ErrEditLimit, infrastructure.ErrEditConflict:
+		return nil, err
+	}
+	ds.logger.Error(err)
+	return nil, err
+}
+
+// GetRevisions returns replaced revisions of caw with cawID, the oldest first
+func (ds *sqliteCawDataStore) GetRevisions(cawID string) ([]models.CawRevision, error) {
+	rows, err := ds.db.Query(`SELECT id, revision, message, mentions, hashtags, created_at, replaced_at
+		FROM caw_revision WHERE caw_id = ? ORDER BY revision`, cawID)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.CawRevision{}
+	for rows.Next() {
+		revision := models.CawRevision{CawID: objectID(cawID)}
+		var id, mentions, hashtags string
+		var createdAt, replacedAt int64
+		err := rows.Scan(&id, &revision.Revision, &revision.Message, &mentions, &hashtags, &createdAt, &replacedAt)
+		if err == nil {
+			err = json.Unmarshal([]byte(mentions), &revision.Mentions)
+		}
+		if err == nil {
+			err = json.Unmarshal([]byte(hashtags), &revision.Hashtags)
+		}
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		revision.ID = objectID(id)
+		revision.CreatedAt = fromMillis(createdAt)
+		revision.ReplacedAt = fromMillis(replacedAt)
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Delete deletes caw with cawID, its revisions, mentions and hashtags are deleted with it
+func (ds *sqliteCawDataStore) Delete(cawID string) error {
+	result, err := ds.db.Exec("DELETE FROM caw WHERE id = ?", cawID)
+	if err != nil {
+		ds.logger.Error(err)
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		ds.logger.Error(err)
+		return err
+	} else if deleted == 0 {
+		return infrastructure.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"testing"
+)
+
+func TestCawVisibility(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	factory := NewFactory(db, logger)
+	users := storeUsers(t, factory.CreateUserDataStore(), "author", "follower", "mentioned", "stranger")
+	author, follower, mentioned, stranger := users[0], users[1], users[2], users[3]
+	if err := factory.CreateUserDataStore().AddFollowingUser(follower.ID.Hex(), author.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := factory.CreateCawDataStore()
+	caws := map[string]*models.Caw{}
+	for _, visibility := range []string{models.VisibilityPublic, models.VisibilityFollowers, models.VisibilityMentioned} {
+		caw, err := ds.Store(models.Caw{
+			UserID:     author.ID,
+			UserName:   author.Name,
+			Message:    "#Caws for @mentioned",
+			Visibility: visibility,
+			Mentions:   []models.Mention{{UserID: mentioned.ID, Name: mentioned.Name}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		caws[visibility] = caw
+	}
+
+	var testCases = []struct {
+		Viewer   string
+		Expected []string
+	}{
+		{Viewer: author.ID.Hex(), Expected: []string{models.VisibilityPublic, models.VisibilityFollowers, models.VisibilityMentioned}},
+		{Viewer: follower.ID.Hex(), Expected: []string{models.VisibilityPublic, models.VisibilityFollowers}},
+		{Viewer: mentioned.ID.Hex(), Expected: []string{models.VisibilityPublic, models.VisibilityMentioned}},
+		{Viewer: stranger.ID.Hex(), Expected: []string{models.VisibilityPublic}},
+		{Viewer: "", Expected: []string{models.VisibilityPublic}},
+	}
+	for _, testCase := range testCases {
+		byUser, err := ds.GetByUserID(author.ID.Hex(), testCase.Viewer, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byHashtag, err := ds.GetByHashtag("#caws", testCase.Viewer, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byMention, err := ds.GetByMention(mentioned.ID.Hex(), testCase.Viewer, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, page := range [][]models.Caw{byUser, byHashtag, byMention} {
+			if len(page) != len(testCase.Expected) {
+				t.Fatalf("Viewer %q expected to see %v, given %d caws", testCase.Viewer, testCase.Expected, len(page))
+			}
+		}
+
+		for visibility, caw := range caws {
+			visible := false
+			for _, expected := range testCase.Expected {
+				visible = visible || expected == visibility
+			}
+			stored, err := ds.GetByID(caw.ID.Hex(), testCase.Viewer)
+			if visible && (err != nil || stored.Message != caw.Message || len(stored.Mentions) != 1) {
+				t.Fatalf("Viewer %q expected to get %s caw, given %v, err: %v", testCase.Viewer, visibility, stored, err)
+			}
+			if !visible && err != infrastructure.ErrNotFound {
+				t.Fatalf("Viewer %q expected not to get %s caw, err: %v", testCase.Viewer, visibility, err)
+			}
+		}
+	}
+
+	public := 0
+	err := ds.ForEachPublic(func(caw models.Caw) error {
+		public++
+		return nil
+	})
+	if err != nil || public != 1 {
+		t.Fatalf("Expected 1 public caw, given %d, err: %v", public, err)
+	}
+}
+
+func TestEditCaw(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateCawDataStore()
+	caw, err := ds.Store(models.Caw{UserID: "597bd1d34ac00c75e9280ae4", UserName: "author", Message: "first #one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := ds.Edit(caw.ID.Hex(), "second #two", nil, 1)
+	if err != nil || edited.EditCount != 1 || edited.EditedAt == nil {
+		t.Fatalf("Unexpected edited caw %v, err: %v", edited, err)
+	}
+	if _, err = ds.Edit(caw.ID.Hex(), "third", nil, 1); err != infrastructure.ErrEditLimit {
+		t.Fatalf("Expected ErrEditLimit, given %v", err)
+	}
+	if _, err = ds.Edit("597bd1d34ac00c75e9280ae5", "third", nil, 1); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, given %v", err)
+	}
+
+	revisions, err := ds.GetRevisions(caw.ID.Hex())
+	if err != nil || len(revisions) != 1 || revisions[0].Message != "first #one" || revisions[0].Revision != 0 {
+		t.Fatalf("Unexpected revisions %v, err: %v", revisions, err)
+	}
+	if tagged, err := ds.GetByHashtag("one", "", 0); err != nil || len(tagged) != 0 {
+		t.Fatalf("Replaced hashtag expected to be removed, given %v, err: %v", tagged, err)
+	}
+	if tagged, err := ds.GetByHashtag("two", "", 0); err != nil || len(tagged) != 1 {
+		t.Fatalf("New hashtag expected to be stored, given %v, err: %v", tagged, err)
+	}
+
+	if err = ds.Delete(caw.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, err = ds.GetRevisions(caw.ID.Hex()); err != nil || len(revisions) != 0 {
+		t.Fatalf("Revisions expected to be deleted with caw, given %v, err: %v", revisions, err)
+	}
+	if err = ds.Delete(caw.ID.Hex()); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, given %v", err)
+	}
+}
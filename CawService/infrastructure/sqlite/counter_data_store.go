@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"database/sql"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// sqliteCounterDataStore implements CounterDataStore on SQLite database. Follow counters
+// are updated in transactions with follow relations, so they drift only when the
+// database is changed directly.
+type sqliteCounterDataStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// Close does nothing, database is shared by all stores and closed by factory
+func (ds *sqliteCounterDataStore) Close() {
+}
+
+// counterDrift is a stored counter and its actual value recomputed from source table
+type counterDrift struct {
+	id             string
+	stored, actual []int
+}
+
+func drift(stored, actual int) int {
+	if stored > actual {
+		return stored - actual
+	}
+	return actual - stored
+}
+
+// ReconcileUsers recomputes follow counters of batch of users with ID greater than afterID
+func (ds *sqliteCounterDataStore) ReconcileUsers(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return ds.reconcile(afterID, batchSize,
+		`SELECT id, followers_count, following_count,
+			(SELECT COUNT(*) FROM follow WHERE following_id = users.id),
+			(SELECT COUNT(*) FROM follow WHERE follower_id = users.id)
+		FROM users WHERE id > ? ORDER BY id LIMIT ?`,
+		"UPDATE users SET followers_count = ?, following_count = ? WHERE id = ? AND followers_count = ? AND following_count = ?",
+		"Fixing drifted counters of user %s. followers_count, following_count: %v -> %v")
+}
+
+// ReconcileCaws recomputes replies counters of batch of caws with ID greater than afterID
+func (ds *sqliteCounterDataStore) ReconcileCaws(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return ds.reconcile(afterID, batchSize,
+		`SELECT id, replies_count, (SELECT COUNT(*) FROM caw reply WHERE reply.parent_id = caw.id)
+		FROM caw WHERE id > ? ORDER BY id LIMIT ?`,
+		"UPDATE caw SET replies_count = ? WHERE id = ? AND replies_count = ?",
+		"Fixing drifted counters of caw %s. replies_count: %v -> %v")
+}
+
+// reconcile reads batch of rows by query selecting ID, stored counters and actual counters
+// and stores actual counters of drifted rows by update taking actual counters, ID and stored
+// counters. Rows with counters changed since they were read are left to the next run.
+func (ds *sqliteCounterDataStore) reconcile(afterID string, batchSize int, query string, update string, warning string) (*infrastructure.ReconcileResult, error) {
+	rows, err := ds.db.Query(query, afterID, batchSize)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		ds.logger.Error(err)
+		return nil, err
+	}
+	counters := (len(columns) - 1) / 2
+
+	var batch []counterDrift
+	for rows.Next() {
+		row := counterDrift{stored: make([]int, counters), actual: make([]int, counters)}
+		dest := []interface{}{&row.id}
+		for i := range row.stored {
+			dest = append(dest, &row.stored[i])
+		}
+		for i := range row.actual {
+			dest = append(dest, &row.actual[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			ds.logger.Error(err)
+			return nil, err
+		}
+		batch = append(batch, row)
+	}
+	// rows hold the only connection, they have to be closed before drifted rows are updated
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	result := &infrastructure.ReconcileResult{LastID: afterID, Scanned: len(batch), Done: len(batch) < batchSize}
+	for _, row := range batch {
+		result.LastID = row.id
+		rowDrift := 0
+		args := []interface{}{}
+		for i := range row.stored {
+			rowDrift += drift(row.stored[i], row.actual[i])
+			args = append(args, row.actual[i])
+		}
+		if rowDrift == 0 {
+			continue
+		}
+		args = append(args, row.id)
+		for i := range row.stored {
+			args = append(args, row.stored[i])
+		}
+		ds.logger.Warnf(warning, row.id, row.stored, row.actual)
+		updated, err := ds.db.Exec(update, args...)
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		if affected, err := updated.RowsAffected(); err != nil || affected == 0 {
+			ds.logger.Warnf("Counters of %s changed during reconciliation", row.id)
+			continue
+		}
+		result.Fixed++
+		result.Drift += rowDrift
+	}
+	return result, nil
+}
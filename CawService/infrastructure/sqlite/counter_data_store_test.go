@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"Caw/UserService/models"
+	"testing"
+)
+
+func TestReconcileCounters(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	factory := NewFactory(db, logger)
+	users := storeUsers(t, factory.CreateUserDataStore(), "alice", "bob")
+	if err := factory.CreateUserDataStore().AddFollowingUser(users[0].ID.Hex(), users[1].ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE users SET followers_count = 5"); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := factory.CreateCawDataStore().Store(models.Caw{UserID: users[0].ID, Message: "parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = factory.CreateCawDataStore().Store(models.Caw{UserID: users[1].ID, ParentID: parent.ID, Message: "reply"}); err != nil {
+		t.Fatal(err)
+	}
+	caw, err := factory.CreateCawDataStore().GetByID(parent.ID.Hex(), "")
+	if err != nil || caw.RepliesCount != 1 {
+		t.Fatalf("Expected replies count incremented by reply, given %v, err: %v", caw, err)
+	}
+	if _, err = db.Exec("UPDATE caw SET replies_count = 3 WHERE id = ?", parent.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := factory.CreateCounterDataStore()
+	result, err := ds.ReconcileUsers("", 10)
+	if err != nil || !result.Done || result.Scanned != 2 || result.Fixed != 2 || result.Drift != 9 {
+		t.Fatalf("Unexpected users result %+v, err: %v", result, err)
+	}
+	assertFollowCounts(t, factory.CreateUserDataStore(), users[1].ID.Hex(), 1, 0)
+
+	result, err = ds.ReconcileCaws("", 1)
+	if err != nil || result.Done || result.Scanned != 1 {
+		t.Fatalf("Unexpected first caws batch %+v, err: %v", result, err)
+	}
+	fixed := result.Fixed
+	result, err = ds.ReconcileCaws(result.LastID, 1)
+	if err != nil || result.Scanned != 1 || fixed+result.Fixed != 1 {
+		t.Fatalf("Unexpected second caws batch %+v, err: %v", result, err)
+	}
+	caw, err = factory.CreateCawDataStore().GetByID(parent.ID.Hex(), "")
+	if err != nil || caw.RepliesCount != 1 {
+		t.Fatalf("Expected 1 reply, given %v, err: %v", caw, err)
+	}
+}
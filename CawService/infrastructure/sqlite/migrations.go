@@ -0,0 +1,175 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// migration is a versioned change of schema. Applied migrations are recorded in
+// schema_migration table, so every migration runs once per database file.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations are applied in order of versions, new migrations are appended with the next version
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create users with unique names and emails",
+		statements: []string{
+			`CREATE TABLE users (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				email TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				followers_count INTEGER NOT NULL DEFAULT 0,
+				following_count INTEGER NOT NULL DEFAULT 0,
+				created_at INTEGER NOT NULL,
+				suspended INTEGER NOT NULL DEFAULT 0
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "create follow relations",
+		statements: []string{
+			`CREATE TABLE follow (
+				follower_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				following_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				PRIMARY KEY (follower_id, following_id),
+				CHECK (follower_id <> following_id)
+			)`,
+			`CREATE INDEX follow_following_id ON follow(following_id)`,
+		},
+	},
+	{
+		version:     3,
+		description: "create caws, caw mentions, hashtags and revisions",
+		statements: []string{
+			`CREATE TABLE caw (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				user_name TEXT NOT NULL,
+				parent_id TEXT,
+				message TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				like_count INTEGER NOT NULL DEFAULT 0,
+				recaw_count INTEGER NOT NULL DEFAULT 0,
+				replies_count INTEGER NOT NULL DEFAULT 0,
+				visibility TEXT NOT NULL DEFAULT 'public',
+				mentions TEXT NOT NULL DEFAULT '[]',
+				hashtags TEXT NOT NULL DEFAULT '[]',
+				edited_at INTEGER,
+				edit_count INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX caw_user_id_created_at ON caw(user_id, created_at)`,
+			`CREATE INDEX caw_parent_id ON caw(parent_id) WHERE parent_id IS NOT NULL`,
+			`CREATE TABLE caw_mention (
+				caw_id TEXT NOT NULL REFERENCES caw(id) ON DELETE CASCADE,
+				user_id TEXT NOT NULL,
+				PRIMARY KEY (caw_id, user_id)
+			)`,
+			`CREATE INDEX caw_mention_user_id ON caw_mention(user_id)`,
+			`CREATE TABLE caw_hashtag (
+				caw_id TEXT NOT NULL REFERENCES caw(id) ON DELETE CASCADE,
+				hashtag TEXT NOT NULL,
+				PRIMARY KEY (caw_id, hashtag)
+			)`,
+			`CREATE INDEX caw_hashtag_hashtag ON caw_hashtag(hashtag)`,
+			`CREATE TABLE caw_revision (
+				id TEXT PRIMARY KEY,
+				caw_id TEXT NOT NULL REFERENCES caw(id) ON DELETE CASCADE,
+				revision INTEGER NOT NULL,
+				message TEXT NOT NULL,
+				mentions TEXT NOT NULL DEFAULT '[]',
+				hashtags TEXT NOT NULL DEFAULT '[]',
+				created_at INTEGER NOT NULL,
+				replaced_at INTEGER NOT NULL,
+				UNIQUE (caw_id, revision)
+			)`,
+		},
+	},
+	{
+		version:     4,
+		description: "create notifications",
+		statements: []string{
+			`CREATE TABLE notification (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				type TEXT NOT NULL,
+				actor_id TEXT NOT NULL,
+				actor_name TEXT NOT NULL,
+				caw_id TEXT,
+				read INTEGER NOT NULL DEFAULT 0,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX notification_user_id_read_created_at ON notification(user_id, read, created_at)`,
+		},
+	},
+}
+
+// Migrate applies migrations not yet recorded in the database, each in its own transaction.
+// In dry run nothing is changed and returned results describe pending migrations.
+func Migrate(db *sql.DB, logger *logrus.Logger, dryRun bool) ([]infrastructure.MigrationResult, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migration (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	results := []infrastructure.MigrationResult{}
+	for _, m := range migrations {
+		pending := false
+		err := inTx(db, func(tx *sql.Tx) error {
+			// checked in transaction, migration may be applied concurrently by another instance
+			var count int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM schema_migration WHERE version = ?", m.version).Scan(&count); err != nil {
+				return err
+			}
+			pending = count == 0
+			if !pending || dryRun {
+				return nil
+			}
+			for _, statement := range m.statements {
+				if _, err := tx.Exec(statement); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec("INSERT INTO schema_migration (version, description, applied_at) VALUES (?, ?, ?)",
+				m.version, m.description, toMillis(time.Now()))
+			return err
+		})
+		if err != nil {
+			logger.Errorf("Cannot apply migration %d. err: %s", m.version, err)
+			return results, err
+		}
+		if !pending {
+			continue
+		}
+		if !dryRun {
+			logger.Infof("Applied migration %d: %s", m.version, m.description)
+		}
+
+		result := infrastructure.MigrationResult{Version: m.version, Description: m.description}
+		for _, statement := range m.statements {
+			result.Changes = append(result.Changes, summary(statement))
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// summary returns the first line of statement, e.g. "CREATE TABLE users"
+func summary(statement string) string {
+	return strings.TrimSuffix(strings.TrimSpace(strings.SplitN(statement, "\n", 2)[0]), " (")
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+var logger = logrus.New()
+
+// InitializeDataBase opens empty in-memory database with migrated schema
+func InitializeDataBase(t *testing.T) *sql.DB {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Migrate(db, logger, false); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	pending, err := Migrate(db, logger, true)
+	if err != nil || len(pending) != len(migrations) {
+		t.Fatalf("Dry run expected %d pending migrations, given %d, err: %v", len(migrations), len(pending), err)
+	}
+	var tables int
+	if err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'").Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("Dry run must not create tables, given %d, err: %v", tables, err)
+	}
+
+	applied, err := Migrate(db, logger, false)
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Expected %d applied migrations, given %d, err: %v", len(migrations), len(applied), err)
+	}
+	if applied[0].Changes[0] != "CREATE TABLE users" {
+		t.Fatalf("Unexpected change %q", applied[0].Changes[0])
+	}
+
+	pending, err = Migrate(db, logger, true)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Expected no pending migrations, given %d, err: %v", len(pending), err)
+	}
+	applied, err = Migrate(db, logger, false)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("Migrations must be applied once, given %d, err: %v", len(applied), err)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"Caw/UserService/models"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// sqliteNotificationDataStore implements NotificationDataStore on SQLite database
+type sqliteNotificationDataStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// Close does nothing, database is shared by all stores and closed by factory
+func (ds *sqliteNotificationDataStore) Close() {
+}
+
+// Store persists provided notification as unread
+func (ds *sqliteNotificationDataStore) Store(notification models.Notification) (*models.Notification, error) {
+	notification.ID = bson.NewObjectId()
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+	_, err := ds.db.Exec(`INSERT INTO notification (id, user_id, type, actor_id, actor_name, caw_id, read, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		notification.ID.Hex(), notification.UserID.Hex(), notification.Type, notification.Actor.UserID.Hex(),
+		notification.Actor.Name, nullID(notification.CawID), notification.Read, toMillis(notification.CreatedAt))
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// GetByUserID returns page of newest notifications of user with provided ID
+func (ds *sqliteNotificationDataStore) GetByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	return ds.queryNotifications(userID,
+		notificationCondition(unreadOnly)+" ORDER BY created_at DESC, rowid DESC LIMIT ? OFFSET ?",
+		userID, pageSize, page*pageSize)
+}
+
+// GetGroupedByUserID returns all notifications of page of notification groups of user with provided ID,
+// so groups built by models.GroupNotifications are not split across pages. Groups are notifications
+// with the same type and caw, the group with the newest notification is the first.
+func (ds *sqliteNotificationDataStore) GetGroupedByUserID(userID string, unreadOnly bool, page int) ([]models.Notification, error) {
+	condition := notificationCondition(unreadOnly)
+	rows, err := ds.db.Query(`SELECT type, caw_id FROM notification WHERE `+condition+`
+		GROUP BY type, caw_id ORDER BY MAX(created_at) DESC, MAX(rowid) DESC LIMIT ? OFFSET ?`,
+		userID, pageSize, page*pageSize)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	args := []interface{}{userID}
+	for rows.Next() {
+		var notificationType string
+		var cawID sql.NullString
+		if err := rows.Scan(&notificationType, &cawID); err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		keys = append(keys, "(type = ? AND caw_id IS ?)")
+		args = append(args, notificationType, cawID)
+	}
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return []models.Notification{}, nil
+	}
+
+	return ds.queryNotifications(userID,
+		condition+" AND ("+strings.Join(keys, " OR ")+") ORDER BY created_at DESC, rowid DESC", args...)
+}
+
+// notificationCondition returns condition selecting notifications of user, optionally only unread ones
+func notificationCondition(unreadOnly bool) string {
+	if unreadOnly {
+		return "user_id = ? AND read = 0"
+	}
+	return "user_id = ?"
+}
+
+// queryNotifications returns notifications of user with provided ID selected by condition
+func (ds *sqliteNotificationDataStore) queryNotifications(userID string, condition string, args ...interface{}) ([]models.Notification, error) {
+	rows, err := ds.db.Query("SELECT id, type, actor_id, actor_name, caw_id, read, created_at FROM notification WHERE "+condition, args...)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notification := models.Notification{UserID: objectID(userID)}
+		var id, actorID string
+		var cawID sql.NullString
+		var createdAt int64
+		err := rows.Scan(&id, &notification.Type, &actorID, &notification.Actor.Name, &cawID, &notification.Read, &createdAt)
+		if err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		notification.ID = objectID(id)
+		notification.Actor.UserID = objectID(actorID)
+		notification.CawID = objectID(cawID.String)
+		notification.CreatedAt = fromMillis(createdAt)
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// CountUnread returns number of unread notifications of user with provided ID
+func (ds *sqliteNotificationDataStore) CountUnread(userID string) (int, error) {
+	var count int
+	err := ds.db.QueryRow("SELECT COUNT(*) FROM notification WHERE user_id = ? AND read = 0", userID).Scan(&count)
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkAsRead marks provided notifications of user as read and returns number of found notifications,
+// including notifications which were already read, so marking is idempotent
+func (ds *sqliteNotificationDataStore) MarkAsRead(userID string, notificationIDs []string) (int, error) {
+	if len(notificationIDs) == 0 {
+		return 0, nil
+	}
+	args := []interface{}{userID}
+	for _, notificationID := range notificationIDs {
+		args = append(args, notificationID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(notificationIDs)), ", ")
+	return ds.markAsRead("user_id = ? AND id IN ("+placeholders+")", args...)
+}
+
+// MarkAllAsRead marks all notifications of user as read and returns number of updated notifications
+func (ds *sqliteNotificationDataStore) MarkAllAsRead(userID string) (int, error) {
+	return ds.markAsRead("user_id = ? AND read = 0", userID)
+}
+
+// markAsRead marks notifications selected by condition as read and returns number of selected notifications
+func (ds *sqliteNotificationDataStore) markAsRead(condition string, args ...interface{}) (int, error) {
+	result, err := ds.db.Exec("UPDATE notification SET read = 1 WHERE "+condition, args...)
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return int(updated), nil
+}
+
+// DeleteByUserID removes notifications sent to user with provided ID and notifications
+// about actions of the user and returns number of removed notifications
+func (ds *sqliteNotificationDataStore) DeleteByUserID(userID string) (int, error) {
+	result, err := ds.db.Exec("DELETE FROM notification WHERE user_id = ? OR actor_id = ?", userID, userID)
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		ds.logger.Error(err)
+		return 0, err
+	}
+	return int(removed), nil
+}
@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"Caw/UserService/models"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestNotificationReadState(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateNotificationDataStore()
+	userID := bson.NewObjectId()
+
+	var stored []*models.Notification
+	for _, notificationType := range []string{models.NotificationFollow, models.NotificationLike, models.NotificationReply} {
+		notification, err := ds.Store(models.Notification{
+			UserID: userID,
+			Type:   notificationType,
+			Actor:  models.Follow{UserID: bson.NewObjectId(), Name: "actor"},
+			CawID:  bson.NewObjectId(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, notification)
+	}
+
+	updated, err := ds.MarkAsRead(userID.Hex(), []string{stored[0].ID.Hex(), bson.NewObjectId().Hex()})
+	if err != nil || updated != 1 {
+		t.Fatalf("Expected 1 updated notification, given %d, err: %v", updated, err)
+	}
+	unread, err := ds.CountUnread(userID.Hex())
+	if err != nil || unread != 2 {
+		t.Fatalf("Expected 2 unread notifications, given %d, err: %v", unread, err)
+	}
+	notifications, err := ds.GetByUserID(userID.Hex(), true, 0)
+	if err != nil || len(notifications) != 2 || notifications[0].Type != models.NotificationReply {
+		t.Fatalf("Expected newest unread notifications first, given %v, err: %v", notifications, err)
+	}
+	if notifications[0].Actor.Name != "actor" || notifications[0].CawID != stored[2].CawID {
+		t.Fatalf("Unexpected notification %+v", notifications[0])
+	}
+
+	if found, err := ds.MarkAsRead(userID.Hex(), []string{stored[0].ID.Hex()}); err != nil || found != 1 {
+		t.Fatalf("Expected already read notification to be found, given %d, err: %v", found, err)
+	}
+	if updated, err = ds.MarkAllAsRead(userID.Hex()); err != nil || updated != 2 {
+		t.Fatalf("Expected 2 updated notifications, given %d, err: %v", updated, err)
+	}
+	if notifications, err = ds.GetByUserID(userID.Hex(), false, 0); err != nil || len(notifications) != 3 || !notifications[0].Read {
+		t.Fatalf("Expected 3 read notifications, given %v, err: %v", notifications, err)
+	}
+}
+
+func TestDeleteNotificationsByUserID(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateNotificationDataStore()
+	userID := bson.NewObjectId()
+	otherID := bson.NewObjectId()
+
+	notifications := []models.Notification{
+		{UserID: userID, Type: models.NotificationFollow, Actor: models.Follow{UserID: otherID, Name: "other"}},
+		{UserID: otherID, Type: models.NotificationFollow, Actor: models.Follow{UserID: userID, Name: "user"}},
+		{UserID: otherID, Type: models.NotificationLike, Actor: models.Follow{UserID: bson.NewObjectId(), Name: "third"}},
+	}
+	for _, notification := range notifications {
+		if _, err := ds.Store(notification); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ds.DeleteByUserID(userID.Hex())
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 removed notifications, given %d, err: %v", removed, err)
+	}
+	remaining, err := ds.GetByUserID(otherID.Hex(), false, 0)
+	if err != nil || len(remaining) != 1 || remaining[0].Type != models.NotificationLike {
+		t.Fatalf("Expected only notification not related to user, given %v, err: %v", remaining, err)
+	}
+}
+
+func TestGetGroupedNotificationsKeepsGroupsWhole(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateNotificationDataStore()
+	userID := bson.NewObjectId()
+
+	// one follow group larger than page and a like group per caw stored after it
+	for i := 0; i < pageSize+2; i++ {
+		if _, err := ds.Store(models.Notification{UserID: userID, Type: models.NotificationFollow,
+			Actor: models.Follow{UserID: bson.NewObjectId(), Name: "follower"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cawIDs := []bson.ObjectId{}
+	for i := 0; i < pageSize; i++ {
+		cawIDs = append(cawIDs, bson.NewObjectId())
+		if _, err := ds.Store(models.Notification{UserID: userID, Type: models.NotificationLike,
+			Actor: models.Follow{UserID: bson.NewObjectId(), Name: "liker"}, CawID: cawIDs[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications, err := ds.GetGroupedByUserID(userID.Hex(), false, 0)
+	if err != nil || len(notifications) != pageSize {
+		t.Fatalf("Expected %d like notifications on first page, given %d, err: %v", pageSize, len(notifications), err)
+	}
+	if groups := models.GroupNotifications(notifications); len(groups) != pageSize || groups[0].CawID != cawIDs[pageSize-1] {
+		t.Fatalf("Expected %d groups with the newest first, given %+v", pageSize, groups)
+	}
+
+	notifications, err = ds.GetGroupedByUserID(userID.Hex(), false, 1)
+	if err != nil || len(notifications) != pageSize+2 {
+		t.Fatalf("Expected whole follow group on second page, given %d, err: %v", len(notifications), err)
+	}
+	if groups := models.GroupNotifications(notifications); len(groups) != 1 || groups[0].Count != pageSize+2 {
+		t.Fatalf("Expected single follow group, given %+v", groups)
+	}
+}
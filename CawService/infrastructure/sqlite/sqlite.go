@@ -0,0 +1,123 @@
+// Package sqlite implements infrastructure data stores on an embedded SQLite
+// database file, so small installations can run without MongoDB.
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"database/sql"
+	"path/filepath"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	pageSize = 10
+	// batchSize limits number of rows read at once by ForEach methods
+	batchSize = 100
+)
+
+// Path returns path of database file with provided name in dir
+func Path(dir string, database string) string {
+	return filepath.Join(dir, database+".db")
+}
+
+// Open opens database file at path, it is created if it does not exist.
+// SQLite allows one writer at a time, so all queries share one connection
+// and long reads are done in batches.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=1&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err = db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type sqliteDataStoreFactory struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// NewFactory creates DataStoreFactory of database opened by Open. Schema is created by Migrate.
+func NewFactory(db *sql.DB, logger *logrus.Logger) infrastructure.DataStoreFactory {
+	return &sqliteDataStoreFactory{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (f sqliteDataStoreFactory) CreateUserDataStore() infrastructure.UserDataStore {
+	return &sqliteUserDataStore{db: f.db, logger: f.logger}
+}
+
+func (f sqliteDataStoreFactory) CreateCawDataStore() infrastructure.CawDataStore {
+	return &sqliteCawDataStore{db: f.db, logger: f.logger}
+}
+
+func (f sqliteDataStoreFactory) CreateNotificationDataStore() infrastructure.NotificationDataStore {
+	return &sqliteNotificationDataStore{db: f.db, logger: f.logger}
+}
+
+func (f sqliteDataStoreFactory) CreateCounterDataStore() infrastructure.CounterDataStore {
+	return &sqliteCounterDataStore{db: f.db, logger: f.logger}
+}
+
+func (f sqliteDataStoreFactory) CreateAdminDataStore() infrastructure.AdminDataStore {
+	return &sqliteAdminDataStore{db: f.db, logger: f.logger}
+}
+
+func (f sqliteDataStoreFactory) Close() {
+	f.db.Close()
+}
+
+// isConstraint returns true if err is violation of constraint with provided extended code,
+// e.g. sqlite3.ErrConstraintUnique
+func isConstraint(err error, code sqlite3.ErrNoExtended) bool {
+	sqliteErr, ok := err.(sqlite3.Error)
+	return ok && sqliteErr.ExtendedCode == code
+}
+
+// inTx runs fn in transaction which is committed if fn succeeds and rolled back otherwise
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Times are stored as Unix milliseconds, so they sort correctly and have the same precision as in MongoDB
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// objectID converts stored hex ID to bson.ObjectId used by models, empty ID stays empty
+func objectID(id string) bson.ObjectId {
+	if !bson.IsObjectIdHex(id) {
+		return ""
+	}
+	return bson.ObjectIdHex(id)
+}
+
+// nullID stores empty ID as NULL
+func nullID(id bson.ObjectId) sql.NullString {
+	if id == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.Hex(), Valid: true}
+}
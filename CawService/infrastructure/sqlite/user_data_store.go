@@ -0,0 +1,347 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"database/sql"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	suggestionReasonSize = 3
+	userColumns          = "id, name, email, password, followers_count, following_count, created_at, suspended"
+)
+
+// sqliteUserDataStore implements UserDataStore on SQLite database
+type sqliteUserDataStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// Close does nothing, database is shared by all stores and closed by factory
+func (ds *sqliteUserDataStore) Close() {
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	var id string
+	var createdAt int64
+	err := row.Scan(&id, &user.Name, &user.Email, &user.Password,
+		&user.FollowersCount, &user.FollowingCount, &createdAt, &user.Suspended)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = objectID(id)
+	user.CreatedAt = fromMillis(createdAt)
+	return &user, nil
+}
+
+func (ds *sqliteUserDataStore) getUser(where string, arg string) (*models.User, error) {
+	user, err := scanUser(ds.db.QueryRow("SELECT "+userColumns+" FROM users WHERE "+where, arg))
+	if err == sql.ErrNoRows {
+		ds.logger.Error(err)
+		return nil, infrastructure.ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser gets and returns User with requested ID
+func (ds *sqliteUserDataStore) GetUser(userID string) (*models.User, error) {
+	return ds.getUser("id = ?", userID)
+}
+
+// IsSuspended returns true if account of user with provided ID is suspended.
+// ErrNotFound is returned if user does not exist.
+func (ds *sqliteUserDataStore) IsSuspended(userID string) (bool, error) {
+	var suspended bool
+	err := ds.db.QueryRow("SELECT suspended FROM users WHERE id = ?", userID).Scan(&suspended)
+	if err == sql.ErrNoRows {
+		return false, infrastructure.ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return false, err
+	}
+	return suspended, nil
+}
+
+// GetUserByName gets and returns User with requested name
+func (ds *sqliteUserDataStore) GetUserByName(userName string) (*models.User, error) {
+	return ds.getUser("name = ?", userName)
+}
+
+// StoreUser persist provided User. ErrUserExists is returned if name or email is taken.
+func (ds *sqliteUserDataStore) StoreUser(user models.User) (*models.User, error) {
+	user.ID = bson.NewObjectId()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	_, err := ds.db.Exec("INSERT INTO users ("+userColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		user.ID.Hex(), user.Name, user.Email, user.Password,
+		user.FollowersCount, user.FollowingCount, toMillis(user.CreatedAt), user.Suspended)
+	if err != nil {
+		if isConstraint(err, sqlite3.ErrConstraintUnique) {
+			return nil, infrastructure.ErrUserExists
+		}
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ForEachUser calls fn for every user until fn returns error. Users are read in batches,
+// so fn can use the database.
+func (ds *sqliteUserDataStore) ForEachUser(fn func(user models.User) error) error {
+	afterID := ""
+	for {
+		users, err := ds.userBatch(afterID)
+		if err != nil {
+			ds.logger.Error(err)
+			return err
+		}
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+			afterID = user.ID.Hex()
+		}
+		if len(users) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (ds *sqliteUserDataStore) userBatch(afterID string) ([]models.User, error) {
+	rows, err := ds.db.Query("SELECT "+userColumns+" FROM users WHERE id > ? ORDER BY id LIMIT ?", afterID, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// DeleteUser deletes user with provider ID with its follow relations and
+// decrements follow counters of followed users and followers
+func (ds *sqliteUserDataStore) DeleteUser(userID string) error {
+	err := inTx(ds.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE users SET followers_count = followers_count - 1
+			WHERE id IN (SELECT following_id FROM follow WHERE follower_id = ?)`, userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE users SET following_count = following_count - 1
+			WHERE id IN (SELECT follower_id FROM follow WHERE following_id = ?)`, userID)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+		if err != nil {
+			return err
+		}
+		if deleted, err := result.RowsAffected(); err != nil {
+			return err
+		} else if deleted == 0 {
+			return infrastructure.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil && err != infrastructure.ErrNotFound {
+		ds.logger.Error(err)
+	}
+	return err
+}
+
+// queryFollows returns user IDs and names selected by query
+func (ds *sqliteUserDataStore) queryFollows(query string, args ...interface{}) ([]models.Follow, error) {
+	rows, err := ds.db.Query(query, args...)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []models.Follow{}
+	for rows.Next() {
+		var id string
+		var follow models.Follow
+		if err := rows.Scan(&id, &follow.Name); err != nil {
+			ds.logger.Error(err)
+			return nil, err
+		}
+		follow.UserID = objectID(id)
+		follows = append(follows, follow)
+	}
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return follows, nil
+}
+
+// GetUserFollowers gets end returns followers of user with provided ID
+func (ds *sqliteUserDataStore) GetUserFollowers(userID string) ([]models.Follow, error) {
+	return ds.queryFollows(`SELECT u.id, u.name FROM follow f JOIN users u ON u.id = f.follower_id
+		WHERE f.following_id = ? ORDER BY f.rowid`, userID)
+}
+
+// GetUserFollowing gets and returns users followed by user with provided ID
+func (ds *sqliteUserDataStore) GetUserFollowing(userID string) ([]models.Follow, error) {
+	return ds.queryFollows(`SELECT u.id, u.name FROM follow f JOIN users u ON u.id = f.following_id
+		WHERE f.follower_id = ? ORDER BY f.rowid`, userID)
+}
+
+// AddFollowingUser creates relationships between follower and following user and increments
+// their follow counters in one transaction. ErrFollowExists is returned if follower already
+// follows following user and ErrNotFound if any of users does not exist.
+func (ds *sqliteUserDataStore) AddFollowingUser(followerID string, followingID string) error {
+	if followerID == followingID {
+		return infrastructure.ErrSelfFollow
+	}
+
+	err := inTx(ds.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO follow (follower_id, following_id) VALUES (?, ?)", followerID, followingID)
+		if err != nil {
+			return err
+		}
+		return updateFollowCounters(tx, followerID, followingID, 1)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case isConstraint(err, sqlite3.ErrConstraintPrimaryKey):
+		return infrastructure.ErrFollowExists
+	case isConstraint(err, sqlite3.ErrConstraintForeignKey):
+		return infrastructure.ErrNotFound
+	}
+	ds.logger.Error(err)
+	return err
+}
+
+// UnfollowUser removes relationship between follower and following user and decrements
+// their follow counters in one transaction. ErrNotFound is returned if relation does not exist.
+func (ds *sqliteUserDataStore) UnfollowUser(followerID, followingID string) error {
+	err := inTx(ds.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM follow WHERE follower_id = ? AND following_id = ?", followerID, followingID)
+		if err != nil {
+			return err
+		}
+		removed, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return infrastructure.ErrNotFound
+		}
+		return updateFollowCounters(tx, followerID, followingID, -1)
+	})
+	if err != nil && err != infrastructure.ErrNotFound {
+		ds.logger.Error(err)
+	}
+	return err
+}
+
+func updateFollowCounters(tx *sql.Tx, followerID, followingID string, delta int) error {
+	_, err := tx.Exec("UPDATE users SET followers_count = followers_count + ? WHERE id = ?", delta, followingID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE users SET following_count = following_count + ? WHERE id = ?", delta, followerID)
+	return err
+}
+
+// GetFollowRelation returns relation of users if user with followerID follows user with followingID
+func (ds *sqliteUserDataStore) GetFollowRelation(followerID string, followingID string) (*models.FollowRelation, error) {
+	var relation models.FollowRelation
+	var follower, following string
+	err := ds.db.QueryRow(`SELECT fr.id, fr.name, fg.id, fg.name FROM follow f
+		JOIN users fr ON fr.id = f.follower_id
+		JOIN users fg ON fg.id = f.following_id
+		WHERE f.follower_id = ? AND f.following_id = ?`, followerID, followingID).
+		Scan(&follower, &relation.Follower.Name, &following, &relation.Following.Name)
+	if err == sql.ErrNoRows {
+		return nil, infrastructure.ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	relation.Follower.UserID = objectID(follower)
+	relation.Following.UserID = objectID(following)
+	return &relation, nil
+}
+
+// GetMutuals returns page of users which user with provided ID follows and which follow him back
+func (ds *sqliteUserDataStore) GetMutuals(userID string, page int) ([]models.Follow, error) {
+	return ds.queryFollows(`SELECT u.id, u.name FROM follow f
+		JOIN follow back ON back.follower_id = f.following_id AND back.following_id = f.follower_id
+		JOIN users u ON u.id = f.following_id
+		WHERE f.follower_id = ?
+		ORDER BY u.name LIMIT ? OFFSET ?`, userID, pageSize, page*pageSize)
+}
+
+// GetFollowSuggestions returns users followed by users which user with userID follows,
+// ordered by number of such users. Already followed users and user itself are excluded.
+func (ds *sqliteUserDataStore) GetFollowSuggestions(userID string, limit int) ([]models.Suggestion, error) {
+	rows, err := ds.db.Query(`SELECT u.id, u.name, COUNT(*) AS score FROM follow f
+		JOIN follow fof ON fof.follower_id = f.following_id
+		JOIN users u ON u.id = fof.following_id
+		WHERE f.follower_id = ? AND fof.following_id <> ?
+			AND fof.following_id NOT IN (SELECT following_id FROM follow WHERE follower_id = ?)
+		GROUP BY u.id
+		ORDER BY score DESC, u.id
+		LIMIT ?`, userID, userID, userID, limit)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	suggestions := []models.Suggestion{}
+	for rows.Next() {
+		var id string
+		var suggestion models.Suggestion
+		if err := rows.Scan(&id, &suggestion.User.Name, &suggestion.Score); err != nil {
+			rows.Close()
+			ds.logger.Error(err)
+			return nil, err
+		}
+		suggestion.User.UserID = objectID(id)
+		suggestions = append(suggestions, suggestion)
+	}
+	// rows hold the only connection, they have to be closed before followers are queried
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	for i := range suggestions {
+		suggestions[i].FollowedBy, err = ds.queryFollows(`SELECT u.id, u.name FROM follow f
+			JOIN follow fof ON fof.follower_id = f.following_id
+			JOIN users u ON u.id = fof.follower_id
+			WHERE f.follower_id = ? AND fof.following_id = ?
+			ORDER BY fof.rowid LIMIT ?`, userID, suggestions[i].User.UserID.Hex(), suggestionReasonSize)
+		if err != nil {
+			return nil, err
+		}
+		suggestions[i].Reason = suggestions[i].Summary()
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,238 @@
+package sqlite
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/models"
+	"fmt"
+	"testing"
+)
+
+func storeUsers(t *testing.T, ds infrastructure.UserDataStore, names ...string) []*models.User {
+	var users []*models.User
+	for _, name := range names {
+		user, err := ds.StoreUser(models.User{Name: name, Email: name + "@email.com", Password: "password1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+func TestStoreUser(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateUserDataStore()
+
+	user := storeUsers(t, ds, "StoreUser")[0]
+	if user.ID.Hex() == "" {
+		t.Fatal("StoreUser does not fill User ID field")
+	}
+
+	stored, err := ds.GetUser(user.ID.Hex())
+	if err != nil || !stored.Equal(*user) || stored.Password != "password1" {
+		t.Fatalf("Expected stored user %v, given %v, err: %v", user, stored, err)
+	}
+	if stored, err = ds.GetUserByName("StoreUser"); err != nil || !stored.Equal(*user) {
+		t.Fatalf("Expected user by name %v, given %v, err: %v", user, stored, err)
+	}
+	if _, err = ds.GetUser("597bd1d34ac00c75e9280ae4"); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, given %v", err)
+	}
+
+	duplicates := []models.User{
+		{Name: "StoreUser", Email: "other@email.com", Password: "password1"},
+		{Name: "OtherUser", Email: "StoreUser@email.com", Password: "password1"},
+	}
+	for _, duplicate := range duplicates {
+		if _, err = ds.StoreUser(duplicate); err != infrastructure.ErrUserExists {
+			t.Fatalf("Expected ErrUserExists for %v, given %v", duplicate, err)
+		}
+	}
+}
+
+func TestFollowUser(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateUserDataStore()
+	users := storeUsers(t, ds, "alice", "bob")
+	alice, bob := users[0].ID.Hex(), users[1].ID.Hex()
+
+	if err := ds.AddFollowingUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	given := map[string]error{
+		"duplicate":        ds.AddFollowingUser(alice, bob),
+		"self":             ds.AddFollowingUser(alice, alice),
+		"missing":          ds.AddFollowingUser(alice, "597bd1d34ac00c75e9280ae4"),
+		"missing unfollow": ds.UnfollowUser(bob, alice),
+	}
+	expected := map[string]error{
+		"duplicate":        infrastructure.ErrFollowExists,
+		"self":             infrastructure.ErrSelfFollow,
+		"missing":          infrastructure.ErrNotFound,
+		"missing unfollow": infrastructure.ErrNotFound,
+	}
+	for name, err := range given {
+		if err != expected[name] {
+			t.Fatalf("Follow %s expected %v, given %v", name, expected[name], err)
+		}
+	}
+
+	relation, err := ds.GetFollowRelation(alice, bob)
+	if err != nil || relation.Follower.Name != "alice" || relation.Following.Name != "bob" {
+		t.Fatalf("Unexpected follow relation %v, err: %v", relation, err)
+	}
+	followers, err := ds.GetUserFollowers(bob)
+	if err != nil || len(followers) != 1 || followers[0].Name != "alice" {
+		t.Fatalf("Unexpected followers %v, err: %v", followers, err)
+	}
+	assertFollowCounts(t, ds, alice, 0, 1)
+	assertFollowCounts(t, ds, bob, 1, 0)
+
+	if err = ds.UnfollowUser(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ds.GetFollowRelation(alice, bob); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, given %v", err)
+	}
+	assertFollowCounts(t, ds, alice, 0, 0)
+	assertFollowCounts(t, ds, bob, 0, 0)
+}
+
+func assertFollowCounts(t *testing.T, ds infrastructure.UserDataStore, userID string, followers uint64, following uint64) {
+	user, err := ds.GetUser(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FollowersCount != followers || user.FollowingCount != following {
+		t.Fatalf("User %s expected to have %d followers and %d following, given %d and %d",
+			user.Name, followers, following, user.FollowersCount, user.FollowingCount)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateUserDataStore()
+	users := storeUsers(t, ds, "alice", "bob", "carol")
+	alice, bob, carol := users[0].ID.Hex(), users[1].ID.Hex(), users[2].ID.Hex()
+	for _, follow := range [][2]string{{alice, bob}, {bob, carol}, {carol, bob}} {
+		if err := ds.AddFollowingUser(follow[0], follow[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.DeleteUser(bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetUser(bob); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, given %v", err)
+	}
+	if err := ds.DeleteUser(bob); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound deleting user again, given %v", err)
+	}
+	following, err := ds.GetUserFollowing(alice)
+	if err != nil || len(following) != 0 {
+		t.Fatalf("Follow relations of deleted user expected to be removed, given %v, err: %v", following, err)
+	}
+	assertFollowCounts(t, ds, alice, 0, 0)
+	assertFollowCounts(t, ds, carol, 0, 0)
+}
+
+func TestPurgeUser(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	factory := NewFactory(db, logger)
+	ds := factory.CreateUserDataStore()
+	users := storeUsers(t, ds, "alice", "bob", "carol")
+	alice, bob, carol := users[0].ID.Hex(), users[1].ID.Hex(), users[2].ID.Hex()
+	for _, follow := range [][2]string{{alice, bob}, {bob, carol}} {
+		if err := ds.AddFollowingUser(follow[0], follow[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	caw, err := factory.CreateCawDataStore().Store(models.Caw{UserID: users[1].ID, UserName: "bob", Message: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = factory.CreateNotificationDataStore().Store(models.Notification{UserID: users[0].ID,
+		Type: models.NotificationFollow, Actor: models.Follow{UserID: users[1].ID, Name: "bob"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := infrastructure.PurgeUser(factory, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *report != (infrastructure.PurgeReport{FollowsRemoved: 2, CawsRemoved: 1, NotificationsRemoved: 1}) {
+		t.Fatalf("Unexpected purge report %+v", report)
+	}
+	if _, err := factory.CreateCawDataStore().GetByID(caw.ID.Hex(), alice); err != infrastructure.ErrNotFound {
+		t.Fatalf("Caw of purged user expected to be removed, given %v", err)
+	}
+	assertFollowCounts(t, ds, alice, 0, 0)
+	assertFollowCounts(t, ds, carol, 0, 0)
+	if _, err := infrastructure.PurgeUser(factory, bob); err != infrastructure.ErrNotFound {
+		t.Fatalf("Expected ErrNotFound purging user again, given %v", err)
+	}
+}
+
+func TestForEachUser(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateUserDataStore()
+	for i := 0; i < batchSize+1; i++ {
+		storeUsers(t, ds, fmt.Sprintf("user%d", i))
+	}
+
+	seen := map[string]bool{}
+	err := ds.ForEachUser(func(user models.User) error {
+		// store can be used while users are iterated
+		if _, err := ds.GetUser(user.ID.Hex()); err != nil {
+			return err
+		}
+		seen[user.Name] = true
+		return nil
+	})
+	if err != nil || len(seen) != batchSize+1 {
+		t.Fatalf("Expected %d users, given %d, err: %v", batchSize+1, len(seen), err)
+	}
+}
+
+func TestMutualsAndFollowSuggestions(t *testing.T) {
+	db := InitializeDataBase(t)
+	defer db.Close()
+	ds := NewFactory(db, logger).CreateUserDataStore()
+	users := storeUsers(t, ds, "alice", "bob", "carol", "dave", "erin")
+	ids := map[string]string{}
+	for _, user := range users {
+		ids[user.Name] = user.ID.Hex()
+	}
+	follows := [][2]string{
+		{"alice", "bob"}, {"alice", "carol"}, {"bob", "alice"},
+		{"bob", "dave"}, {"carol", "dave"}, {"carol", "erin"}, {"carol", "alice"},
+	}
+	for _, follow := range follows {
+		if err := ds.AddFollowingUser(ids[follow[0]], ids[follow[1]]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mutuals, err := ds.GetMutuals(ids["alice"], 0)
+	if err != nil || len(mutuals) != 2 || mutuals[0].Name != "bob" || mutuals[1].Name != "carol" {
+		t.Fatalf("Expected mutuals bob and carol, given %v, err: %v", mutuals, err)
+	}
+
+	suggestions, err := ds.GetFollowSuggestions(ids["alice"], 10)
+	if err != nil || len(suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions, given %v, err: %v", suggestions, err)
+	}
+	if suggestions[0].User.Name != "dave" || suggestions[0].Score != 2 || suggestions[0].Reason != "Followed by bob and carol" {
+		t.Fatalf("Expected dave followed by bob and carol first, given %+v", suggestions[0])
+	}
+	if suggestions[1].User.Name != "erin" || suggestions[1].Score != 1 || len(suggestions[1].FollowedBy) != 1 {
+		t.Fatalf("Expected erin followed by carol second, given %+v", suggestions[1])
+	}
+}
@@ -11,56 +11,92 @@ import (
 const (
 	userCollection           = "user"
 	followRelationCollection = "followRelation"
+	// suggestionReasonSize limits number of followed users attached to suggestion
+	suggestionReasonSize = 3
 )
 
 // UserDataStore represents data access layer
 type UserDataStore interface {
 	GetUser(userID string) (*models.User, error)
 	GetUserByName(userID string) (*models.User, error)
+	IsSuspended(userID string) (bool, error)
 	StoreUser(user models.User) (*models.User, error)
 	DeleteUser(userID string) error
+	ForEachUser(fn func(user models.User) error) error
 	GetUserFollowers(userID string) ([]models.Follow, error)
 	GetUserFollowing(userID string) ([]models.Follow, error)
 	AddFollowingUser(followerID string, followingID string) error
 	UnfollowUser(followerID string, followingID string) error
+	GetFollowRelation(followerID string, followingID string) (*models.FollowRelation, error)
+	GetMutuals(userID string, page int) ([]models.Follow, error)
+	GetFollowSuggestions(userID string, limit int) ([]models.Suggestion, error)
 	Close()
 }
 
 // mgoUserDataStore implements UserDataStore and provides access to mongodb store
 type mgoUserDataStore struct {
-	session *mgo.Session
-	logger  *logrus.Logger
+	session  *mgo.Session
+	database string
+	logger   *logrus.Logger
 }
 
-// NewMgoUserDataStore creates mgoUserDataStore
-func NewMgoUserDataStore(session *mgo.Session, logger *logrus.Logger) *mgoUserDataStore {
-	dataStore := mgoUserDataStore{session: session, logger: logger}
-	index := mgo.Index{
-		Key:        []string{"name", "email"},
-		Unique:     true,
-		DropDups:   true,
-		Background: true,
-		Sparse:     true,
-	}
-	err := dataStore.user().EnsureIndex(index)
-	if err != nil {
-		logger.Error(err)
+// NewMgoUserDataStore creates mgoUserDataStore of database with provided name
+func NewMgoUserDataStore(session *mgo.Session, database string, logger *logrus.Logger) *mgoUserDataStore {
+	dataStore := mgoUserDataStore{session: session, database: database, logger: logger}
+	if err := ensureUserIndexes(session.DB(database), logger); err != nil {
 		panic(err)
 	}
 
 	return &dataStore
 }
 
+// userIndexes are required by user queries, unique index rejects duplicated names and emails
+var userIndexes = []mgo.Index{
+	{Key: []string{"name", "email"}, Unique: true, DropDups: true, Background: true, Sparse: true},
+}
+
+// followRelationIndexes are required by follow graph queries
+var followRelationIndexes = []mgo.Index{
+	{Key: []string{"follower.user_id", "following.user_id"}, Unique: true, Background: true},
+	{Key: []string{"following.user_id", "follower.name"}, Background: true},
+}
+
+// ensureUserIndexes creates unique index of user names and emails
+func ensureUserIndexes(database *mgo.Database, logger *logrus.Logger) error {
+	return ensureCollectionIndexes(database, logger, userCollection, userIndexes)
+}
+
+// ensureFollowRelationIndexes creates indexes required by follow graph queries.
+// Unique index cannot be created while duplicated relations exist, they have
+// to be removed with RepairFollowRelations first.
+func ensureFollowRelationIndexes(database *mgo.Database, logger *logrus.Logger) error {
+	if err := dropConflictingIndexes(database, logger, followRelationCollection, followRelationIndexes); err != nil {
+		return err
+	}
+	for _, index := range followRelationIndexes {
+		err := database.C(followRelationCollection).EnsureIndex(index)
+		if err != nil && mgo.IsDup(err) {
+			logger.Errorf("Cannot create unique follow relation index, run cawadmin repair-follows. err: %s", err)
+			continue
+		}
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (ds *mgoUserDataStore) Close() {
 	ds.session.Close()
 }
 
 func (ds *mgoUserDataStore) user() *mgo.Collection {
-	return ds.session.DB(db).C(userCollection)
+	return ds.session.DB(ds.database).C(userCollection)
 }
 
 func (ds *mgoUserDataStore) followRelation() *mgo.Collection {
-	return ds.session.DB(db).C(followRelationCollection)
+	return ds.session.DB(ds.database).C(followRelationCollection)
 }
 
 // GetUser gets and returns User with requested ID
@@ -85,6 +121,27 @@ func (ds *mgoUserDataStore) GetUser(userID string) (*models.User, error) {
 	return &user, nil
 }
 
+// IsSuspended returns true if account of user with provided ID is suspended.
+// ErrNotFound is returned if user does not exist.
+func (ds *mgoUserDataStore) IsSuspended(userID string) (bool, error) {
+	if !bson.IsObjectIdHex(userID) {
+		return false, ErrNotFound
+	}
+
+	var user models.User
+	err := ds.user().
+		FindId(bson.ObjectIdHex(userID)).
+		Select(bson.M{"suspended": true}).
+		One(&user)
+	if err == mgo.ErrNotFound {
+		return false, ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return false, err
+	}
+	return user.Suspended, nil
+}
+
 // GetUserByName gets and returns User with requested name
 func (ds *mgoUserDataStore) GetUserByName(userName string) (*models.User, error) {
 	var user models.User
@@ -116,10 +173,33 @@ func (ds *mgoUserDataStore) StoreUser(user models.User) (*models.User, error) {
 	return &user, nil
 }
 
-// DeleteUser deletes user with provider ID
+// ForEachUser calls fn for every user until fn returns error
+func (ds *mgoUserDataStore) ForEachUser(fn func(user models.User) error) error {
+	iter := ds.user().Find(nil).Iter()
+	var user models.User
+	for iter.Next(&user) {
+		if err := fn(user); err != nil {
+			iter.Close()
+			return err
+		}
+		user = models.User{}
+	}
+	if err := iter.Close(); err != nil {
+		ds.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// DeleteUser deletes user with provider ID, ErrNotFound is returned if user does not exist
 func (ds *mgoUserDataStore) DeleteUser(userID string) error {
+	if !bson.IsObjectIdHex(userID) {
+		return ErrNotFound
+	}
 	err := ds.user().RemoveId(bson.ObjectIdHex(userID))
-	if err != nil {
+	if err == mgo.ErrNotFound {
+		return ErrNotFound
+	} else if err != nil {
 		ds.logger.Error(err)
 	}
 	return err
@@ -169,8 +249,20 @@ func (ds *mgoUserDataStore) GetUserFollowing(userID string) ([]models.Follow, er
 	return following, nil
 }
 
-// AddFollowingUser creates relationships between follower and following user
+// AddFollowingUser creates relationships between follower and following user.
+// ErrFollowExists is returned if follower already follows following user.
 func (ds *mgoUserDataStore) AddFollowingUser(followerID string, followingID string) error {
+	if followerID == followingID {
+		return ErrSelfFollow
+	}
+
+	_, err := ds.GetFollowRelation(followerID, followingID)
+	if err == nil {
+		return ErrFollowExists
+	} else if err != ErrNotFound {
+		return err
+	}
+
 	followerUser, err := ds.GetUser(followerID)
 	if err != nil {
 		ds.logger.Error(err)
@@ -200,6 +292,9 @@ func (ds *mgoUserDataStore) AddFollowingUser(followerID string, followingID stri
 	}
 	err = ds.followRelation().Insert(&followRelation)
 	if err != nil {
+		if mgo.IsDup(err) {
+			return ErrFollowExists
+		}
 		ds.logger.Error(err)
 		return err
 	}
@@ -212,18 +307,110 @@ func (ds *mgoUserDataStore) AddFollowingUser(followerID string, followingID stri
 	return nil
 }
 
+// GetFollowRelation returns relation of users if user with followerID follows user with followingID
+func (ds *mgoUserDataStore) GetFollowRelation(followerID string, followingID string) (*models.FollowRelation, error) {
+	if !bson.IsObjectIdHex(followerID) || !bson.IsObjectIdHex(followingID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+
+	var relation models.FollowRelation
+	err := ds.followRelation().
+		Find(bson.M{
+			"follower.user_id":  bson.ObjectIdHex(followerID),
+			"following.user_id": bson.ObjectIdHex(followingID),
+		}).One(&relation)
+	if err == mgo.ErrNotFound {
+		return nil, ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// GetMutuals returns page of users which user with provided ID follows and which follow him back.
+// Lookup with pipeline requires MongoDB 3.6.
+func (ds *mgoUserDataStore) GetMutuals(userID string, page int) ([]models.Follow, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+	user := bson.ObjectIdHex(userID)
+
+	// followers are read by (following, follower name) index and every follower is looked up
+	// in users followed by user on (follower, following) index, so lists are not loaded whole
+	var queryResult []struct {
+		Follower models.Follow `bson:"follower"`
+	}
+	err := ds.followRelation().Pipe([]bson.M{
+		{"$match": bson.M{"following.user_id": user}},
+		{"$sort": bson.M{"follower.name": 1}},
+		{"$lookup": bson.M{
+			"from": followRelationCollection,
+			"let":  bson.M{"follower": "$follower.user_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{
+					"follower.user_id": user,
+					"$expr":            bson.M{"$eq": []string{"$following.user_id", "$$follower"}},
+				}},
+				{"$limit": 1},
+				{"$project": bson.M{"_id": true}},
+			},
+			"as": "followed_back",
+		}},
+		{"$match": bson.M{"followed_back": bson.M{"$ne": []interface{}{}}}},
+		{"$skip": page * pageSize},
+		{"$limit": pageSize},
+		{"$project": bson.M{"follower": true}},
+	}).All(&queryResult)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	mutuals := []models.Follow{}
+	for _, f := range queryResult {
+		mutuals = append(mutuals, f.Follower)
+	}
+	return mutuals, nil
+}
+
+// UnfollowUser removes follow relation of users and decrements their follow stats.
+// ErrNotFound is returned when user with followerID does not follow user with followingID
 func (ds *mgoUserDataStore) UnfollowUser(followerID, followingID string) error {
-	return nil
+	if !bson.IsObjectIdHex(followerID) || !bson.IsObjectIdHex(followingID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return ErrNotFound
+	}
+
+	err := ds.followRelation().Remove(bson.M{
+		"follower.user_id":  bson.ObjectIdHex(followerID),
+		"following.user_id": bson.ObjectIdHex(followingID),
+	})
+	if err == mgo.ErrNotFound {
+		return ErrNotFound
+	} else if err != nil {
+		ds.logger.Error(err)
+		return err
+	}
+
+	return ds.changeFollowStat(followerID, followingID, -1)
 }
 
 func (ds *mgoUserDataStore) incrementFollowStat(followerID, followingID string) error {
-	err := ds.user().Update(bson.M{"_id": bson.ObjectIdHex(followingID)}, bson.M{"$inc": bson.M{"followers_count": 1}})
+	return ds.changeFollowStat(followerID, followingID, 1)
+}
+
+// changeFollowStat adds delta to followers count of followed user and following count of follower
+func (ds *mgoUserDataStore) changeFollowStat(followerID, followingID string, delta int) error {
+	err := ds.user().Update(bson.M{"_id": bson.ObjectIdHex(followingID)}, bson.M{"$inc": bson.M{"followers_count": delta}})
 	if err != nil {
 		ds.logger.Error(err)
 		return err
 	}
 
-	err = ds.user().Update(bson.M{"_id": bson.ObjectIdHex(followerID)}, bson.M{"$inc": bson.M{"following_count": 1}})
+	err = ds.user().Update(bson.M{"_id": bson.ObjectIdHex(followerID)}, bson.M{"$inc": bson.M{"following_count": delta}})
 	if err != nil {
 		ds.logger.Error(err)
 		return err
@@ -231,3 +418,62 @@ func (ds *mgoUserDataStore) incrementFollowStat(followerID, followingID string)
 
 	return nil
 }
+
+// GetFollowSuggestions returns users followed by users which user with userID follows,
+// ordered by number of such users. Already followed users and user itself are excluded.
+func (ds *mgoUserDataStore) GetFollowSuggestions(userID string, limit int) ([]models.Suggestion, error) {
+	if !bson.IsObjectIdHex(userID) {
+		ds.logger.Error("User Id is not mongo ObjectId")
+		return nil, ErrNotFound
+	}
+	user := bson.ObjectIdHex(userID)
+
+	var following []struct {
+		Following models.Follow `bson:"following"`
+	}
+	err := ds.followRelation().
+		Find(bson.M{"follower.user_id": user}).
+		Select(bson.M{"following.user_id": true}).All(&following)
+	if err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	suggestions := []models.Suggestion{}
+	if len(following) == 0 {
+		return suggestions, nil
+	}
+	followingIDs := []bson.ObjectId{}
+	for _, f := range following {
+		followingIDs = append(followingIDs, f.Following.UserID)
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"follower.user_id":  bson.M{"$in": followingIDs},
+			"following.user_id": bson.M{"$nin": append(followingIDs, user)},
+		}},
+		{"$group": bson.M{
+			"_id":         "$following.user_id",
+			"user":        bson.M{"$first": "$following"},
+			"score":       bson.M{"$sum": 1},
+			"followed_by": bson.M{"$push": "$follower"},
+		}},
+		{"$sort": bson.D{{Name: "score", Value: -1}, {Name: "_id", Value: 1}}},
+		{"$limit": limit},
+		{"$project": bson.M{
+			"user":        1,
+			"score":       1,
+			"followed_by": bson.M{"$slice": []interface{}{"$followed_by", suggestionReasonSize}},
+		}},
+	}
+	if err = ds.followRelation().Pipe(pipeline).All(&suggestions); err != nil {
+		ds.logger.Error(err)
+		return nil, err
+	}
+
+	for i := range suggestions {
+		suggestions[i].Reason = suggestions[i].Summary()
+	}
+	return suggestions, nil
+}
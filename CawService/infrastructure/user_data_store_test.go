@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"Caw/UserService/models"
+	"strings"
 	"testing"
 
 	"github.com/Sirupsen/logrus"
@@ -19,7 +20,7 @@ func InitializeDataBase() *mgo.Session {
 		panic(err)
 	}
 
-	err = session.DB(db).DropDatabase()
+	err = session.DB(DefaultDatabase).DropDatabase()
 	if err != nil {
 		panic(err)
 	}
@@ -29,7 +30,7 @@ func InitializeDataBase() *mgo.Session {
 func TestStoreUser(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	user := models.User{
@@ -52,7 +53,7 @@ func TestStoreUser(t *testing.T) {
 func TestStoreDuplicatedUser(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	user := models.User{
@@ -81,7 +82,7 @@ func TestStoreDuplicatedUser(t *testing.T) {
 func TestGetUser(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	user := models.User{
@@ -109,7 +110,7 @@ func TestGetUser(t *testing.T) {
 func TestDeleteUser(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	testUser := models.User{
@@ -137,12 +138,15 @@ func TestDeleteUser(t *testing.T) {
 	if err != nil {
 		t.Errorf("%v", err)
 	}
+	if err = userDataStore.DeleteUser(storedUser.ID.Hex()); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound deleting user again, given %v", err)
+	}
 }
 
 func TestAddFollowedUser(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	follower := models.User{
@@ -178,7 +182,7 @@ func TestAddFollowedUser(t *testing.T) {
 func TestGetFollowedUsers(t *testing.T) {
 	session := InitializeDataBase()
 	defer session.Close()
-	userDataStore := NewMgoUserDataStore(session, logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
 	defer userDataStore.Close()
 
 	follower := models.User{
@@ -228,3 +232,253 @@ func TestGetFollowedUsers(t *testing.T) {
 		t.Fatal("Following user is not equal to expected user", followingUsers, expectedFollowingUser)
 	}
 }
+
+func TestGetFollowSuggestions(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	names := []string{"user", "bob", "alice", "popular", "niche"}
+	users := map[string]*models.User{}
+	for _, name := range names {
+		storedUser, err := userDataStore.StoreUser(models.User{Name: name, Email: name + "@email.com"})
+		if err != nil {
+			t.Fatalf("StoreUser error: %s, user: %s", err, name)
+		}
+		users[name] = storedUser
+	}
+
+	follows := [][]string{
+		{"user", "bob"}, {"user", "alice"},
+		{"bob", "popular"}, {"alice", "popular"},
+		{"bob", "niche"}, {"bob", "user"}, {"alice", "bob"},
+	}
+	for _, follow := range follows {
+		err := userDataStore.AddFollowingUser(users[follow[0]].ID.Hex(), users[follow[1]].ID.Hex())
+		if err != nil {
+			t.Fatal("AddFollowingUser err", err)
+		}
+	}
+
+	suggestions, err := userDataStore.GetFollowSuggestions(users["user"].ID.Hex(), 10)
+	if err != nil {
+		t.Fatal("GetFollowSuggestions err", err)
+	}
+	if len(suggestions) != 2 {
+		t.Fatalf("Suggestions count are different expected %d, given %d", 2, len(suggestions))
+	}
+	if suggestions[0].User.Name != "popular" || suggestions[0].Score != 2 {
+		t.Fatalf("Suggestion with the biggest overlap should be first. given %v", suggestions[0])
+	}
+	if suggestions[1].User.Name != "niche" || suggestions[1].Reason != "Followed by bob" {
+		t.Fatalf("Unexpected second suggestion %v", suggestions[1])
+	}
+}
+
+func TestGetFollowSuggestionsOrdersByScoreThenID(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	// candidates stored first have lower ids, so sorting by _id first would put the popular one last
+	names := []string{"first", "second", "popular", "user", "bob", "alice"}
+	users := map[string]*models.User{}
+	for _, name := range names {
+		storedUser, err := userDataStore.StoreUser(models.User{Name: name, Email: name + "@email.com"})
+		if err != nil {
+			t.Fatalf("StoreUser error: %s, user: %s", err, name)
+		}
+		users[name] = storedUser
+	}
+
+	follows := [][]string{
+		{"user", "bob"}, {"user", "alice"},
+		{"bob", "popular"}, {"alice", "popular"},
+		{"bob", "second"}, {"alice", "first"},
+	}
+	for _, follow := range follows {
+		err := userDataStore.AddFollowingUser(users[follow[0]].ID.Hex(), users[follow[1]].ID.Hex())
+		if err != nil {
+			t.Fatal("AddFollowingUser err", err)
+		}
+	}
+
+	suggestions, err := userDataStore.GetFollowSuggestions(users["user"].ID.Hex(), 10)
+	if err != nil {
+		t.Fatal("GetFollowSuggestions err", err)
+	}
+	var given []string
+	for _, suggestion := range suggestions {
+		given = append(given, suggestion.User.Name)
+	}
+	if strings.Join(given, ",") != "popular,first,second" {
+		t.Fatalf("Suggestions expected by score and then by id, given %v", given)
+	}
+}
+
+func TestFollowRelationQueries(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	names := []string{"user", "mutual", "fan", "idol"}
+	users := map[string]*models.User{}
+	for _, name := range names {
+		storedUser, err := userDataStore.StoreUser(models.User{Name: name, Email: name + "@email.com"})
+		if err != nil {
+			t.Fatalf("StoreUser error: %s, user: %s", err, name)
+		}
+		users[name] = storedUser
+	}
+
+	follows := [][]string{{"user", "mutual"}, {"mutual", "user"}, {"fan", "user"}, {"user", "idol"}}
+	for _, follow := range follows {
+		err := userDataStore.AddFollowingUser(users[follow[0]].ID.Hex(), users[follow[1]].ID.Hex())
+		if err != nil {
+			t.Fatal("AddFollowingUser err", err)
+		}
+	}
+
+	relation, err := userDataStore.GetFollowRelation(users["fan"].ID.Hex(), users["user"].ID.Hex())
+	if err != nil {
+		t.Fatal("GetFollowRelation err", err)
+	}
+	if relation.Follower.Name != "fan" || relation.Following.Name != "user" {
+		t.Fatalf("Unexpected follow relation %v", relation)
+	}
+	if _, err = userDataStore.GetFollowRelation(users["user"].ID.Hex(), users["fan"].ID.Hex()); err != ErrNotFound {
+		t.Fatalf("Not existing relation should return ErrNotFound, given %v", err)
+	}
+
+	mutuals, err := userDataStore.GetMutuals(users["user"].ID.Hex(), 0)
+	if err != nil {
+		t.Fatal("GetMutuals err", err)
+	}
+	if len(mutuals) != 1 || mutuals[0].Name != "mutual" {
+		t.Fatalf("Unexpected mutuals %v", mutuals)
+	}
+}
+
+func TestAddFollowingUserIsDuplicateSafe(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	ensureFollowRelationIndexes(session.DB(DefaultDatabase), logger)
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	follower, err := userDataStore.StoreUser(models.User{Name: "follower", Email: "follower@email.com"})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+	following, err := userDataStore.StoreUser(models.User{Name: "following", Email: "following@email.com"})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+
+	if err = userDataStore.AddFollowingUser(follower.ID.Hex(), following.ID.Hex()); err != nil {
+		t.Fatal("AddFollowingUser err", err)
+	}
+	if err = userDataStore.AddFollowingUser(follower.ID.Hex(), following.ID.Hex()); err != ErrFollowExists {
+		t.Fatalf("Repeated follow should return ErrFollowExists, given %v", err)
+	}
+	if err = userDataStore.AddFollowingUser(follower.ID.Hex(), follower.ID.Hex()); err != ErrSelfFollow {
+		t.Fatalf("Self follow should return ErrSelfFollow, given %v", err)
+	}
+
+	storedFollowing, err := userDataStore.GetUser(following.ID.Hex())
+	if err != nil {
+		t.Fatal("GetUser err", err)
+	}
+	if storedFollowing.FollowersCount != 1 {
+		t.Fatalf("Followers count should be incremented once, given %d", storedFollowing.FollowersCount)
+	}
+}
+
+func TestUnfollowUser(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	follower, err := userDataStore.StoreUser(models.User{Name: "follower", Email: "follower@email.com"})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+	following, err := userDataStore.StoreUser(models.User{Name: "following", Email: "following@email.com"})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+	if err = userDataStore.AddFollowingUser(follower.ID.Hex(), following.ID.Hex()); err != nil {
+		t.Fatal("AddFollowingUser err", err)
+	}
+
+	if err = userDataStore.UnfollowUser(follower.ID.Hex(), following.ID.Hex()); err != nil {
+		t.Fatal("UnfollowUser err", err)
+	}
+	if _, err = userDataStore.GetFollowRelation(follower.ID.Hex(), following.ID.Hex()); err != ErrNotFound {
+		t.Fatalf("Follow relation should be removed, given %v", err)
+	}
+	if err = userDataStore.UnfollowUser(follower.ID.Hex(), following.ID.Hex()); err != ErrNotFound {
+		t.Fatalf("Repeated unfollow should return ErrNotFound, given %v", err)
+	}
+
+	for _, user := range []*models.User{follower, following} {
+		stored, err := userDataStore.GetUser(user.ID.Hex())
+		if err != nil {
+			t.Fatal("GetUser err", err)
+		}
+		if stored.FollowersCount != 0 || stored.FollowingCount != 0 {
+			t.Fatalf("Follow stats of %s should be decremented, given %d and %d", stored.Name, stored.FollowersCount, stored.FollowingCount)
+		}
+	}
+}
+
+func TestRepairFollowRelations(t *testing.T) {
+	session := InitializeDataBase()
+	defer session.Close()
+	userDataStore := NewMgoUserDataStore(session, DefaultDatabase, logger)
+	defer userDataStore.Close()
+
+	follower, err := userDataStore.StoreUser(models.User{Name: "follower", Email: "follower@email.com", FollowingCount: 5})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+	following, err := userDataStore.StoreUser(models.User{Name: "following", Email: "following@email.com"})
+	if err != nil {
+		t.Fatal("StoreUser err", err)
+	}
+
+	relation := models.FollowRelation{
+		Follower:  models.Follow{UserID: follower.ID, Name: follower.Name},
+		Following: models.Follow{UserID: following.ID, Name: following.Name},
+	}
+	self := models.FollowRelation{Follower: relation.Follower, Following: relation.Follower}
+	for _, r := range []models.FollowRelation{relation, relation, relation, self} {
+		if err = userDataStore.followRelation().Insert(r); err != nil {
+			t.Fatal("Insert err", err)
+		}
+	}
+
+	report, err := RepairFollowRelations(session.DB(DefaultDatabase), logger)
+	if err != nil {
+		t.Fatal("RepairFollowRelations err", err)
+	}
+	expectedReport := FollowRepairReport{DuplicatesRemoved: 2, SelfFollowsRemoved: 1, UsersUpdated: 2}
+	if *report != expectedReport {
+		t.Fatalf("Reports are different expected %v, given %v", expectedReport, *report)
+	}
+
+	storedFollower, err := userDataStore.GetUser(follower.ID.Hex())
+	if err != nil {
+		t.Fatal("GetUser err", err)
+	}
+	if storedFollower.FollowingCount != 1 || storedFollower.FollowersCount != 0 {
+		t.Fatalf("Counters were not recomputed %v", storedFollower)
+	}
+	if err = userDataStore.followRelation().Insert(relation); !mgo.IsDup(err) {
+		t.Fatalf("Unique index should reject duplicated relation, given %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"Caw/UserService/app"
 	"Caw/UserService/infrastructure"
+	"Caw/UserService/reconciler"
 	"Caw/UserService/utils"
 	"context"
 	"net/http"
@@ -17,25 +18,39 @@ import (
 func main() {
 	appConfig := utils.New()
 	logger := logrus.New()
-	logger.Infof("Connecting to mongo %s", appConfig.Mongo)
-	session, err := mgo.Dial(appConfig.Mongo)
-	if err != nil {
-		logger.Error(err)
-		panic(err)
-	}
-
-	logger.Info("Connected to mongo")
+	open, closeStore := connect(appConfig, logger)
+	defer closeStore()
 
-	mgoDataStoreFactory := infrastructure.NewFactory(session, logger)
-	defer mgoDataStoreFactory.Close()
-
-	app := app.New(appConfig, mgoDataStoreFactory, logger)
+	var service service
+	if len(appConfig.Tenants) == 0 {
+		singleApp, stop := startApp(appConfig, appConfig.Database, open(appConfig.Database), logger)
+		defer stop()
+		service = singleApp
+	} else {
+		if appConfig.TenantSource != utils.TenantSourceHost && appConfig.TenantSource != utils.TenantSourceClaim {
+			logger.Fatalf("Unsupported tenant_source %q, expected %s or %s",
+				appConfig.TenantSource, utils.TenantSourceHost, utils.TenantSourceClaim)
+		}
+		apps := map[string]*app.App{}
+		for _, tenant := range appConfig.Tenants {
+			database := infrastructure.TenantDatabase(appConfig.Database, tenant)
+			tenantApp, stop := startApp(appConfig.ForTenant(tenant), database, open(database), logger)
+			defer stop()
+			apps[tenant] = tenantApp
+		}
+		service = app.NewTenants(apps, appConfig.TenantSource, logger)
+		logger.Infof("Serving tenants %v identified by %s", appConfig.Tenants, appConfig.TenantSource)
+	}
+	defer service.Close()
+	go service.LoadSearchIndex()
 
 	appConfig.WithWatchConfig(func(appConfig *utils.AppConfig) {
-		app.UpdateConfig(appConfig)
+		service.UpdateConfig(appConfig)
 	})
 
-	srv := http.Server{Addr: appConfig.Address, Handler: app}
+	srv := http.Server{Addr: appConfig.Address, Handler: service}
+	// open event streams never become idle, so they have to be closed for Shutdown to complete
+	srv.RegisterOnShutdown(service.Close)
 	stopChan := make(chan struct{})
 	go func() {
 		sigint := make(chan os.Signal, 1)
@@ -56,3 +71,78 @@ func main() {
 
 	logger.Info("Server gracefully stopped")
 }
+
+// service is App or Tenants serving App of every tenant
+type service interface {
+	http.Handler
+	UpdateConfig(appConfig *utils.AppConfig)
+	LoadSearchIndex()
+	Close()
+}
+
+// connect connects to store configured by appConfig and returns function opening
+// migrated database with provided name and function closing the connection
+func connect(appConfig *utils.AppConfig, logger *logrus.Logger) (func(database string) infrastructure.DataStoreFactory, func()) {
+	switch appConfig.Store {
+	case utils.StoreMongo:
+		logger.Infof("Connecting to mongo %s", appConfig.Mongo)
+		session, err := mgo.Dial(appConfig.Mongo)
+		if err != nil {
+			logger.Error(err)
+			panic(err)
+		}
+		logger.Info("Connected to mongo")
+
+		return func(database string) infrastructure.DataStoreFactory {
+			databaseSession := session.Clone()
+			migrate(appConfig, database, logger, func(dryRun bool) ([]infrastructure.MigrationResult, error) {
+				return infrastructure.Migrate(databaseSession.DB(database), logger, dryRun)
+			})
+			return infrastructure.NewFactory(databaseSession, database, logger)
+		}, session.Close
+	case utils.StoreSQLite:
+		return connectSQLite(appConfig, logger)
+	}
+	logger.Fatalf("Unsupported store %q, expected %s or %s", appConfig.Store, utils.StoreMongo, utils.StoreSQLite)
+	return nil, nil
+}
+
+// startApp starts App and counter reconciler of the database.
+// Returned function stops reconciler and closes the database.
+func startApp(appConfig *utils.AppConfig, database string, dataStoreFactory infrastructure.DataStoreFactory, logger *logrus.Logger) (*app.App, func()) {
+	counterReconciler := reconciler.New(database, dataStoreFactory, appConfig.ReconcileInterval, logger)
+	go counterReconciler.Run()
+
+	return app.New(appConfig, dataStoreFactory, logger), func() {
+		counterReconciler.Stop()
+		dataStoreFactory.Close()
+	}
+}
+
+// migrate applies pending migrations by run or, when disabled in config, warns about them.
+// Migrations deleting data are never applied on startup, they have to be applied by cawadmin migrate.
+func migrate(appConfig *utils.AppConfig, database string, logger *logrus.Logger, run func(dryRun bool) ([]infrastructure.MigrationResult, error)) {
+	pending, err := run(true)
+	if err != nil {
+		logger.Error(err)
+		panic(err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if !appConfig.MigrateOnStartup {
+		logger.Warnf("%d migrations of database %s are pending, run cawadmin migrate", len(pending), database)
+		return
+	}
+	for _, migration := range pending {
+		if migration.Destructive {
+			logger.Warnf("Migration %d of database %s deletes data, run cawadmin migrate. %d migrations are pending",
+				migration.Version, database, len(pending))
+			return
+		}
+	}
+	if _, err = run(false); err != nil {
+		logger.Error(err)
+		panic(err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build nosqlite
+// +build nosqlite
+
+package main
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/utils"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// connectSQLite fails, service built with nosqlite tag supports only MongoDB and does not require cgo
+func connectSQLite(appConfig *utils.AppConfig, logger *logrus.Logger) (func(database string) infrastructure.DataStoreFactory, func()) {
+	logger.Fatalf("Store %s is not supported, service was built with nosqlite tag", utils.StoreSQLite)
+	return nil, nil
+}
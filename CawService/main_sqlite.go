@@ -0,0 +1,36 @@
+//go:build !nosqlite
+// +build !nosqlite
+
+package main
+
+import (
+	"Caw/UserService/infrastructure"
+	"Caw/UserService/infrastructure/sqlite"
+	"Caw/UserService/utils"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// connectSQLite returns function opening migrated database file with provided name in sqlite_dir.
+// SQLite driver requires cgo, build with -tags nosqlite to leave it out of MongoDB only deployments.
+func connectSQLite(appConfig *utils.AppConfig, logger *logrus.Logger) (func(database string) infrastructure.DataStoreFactory, func()) {
+	if err := os.MkdirAll(appConfig.SQLiteDir, 0755); err != nil {
+		logger.Error(err)
+		panic(err)
+	}
+
+	return func(database string) infrastructure.DataStoreFactory {
+		path := sqlite.Path(appConfig.SQLiteDir, database)
+		db, err := sqlite.Open(path)
+		if err != nil {
+			logger.Error(err)
+			panic(err)
+		}
+		logger.Infof("Opened sqlite database %s", path)
+		migrate(appConfig, database, logger, func(dryRun bool) ([]infrastructure.MigrationResult, error) {
+			return sqlite.Migrate(db, logger, dryRun)
+		})
+		return sqlite.NewFactory(db, logger)
+	}, func() {}
+}
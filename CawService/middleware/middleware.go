@@ -1,10 +1,16 @@
 package middleware
 
 import (
+	"Caw/UserService/models"
 	"Caw/UserService/utils"
+	"bufio"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/Sirupsen/logrus"
 	"github.com/anycmon/throttle"
+	"io"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -16,6 +22,26 @@ const (
 
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
+// WriteProblem writes RFC 7807 problem details. Instance defaults to requested path.
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem models.Problem) {
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	js, err := problem.ToJSON()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", models.ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(js)
+}
+
+func unauthenticated(w http.ResponseWriter, r *http.Request) {
+	WriteProblem(w, r, models.NewProblem(http.StatusUnauthorized, models.CodeUnauthenticated,
+		"Valid bearer token is required"))
+}
+
 func Chain(f http.HandlerFunc, middlewares ...Middleware) http.HandlerFunc {
 	for _, middleware := range middlewares {
 		f = middleware(f)
@@ -34,6 +60,23 @@ func (w *loggingResponseWriter) WriteHeader(code int) {
 	w.statusCode = code
 }
 
+// Hijack allows WebSocket handlers to take over connection of wrapped ResponseWriter
+func (w *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter does not support hijacking")
+	}
+	w.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Flush allows streaming handlers to flush wrapped ResponseWriter
+func (w *loggingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func Logging(log *logrus.Logger) Middleware {
 	return func(f http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
@@ -50,33 +93,40 @@ func Logging(log *logrus.Logger) Middleware {
 	}
 }
 
-func MustAuth(log *logrus.Logger) Middleware {
+// MustAuth accepts only requests with valid bearer token issued for tenant
+func MustAuth(log *logrus.Logger, tenant string) Middleware {
 	return func(f http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			authorization := r.Header.Get("Authorization")
 			words := strings.Fields(authorization)
 			if len(words) != 2 {
 				log.Errorf("Invalid Authorization header %v", authorization)
-				w.WriteHeader(http.StatusUnauthorized)
+				unauthenticated(w, r)
 				return
 			}
 
 			if words[0] != "Bearer" {
 				log.Errorf("Unsupported Authorization type: %v", words[0])
-				w.WriteHeader(http.StatusUnauthorized)
+				unauthenticated(w, r)
 				return
 			}
 
 			userClaims, err := utils.DecodeUserToken(words[1])
 			if err != nil {
 				log.Errorf("Cannot decode user token: %v", err)
-				w.WriteHeader(http.StatusUnauthorized)
+				unauthenticated(w, r)
 				return
 			}
 
 			if err = userClaims.Valid(); err != nil {
 				log.Errorf("Invalid user token: %v", err)
-				w.WriteHeader(http.StatusUnauthorized)
+				unauthenticated(w, r)
+				return
+			}
+
+			if userClaims.Tenant() != tenant {
+				log.Errorf("User token issued for other tenant: %v", userClaims.Tenant())
+				unauthenticated(w, r)
 				return
 			}
 
@@ -103,7 +153,8 @@ func Produce(supportedAccept string) Middleware {
 				}
 			}
 
-			w.WriteHeader(http.StatusNotAcceptable)
+			WriteProblem(w, r, models.NewProblem(http.StatusNotAcceptable, models.CodeNotAcceptable,
+				"Supported media type is "+supportedAccept))
 		}
 	}
 }
@@ -118,7 +169,8 @@ func Consume(supportedContentType string) Middleware {
 					return
 				}
 			}
-			w.WriteHeader(http.StatusUnsupportedMediaType)
+			WriteProblem(w, r, models.NewProblem(http.StatusUnsupportedMediaType, models.CodeUnsupportedMediaType,
+				"Supported content type is "+supportedContentType))
 		}
 	}
 }
@@ -132,11 +184,51 @@ func CQRS() Middleware {
 	}
 }
 
+// ErrBodyTooLarge is returned by reads of request body exceeding limit of MaxBodySize
+var ErrBodyTooLarge = errors.New("Request body is too large")
+
+// MaxBodySize rejects requests declaring body longer than limit bytes and makes
+// reads of longer bodies fail with ErrBodyTooLarge
+func MaxBodySize(limit int64) Middleware {
+	return func(f http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				WriteProblem(w, r, models.NewProblem(http.StatusRequestEntityTooLarge, models.CodePayloadTooLarge,
+					fmt.Sprintf("Request body can have at most %d bytes", limit)))
+				return
+			}
+			r.Body = &limitedBody{ReadCloser: r.Body, remaining: limit}
+			f(w, r)
+		}
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// one byte more is read to find out if body exceeds limit
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	return n, ErrBodyTooLarge
+}
+
 func Throttle(throttle throttle.Throttle) Middleware {
 	return func(f http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			if throttle.Allow() == false {
-				w.WriteHeader(http.StatusForbidden)
+				WriteProblem(w, r, models.NewProblem(http.StatusForbidden, models.CodeRateLimited,
+					"Too many requests"))
 				return
 			}
 
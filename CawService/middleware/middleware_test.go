@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"time"
 
+	"Caw/UserService/models"
 	"Caw/UserService/utils"
 	"testing"
 
@@ -34,6 +37,10 @@ func TestMustAuth(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	otherTenantToken, err := utils.NewTenantUserToken("anycmon", "cats", time.Now().Add(time.Duration(1*time.Minute)).Unix())
+	if err != nil {
+		t.Error(err)
+	}
 	testCases := []struct {
 		Name                                 string
 		AuthorizationHeader                  string
@@ -55,6 +62,13 @@ func TestMustAuth(t *testing.T) {
 			false,
 			http.StatusUnauthorized,
 		},
+		{
+			"OtherTenantTokenTest",
+			"Bearer " + otherTenantToken,
+			false,
+			false,
+			http.StatusUnauthorized,
+		},
 		{
 			"UnsupportedAuthorizationMethodTest",
 			"UnknowMethod " + validToken,
@@ -81,7 +95,7 @@ func TestMustAuth(t *testing.T) {
 	isProtectedHandlerCalled := false
 	isUserClaimsPresentInContext := false
 
-	mustAuth := MustAuth(log)(func(w http.ResponseWriter, r *http.Request) {
+	mustAuth := MustAuth(log, "")(func(w http.ResponseWriter, r *http.Request) {
 		isProtectedHandlerCalled = true
 		if r.Context().Value("userClaims") != nil {
 			isUserClaimsPresentInContext = true
@@ -111,6 +125,9 @@ func TestMustAuth(t *testing.T) {
 		if res.StatusCode != testCase.ExpectedStatusCode {
 			t.Errorf("Wrong status code: expected %v given %v", testCase.ExpectedStatusCode, res.StatusCode)
 		}
+		if res.StatusCode == http.StatusUnauthorized && res.Header.Get("Content-Type") != models.ProblemContentType {
+			t.Errorf("Rejected request should be described by problem details, given %v", res.Header.Get("Content-Type"))
+		}
 		if isProtectedHandlerCalled != testCase.ExpectedIsProtectedHandlerCalled {
 			t.Errorf("IsProtectedHandlerCalled is different than expected. Expected %v given %v ",
 				testCase.ExpectedIsProtectedHandlerCalled, isProtectedHandlerCalled)
@@ -272,6 +289,51 @@ func TestConsume(t *testing.T) {
 	}
 }
 
+func TestMaxBodySize(t *testing.T) {
+	testCases := []struct {
+		Name                           string
+		Body                           string
+		DeclareLength                  bool
+		ExceptedIsWrappedHandlerCalled bool
+		ExpectedStatusCode             int
+		ExpectedReadErr                error
+	}{
+		{"BodyWithinLimitTest", "12345", true, true, http.StatusOK, nil},
+		{"DeclaredBodyOverLimitTest", "1234567", true, false, http.StatusRequestEntityTooLarge, nil},
+		{"UndeclaredBodyWithinLimitTest", "12345", false, true, http.StatusOK, nil},
+		{"UndeclaredBodyOverLimitTest", "1234567", false, true, http.StatusOK, ErrBodyTooLarge},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		var readErr error
+		wasCalled := false
+		handler := MaxBodySize(5)(func(w http.ResponseWriter, r *http.Request) {
+			wasCalled = true
+			_, readErr = ioutil.ReadAll(r.Body)
+		})
+
+		req := httptest.NewRequest("POST", "/testUrl", strings.NewReader(testCase.Body))
+		if !testCase.DeclareLength {
+			req.ContentLength = -1
+		}
+		res := httptest.NewRecorder()
+		handler(res, req)
+
+		if res.Code != testCase.ExpectedStatusCode {
+			t.Errorf("Wrong status code. Expected %v given %v", testCase.ExpectedStatusCode, res.Code)
+		}
+		if wasCalled != testCase.ExceptedIsWrappedHandlerCalled {
+			t.Errorf("WasCalled is different than expected. Expected %v given %v",
+				testCase.ExceptedIsWrappedHandlerCalled, wasCalled)
+		}
+		if readErr != testCase.ExpectedReadErr {
+			t.Errorf("Wrong read error. Expected %v given %v", testCase.ExpectedReadErr, readErr)
+		}
+	}
+}
+
 type ThrottleMock struct {
 	OnAllow bool
 }
@@ -1,7 +1,19 @@
 package models
 
+import "io"
+
 // Auth represents credentials required to authorize
 type Auth struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
 }
+
+// AuthFromJSON parse JSON payload to Auth model
+func AuthFromJSON(jAuth io.Reader) (*Auth, error) {
+	var auth Auth
+	if err := DecodeJSON(jAuth, &auth); err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
@@ -2,12 +2,29 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"time"
 
 	"gopkg.in/mgo.v2/bson"
 )
 
+const (
+	// VisibilityPublic makes caw readable by everyone
+	VisibilityPublic = "public"
+	// VisibilityFollowers makes caw readable only by author followers
+	VisibilityFollowers = "followers"
+	// VisibilityMentioned makes caw readable only by mentioned users
+	VisibilityMentioned = "mentioned"
+)
+
+const (
+	// OrderOldest lists caws of user the oldest first
+	OrderOldest = "oldest"
+	// OrderNewest lists caws of user the newest first
+	OrderNewest = "newest"
+)
+
 // Caw represents message or response to message created by user
 type Caw struct {
 	ID           bson.ObjectId `json:"id" bson:"_id,omitempty"`
@@ -19,13 +36,23 @@ type Caw struct {
 	LikeCount    int           `json:"like_count" bson:"like_count"`
 	RecawCount   int           `json:"recaw_count" bson:"recaw_count"`
 	RepliesCount int           `json:"replies_count" bson:"replies_count"`
+	Visibility   string        `json:"visibility" bson:"visibility"`
+	Mentions     []Mention     `json:"mentions,omitempty" bson:"mentions,omitempty"`
+	Hashtags     []string      `json:"hashtags,omitempty" bson:"hashtags,omitempty"`
+	EditedAt     *time.Time    `json:"edited_at,omitempty" bson:"edited_at,omitempty"`
+	EditCount    int           `json:"edit_count" bson:"edit_count"`
+}
+
+// CawWithAuthor represents Caw with embedded public profile of its author
+type CawWithAuthor struct {
+	Caw
+	Author *PublicUser `json:"author,omitempty"`
 }
 
 // CawFromJson parse JSON payload to Caw model
 func CawFromJSON(jCaw io.Reader) (*Caw, error) {
 	var caw Caw
-	decoder := json.NewDecoder(jCaw)
-	if err := decoder.Decode(&caw); err != nil {
+	if err := DecodeJSON(jCaw, &caw); err != nil {
 		return nil, err
 	}
 
@@ -36,3 +63,59 @@ func CawFromJSON(jCaw io.Reader) (*Caw, error) {
 func (c Caw) ToJSON() ([]byte, error) {
 	return json.Marshal(c)
 }
+
+// HasValidVisibility returns true if visibility is empty or one of supported values
+func (c Caw) HasValidVisibility() bool {
+	switch c.Visibility {
+	case "", VisibilityPublic, VisibilityFollowers, VisibilityMentioned:
+		return true
+	}
+	return false
+}
+
+// Validate returns all violations of message and visibility rules
+func (c Caw) Validate(limits Limits) []FieldError {
+	limits = limits.WithDefaults()
+	return Validate(
+		Field{Name: "message", Value: c.Message, Rules: []Rule{
+			Required("Message cannot be empty"),
+			MaxLength(limits.CawMaxLength, fmt.Sprintf("Message can have at most %d characters", limits.CawMaxLength)),
+			NoControlCharacters("Message cannot contain control characters"),
+		}},
+		Field{Name: "visibility", Value: c.Visibility, Rules: []Rule{
+			OneOf([]string{"", VisibilityPublic, VisibilityFollowers, VisibilityMentioned},
+				"Visibility has to be one of: public, followers, mentioned"),
+		}},
+	)
+}
+
+// IsEditableAt returns true if current Caw can still be edited at provided time
+func (c Caw) IsEditableAt(at time.Time, window time.Duration) bool {
+	return !at.After(c.CreatedAt.Add(window))
+}
+
+// IsMentioned returns true if user with provided ID is mentioned in current Caw
+func (c Caw) IsMentioned(userID string) bool {
+	for _, mention := range c.Mentions {
+		if mention.UserID.Hex() == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVisibleTo returns true if user with viewerID can read current Caw.
+// Caws without visibility are treated as public.
+func (c Caw) IsVisibleTo(viewerID string, isFollower bool) bool {
+	if c.UserID.Hex() == viewerID {
+		return true
+	}
+
+	switch c.Visibility {
+	case VisibilityFollowers:
+		return isFollower
+	case VisibilityMentioned:
+		return c.IsMentioned(viewerID)
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// CawRevision represents content of caw replaced by an edit
+type CawRevision struct {
+	ID    bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	CawID bson.ObjectId `json:"caw_id" bson:"caw_id"`
+	// Revision is 0 for original content and grows with every edit
+	Revision int       `json:"revision" bson:"revision"`
+	Message  string    `json:"message" bson:"message"`
+	Mentions []Mention `json:"mentions,omitempty" bson:"mentions,omitempty"`
+	Hashtags []string  `json:"hashtags,omitempty" bson:"hashtags,omitempty"`
+	// CreatedAt is time when content of revision was written
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	// ReplacedAt is time when revision was replaced by the next one
+	ReplacedAt time.Time `json:"replaced_at" bson:"replaced_at"`
+}
+
+// NewCawRevision returns revision keeping current content of caw
+func NewCawRevision(caw Caw, replacedAt time.Time) CawRevision {
+	createdAt := caw.CreatedAt
+	if caw.EditedAt != nil {
+		createdAt = *caw.EditedAt
+	}
+	return CawRevision{
+		CawID:      caw.ID,
+		Revision:   caw.EditCount,
+		Message:    caw.Message,
+		Mentions:   caw.Mentions,
+		Hashtags:   caw.Hashtags,
+		CreatedAt:  createdAt,
+		ReplacedAt: replacedAt,
+	}
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCawVisibility(t *testing.T) {
+	authorID := bson.NewObjectId()
+	mentionedID := bson.NewObjectId()
+	viewerID := bson.NewObjectId()
+	mentions := []Mention{{UserID: mentionedID, Name: "mentioned"}}
+
+	testCases := []struct {
+		Name            string
+		Caw             Caw
+		ViewerID        string
+		IsFollower      bool
+		ExcpectedResult bool
+	}{
+		{
+			"PublicCawTest",
+			Caw{UserID: authorID, Visibility: VisibilityPublic},
+			viewerID.Hex(),
+			false,
+			true,
+		},
+		{
+			"CawWithoutVisibilityIsPublicTest",
+			Caw{UserID: authorID},
+			viewerID.Hex(),
+			false,
+			true,
+		},
+		{
+			"FollowersCawVisibleToFollowerTest",
+			Caw{UserID: authorID, Visibility: VisibilityFollowers},
+			viewerID.Hex(),
+			true,
+			true,
+		},
+		{
+			"FollowersCawHiddenFromNotFollowerTest",
+			Caw{UserID: authorID, Visibility: VisibilityFollowers},
+			viewerID.Hex(),
+			false,
+			false,
+		},
+		{
+			"MentionedCawVisibleToMentionedUserTest",
+			Caw{UserID: authorID, Visibility: VisibilityMentioned, Mentions: mentions},
+			mentionedID.Hex(),
+			false,
+			true,
+		},
+		{
+			"MentionedCawHiddenFromFollowerTest",
+			Caw{UserID: authorID, Visibility: VisibilityMentioned, Mentions: mentions},
+			viewerID.Hex(),
+			true,
+			false,
+		},
+		{
+			"MentionedCawVisibleToAuthorTest",
+			Caw{UserID: authorID, Visibility: VisibilityMentioned},
+			authorID.Hex(),
+			false,
+			true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		if result := testCase.Caw.IsVisibleTo(testCase.ViewerID, testCase.IsFollower); result != testCase.ExcpectedResult {
+			t.Errorf("Caw %v visibility for %v returned %t expected %t", testCase.Caw, testCase.ViewerID, result, testCase.ExcpectedResult)
+		}
+	}
+}
+
+func TestCawVisibilityValidation(t *testing.T) {
+	testCases := []struct {
+		Name            string
+		Visibility      string
+		ExcpectedResult bool
+	}{
+		{"EmptyVisibilityTest", "", true},
+		{"PublicVisibilityTest", VisibilityPublic, true},
+		{"FollowersVisibilityTest", VisibilityFollowers, true},
+		{"MentionedVisibilityTest", VisibilityMentioned, true},
+		{"UnknownVisibilityTest", "friends", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		caw := Caw{Visibility: testCase.Visibility}
+		if result := caw.HasValidVisibility(); result != testCase.ExcpectedResult {
+			t.Errorf("Visibility %v validation returned %t expected %t", testCase.Visibility, result, testCase.ExcpectedResult)
+		}
+	}
+}
+
+func TestCawIsEditableAt(t *testing.T) {
+	createdAt := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	caw := Caw{CreatedAt: createdAt}
+
+	testCases := []struct {
+		Name     string
+		At       time.Time
+		Expected bool
+	}{
+		{"WithinWindowTest", createdAt.Add(time.Minute), true},
+		{"EndOfWindowTest", createdAt.Add(15 * time.Minute), true},
+		{"AfterWindowTest", createdAt.Add(15*time.Minute + time.Second), false},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		if caw.IsEditableAt(testCase.At, 15*time.Minute) != testCase.Expected {
+			t.Errorf("IsEditableAt is %t expected %t", !testCase.Expected, testCase.Expected)
+		}
+	}
+}
+
+func TestCawValidation(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Caw            Caw
+		ExpectedFields []string
+		ExpectedCodes  []string
+	}{
+		{"ValidCawTest", Caw{Message: "hello\nworld ✓"}, nil, nil},
+		{"EmptyMessageTest", Caw{Message: " \n"}, []string{"message"}, []string{FieldRequired}},
+		{"MaxLengthInCharactersTest", Caw{Message: strings.Repeat("ż", 280)}, nil, nil},
+		{"TooLongMessageTest", Caw{Message: strings.Repeat("a", 281)}, []string{"message"}, []string{FieldTooLong}},
+		{"ControlCharactersTest", Caw{Message: "hello\x00"}, []string{"message"}, []string{FieldInvalidCharacters}},
+		{
+			"AllViolationsTest",
+			Caw{Message: "", Visibility: "friends"},
+			[]string{"message", "visibility"},
+			[]string{FieldRequired, FieldInvalid},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		errors := testCase.Caw.Validate(DefaultLimits)
+		if len(errors) != len(testCase.ExpectedFields) {
+			t.Fatalf("Field errors count is %d expected %d: %v", len(errors), len(testCase.ExpectedFields), errors)
+		}
+		for i, err := range errors {
+			if err.Field != testCase.ExpectedFields[i] || err.Code != testCase.ExpectedCodes[i] {
+				t.Errorf("Field error is %v expected %s %s", err, testCase.ExpectedFields[i], testCase.ExpectedCodes[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeHashtag returns canonical form of hashtag so that #Go, #go
+// and their full-width variants are equal. Leading # is removed.
+func NormalizeHashtag(tag string) string {
+	tag = norm.NFKC.String(tag)
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// ExtractHashtags returns unique normalized hashtags found in message
+func ExtractHashtags(message string) []string {
+	var hashtags []string
+	seen := map[string]bool{}
+	runes := []rune(norm.NFKC.String(message))
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isHashtagRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isHashtagRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+
+		tag := NormalizeHashtag(string(runes[i+1 : end]))
+		if !seen[tag] {
+			seen[tag] = true
+			hashtags = append(hashtags, tag)
+		}
+		i = end - 1
+	}
+	return hashtags
+}
+
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	testCases := []struct {
+		Name             string
+		Message          string
+		ExpectedHashtags []string
+	}{
+		{"NoHashtagsTest", "plain message", nil},
+		{"SingleHashtagTest", "I like #go", []string{"go"}},
+		{"CaseInsensitiveHashtagsTest", "#Go and #go and #GO", []string{"go"}},
+		{"FullWidthHashtagTest", "＃Ｇｏ is #go", []string{"go"}},
+		{"UnicodeHashtagTest", "#zażółć #日本", []string{"zażółć", "日本"}},
+		{"HashtagWithPunctuationTest", "#go, #rust!", []string{"go", "rust"}},
+		{"HashInsideWordTest", "issue#12 #", nil},
+		{"HashtagWithUnderscoreTest", "#go_lang", []string{"go_lang"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		if result := ExtractHashtags(testCase.Message); !reflect.DeepEqual(result, testCase.ExpectedHashtags) {
+			t.Errorf("Hashtags of %q are %v expected %v", testCase.Message, result, testCase.ExpectedHashtags)
+		}
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	testCases := []struct {
+		Tag         string
+		ExpectedTag string
+	}{
+		{"#Go", "go"},
+		{"go", "go"},
+		{"Ｇｏ", "go"},
+	}
+
+	for _, testCase := range testCases {
+		if result := NormalizeHashtag(testCase.Tag); result != testCase.ExpectedTag {
+			t.Errorf("Normalized %q is %q expected %q", testCase.Tag, result, testCase.ExpectedTag)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrTrailingData is returned when payload contains more than one JSON value
+var ErrTrailingData = errors.New("Payload has to contain exactly one JSON value")
+
+// DecodeJSON decodes single JSON value from payload into v. Unknown fields and
+// data following the value are rejected, so misspelled fields do not pass unnoticed.
+func DecodeJSON(payload io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(payload)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return ErrTrailingData
+	}
+	return nil
+}
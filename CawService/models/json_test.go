@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Payload       string
+		ExpectedError bool
+		ExpectedName  string
+	}{
+		{"ValidPayloadTest", `{"name": "foo"}`, false, "foo"},
+		{"ValidPayloadWithTrailingWhitespaceTest", "{\"name\": \"foo\"}\n", false, "foo"},
+		{"UnknownFieldTest", `{"name": "foo", "userId": "bar"}`, true, ""},
+		{"MultipleValuesTest", `{"name": "foo"}{"name": "bar"}`, true, ""},
+		{"TrailingGarbageTest", `{"name": "foo"} garbage`, true, ""},
+		{"EmptyPayloadTest", ``, true, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+
+		var auth Auth
+		err := DecodeJSON(strings.NewReader(testCase.Payload), &auth)
+		if (err != nil) != testCase.ExpectedError {
+			t.Fatalf("Unexpected error. Expected error: %v given %v", testCase.ExpectedError, err)
+		}
+		if auth.Name != testCase.ExpectedName && !testCase.ExpectedError {
+			t.Fatalf("Wrong name. Expected %s given %s", testCase.ExpectedName, auth.Name)
+		}
+	}
+}
+
+func TestDecodeJSONTrailingData(t *testing.T) {
+	var auth Auth
+	err := DecodeJSON(strings.NewReader(`{"name": "foo"} {}`), &auth)
+	if err != ErrTrailingData {
+		t.Fatalf("Expected ErrTrailingData given %v", err)
+	}
+}
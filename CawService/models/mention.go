@@ -0,0 +1,45 @@
+package models
+
+import (
+	"unicode"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Mention represents user mentioned in caw message
+type Mention struct {
+	UserID bson.ObjectId `json:"user_id" bson:"user_id"`
+	Name   string        `json:"name" bson:"name"`
+}
+
+// ExtractMentionNames returns unique names of users mentioned in message as @name
+func ExtractMentionNames(message string) []string {
+	var names []string
+	seen := map[string]bool{}
+	runes := []rune(message)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && isMentionRune(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+
+		name := string(runes[i+1 : end])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		i = end - 1
+	}
+	return names
+}
+
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentionNames(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Message       string
+		ExpectedNames []string
+	}{
+		{"NoMentionsTest", "plain message", nil},
+		{"SingleMentionTest", "hello @anycmon", []string{"anycmon"}},
+		{"DuplicatedMentionsTest", "@bob and @bob", []string{"bob"}},
+		{"CaseSensitiveMentionsTest", "@Bob and @bob", []string{"Bob", "bob"}},
+		{"MentionWithPunctuationTest", "@bob, @alice!", []string{"bob", "alice"}},
+		{"EmailIsNotMentionTest", "write to bob@email.com @", nil},
+		{"UnicodeMentionTest", "cześć @żaneta_1", []string{"żaneta_1"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		if result := ExtractMentionNames(testCase.Message); !reflect.DeepEqual(result, testCase.ExpectedNames) {
+			t.Errorf("Mentions of %q are %v expected %v", testCase.Message, result, testCase.ExpectedNames)
+		}
+	}
+}
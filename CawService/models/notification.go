@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// NotificationFollow is sent to user who was followed
+	NotificationFollow = "follow"
+	// NotificationLike is sent to author of liked caw
+	NotificationLike = "like"
+	// NotificationReply is sent to author of caw which was replied
+	NotificationReply = "reply"
+	// NotificationMention is sent to user mentioned in caw
+	NotificationMention = "mention"
+)
+
+// maxGroupActors limits number of actors listed in NotificationGroup
+const maxGroupActors = 3
+
+// Notification represents event which user should learn about
+type Notification struct {
+	ID        bson.ObjectId `json:"id" bson:"_id,omitempty"`
+	UserID    bson.ObjectId `json:"user_id" bson:"user_id"`
+	Type      string        `json:"type" bson:"type"`
+	Actor     Follow        `json:"actor" bson:"actor"`
+	CawID     bson.ObjectId `json:"caw_id,omitempty" bson:"caw_id,omitempty"`
+	Read      bool          `json:"read" bson:"read"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+// NotificationGroup represents similar notifications presented together, e.g. "bob and 4 others followed you"
+type NotificationGroup struct {
+	Type            string          `json:"type"`
+	CawID           bson.ObjectId   `json:"caw_id,omitempty"`
+	Summary         string          `json:"summary"`
+	Count           int             `json:"count"`
+	Actors          []Follow        `json:"actors"`
+	Read            bool            `json:"read"`
+	LatestAt        time.Time       `json:"latest_at"`
+	NotificationIDs []bson.ObjectId `json:"notification_ids"`
+}
+
+// Notifications represents page of user notifications
+type Notifications struct {
+	UnreadCount   int                 `json:"unread_count"`
+	Notifications []Notification      `json:"notifications,omitempty"`
+	Groups        []NotificationGroup `json:"groups,omitempty"`
+}
+
+// GroupNotifications groups notifications with the same type and caw. Notifications are expected
+// to be sorted from the newest, groups keep that order.
+func GroupNotifications(notifications []Notification) []NotificationGroup {
+	groups := []NotificationGroup{}
+	index := map[string]int{}
+	for _, notification := range notifications {
+		key := notification.Type + "/" + notification.CawID.Hex()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, NotificationGroup{
+				Type:     notification.Type,
+				CawID:    notification.CawID,
+				Read:     true,
+				LatestAt: notification.CreatedAt,
+			})
+		}
+
+		group := &groups[i]
+		group.Count++
+		group.Read = group.Read && notification.Read
+		group.NotificationIDs = append(group.NotificationIDs, notification.ID)
+		if len(group.Actors) < maxGroupActors && !containsActor(group.Actors, notification.Actor) {
+			group.Actors = append(group.Actors, notification.Actor)
+		}
+	}
+
+	for i := range groups {
+		groups[i].Summary = groups[i].summary()
+	}
+	return groups
+}
+
+func containsActor(actors []Follow, actor Follow) bool {
+	for _, a := range actors {
+		if a.Equal(actor) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g NotificationGroup) summary() string {
+	var action string
+	switch g.Type {
+	case NotificationFollow:
+		action = "followed you"
+	case NotificationLike:
+		action = "liked your caw"
+	case NotificationReply:
+		action = "replied to your caw"
+	case NotificationMention:
+		action = "mentioned you"
+	default:
+		action = g.Type
+	}
+
+	if len(g.Actors) == 0 {
+		return action
+	}
+	switch others := g.Count - 1; {
+	case others == 0:
+		return fmt.Sprintf("%s %s", g.Actors[0].Name, action)
+	case others == 1 && len(g.Actors) > 1:
+		return fmt.Sprintf("%s and %s %s", g.Actors[0].Name, g.Actors[1].Name, action)
+	default:
+		return fmt.Sprintf("%s and %d others %s", g.Actors[0].Name, others, action)
+	}
+}
+
+// ReadNotifications represents request marking notifications as read.
+// Either IDs of notifications are listed or All is set to mark all user notifications.
+type ReadNotifications struct {
+	IDs []string `json:"ids"`
+	All bool     `json:"all"`
+}
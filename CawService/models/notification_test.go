@@ -0,0 +1,81 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestGroupNotifications(t *testing.T) {
+	now := time.Now()
+	bob := Follow{UserID: bson.NewObjectId(), Name: "bob"}
+	alice := Follow{UserID: bson.NewObjectId(), Name: "alice"}
+	cawID := bson.NewObjectId()
+
+	var follows []Notification
+	for i := 0; i < 5; i++ {
+		follows = append(follows, Notification{
+			ID:        bson.NewObjectId(),
+			Type:      NotificationFollow,
+			Actor:     Follow{UserID: bson.NewObjectId(), Name: "user"},
+			CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+		})
+	}
+	follows[0].Actor = bob
+
+	testCases := []struct {
+		Name            string
+		Notifications   []Notification
+		ExpectedSummary []string
+		ExpectedRead    []bool
+	}{
+		{
+			"SingleNotificationTest",
+			[]Notification{{Type: NotificationFollow, Actor: bob, Read: true}},
+			[]string{"bob followed you"},
+			[]bool{true},
+		},
+		{
+			"TwoActorsTest",
+			[]Notification{
+				{Type: NotificationReply, Actor: bob, CawID: cawID},
+				{Type: NotificationReply, Actor: alice, CawID: cawID, Read: true},
+			},
+			[]string{"bob and alice replied to your caw"},
+			[]bool{false},
+		},
+		{
+			"ManyFollowersTest",
+			follows,
+			[]string{"bob and 4 others followed you"},
+			[]bool{false},
+		},
+		{
+			"DifferentCawsAreNotGroupedTest",
+			[]Notification{
+				{Type: NotificationMention, Actor: bob, CawID: cawID},
+				{Type: NotificationMention, Actor: bob, CawID: bson.NewObjectId()},
+				{Type: NotificationFollow, Actor: alice},
+			},
+			[]string{"bob mentioned you", "bob mentioned you", "alice followed you"},
+			[]bool{false, false, false},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		groups := GroupNotifications(testCase.Notifications)
+		if len(groups) != len(testCase.ExpectedSummary) {
+			t.Fatalf("Groups count is %d expected %d", len(groups), len(testCase.ExpectedSummary))
+		}
+		for i, group := range groups {
+			if group.Summary != testCase.ExpectedSummary[i] {
+				t.Errorf("Group summary is %q expected %q", group.Summary, testCase.ExpectedSummary[i])
+			}
+			if group.Read != testCase.ExpectedRead[i] {
+				t.Errorf("Group read is %t expected %t", group.Read, testCase.ExpectedRead[i])
+			}
+		}
+	}
+}
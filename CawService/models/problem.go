@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is media type of problem details responses
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix prefixes problem codes to build problem type URIs
+const problemTypePrefix = "urn:caw:problem:"
+
+// Stable problem codes clients can rely on
+const (
+	CodeInvalidPayload         = "invalid_payload"
+	CodePayloadTooLarge        = "payload_too_large"
+	CodeInvalidParameter       = "invalid_parameter"
+	CodeValidationFailed       = "validation_failed"
+	CodeUnauthenticated        = "unauthenticated"
+	CodeForbidden              = "forbidden"
+	CodeNotOwner               = "not_owner"
+	CodeRateLimited            = "rate_limited"
+	CodeNotAcceptable          = "not_acceptable"
+	CodeUnsupportedMediaType   = "unsupported_media_type"
+	CodeNotFound               = "not_found"
+	CodeUserNotFound           = "user_not_found"
+	CodeCawNotFound            = "caw_not_found"
+	CodeParentCawNotFound      = "parent_caw_not_found"
+	CodeNotificationNotFound   = "notification_not_found"
+	CodeFollowNotFound         = "follow_not_found"
+	CodeUserExists             = "user_exists"
+	CodeFollowExists           = "follow_exists"
+	CodeSelfFollow             = "self_follow"
+	CodeEditWindowExpired      = "edit_window_expired"
+	CodeEditLimitReached       = "edit_limit_reached"
+	CodeEditConflict           = "edit_conflict"
+	CodeInvalidCredentials     = "invalid_credentials"
+	CodeAccountSuspended       = "account_suspended"
+	CodeInternalError          = "internal_error"
+	CodeTrendWindowUnsupported = "trend_window_unsupported"
+	CodeTenantNotFound         = "tenant_not_found"
+)
+
+// Field error codes
+const (
+	FieldRequired          = "required"
+	FieldUnknown           = "unknown"
+	FieldInvalid           = "invalid"
+	FieldMismatch          = "mismatch"
+	FieldTooShort          = "too_short"
+	FieldTooLong           = "too_long"
+	FieldWeak              = "weak"
+	FieldInvalidCharacters = "invalid_characters"
+)
+
+// FieldError describes why value of single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem represents RFC 7807 problem details of failed request
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem creates Problem with type and title derived from code and status
+func NewProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// NewValidationProblem creates Problem describing rejected fields of request payload
+func NewValidationProblem(errors []FieldError) Problem {
+	problem := NewProblem(http.StatusUnprocessableEntity, CodeValidationFailed, "Request payload is invalid")
+	problem.Errors = errors
+	return problem
+}
+
+// Error allows to return Problem as error
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// ToJSON converts current Problem to JSON payload
+func (p Problem) ToJSON() ([]byte, error) {
+	return json.Marshal(p)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gopkg.in/mgo.v2/bson"
+
+// Relationship represents how user relates to other user.
+// Blocked, Muted and Pending are reported as false as long as
+// blocking, muting and follow requests are not supported.
+type Relationship struct {
+	UserID     bson.ObjectId `json:"user_id"`
+	OtherID    bson.ObjectId `json:"other_id"`
+	Following  bool          `json:"following"`
+	FollowedBy bool          `json:"followed_by"`
+	Blocked    bool          `json:"blocked"`
+	Muted      bool          `json:"muted"`
+	Pending    bool          `json:"pending"`
+}
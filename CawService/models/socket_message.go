@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Message types sent by WebSocket clients
+const (
+	SocketAuth        = "auth"
+	SocketSubscribe   = "subscribe"
+	SocketUnsubscribe = "unsubscribe"
+	SocketPost        = "post"
+)
+
+// Message types sent by server to WebSocket clients
+const (
+	SocketAuthenticated = "authenticated"
+	SocketSubscribed    = "subscribed"
+	SocketUnsubscribed  = "unsubscribed"
+	SocketEvent         = "event"
+	SocketPosted        = "posted"
+	SocketError         = "error"
+)
+
+// Topics which WebSocket clients can subscribe to
+const (
+	TopicTimeline = "timeline"
+	TopicHashtag  = "hashtag"
+	TopicReplies  = "replies"
+)
+
+// SocketMessage represents message exchanged over WebSocket connection
+type SocketMessage struct {
+	Type      string          `json:"type"`
+	Token     string          `json:"token,omitempty"`
+	Topic     string          `json:"topic,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	EventID   uint64          `json:"event_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Caw       *Caw            `json:"caw,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
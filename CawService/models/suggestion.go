@@ -0,0 +1,29 @@
+package models
+
+import "fmt"
+
+// Suggestion represents account recommended to follow because users followed
+// by requestor follow it, e.g. "Followed by bob and 3 others"
+type Suggestion struct {
+	User       Follow   `json:"user" bson:"user"`
+	Score      int      `json:"score" bson:"score"`
+	FollowedBy []Follow `json:"followed_by" bson:"followed_by"`
+	Reason     string   `json:"reason" bson:"-"`
+}
+
+// Summary describes why user is suggested. Score is number of followed users following suggested user.
+func (s Suggestion) Summary() string {
+	if len(s.FollowedBy) == 0 {
+		return "Suggested for you"
+	}
+	switch others := s.Score - 1; {
+	case others <= 0:
+		return fmt.Sprintf("Followed by %s", s.FollowedBy[0].Name)
+	case others == 1 && len(s.FollowedBy) > 1:
+		return fmt.Sprintf("Followed by %s and %s", s.FollowedBy[0].Name, s.FollowedBy[1].Name)
+	case others == 1:
+		return fmt.Sprintf("Followed by %s and 1 other", s.FollowedBy[0].Name)
+	default:
+		return fmt.Sprintf("Followed by %s and %d others", s.FollowedBy[0].Name, others)
+	}
+}
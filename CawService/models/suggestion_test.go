@@ -0,0 +1,41 @@
+package models
+
+import "testing"
+
+func TestSuggestionSummary(t *testing.T) {
+	bob := Follow{Name: "bob"}
+	alice := Follow{Name: "alice"}
+	var testCases = []struct {
+		Name            string
+		Suggestion      Suggestion
+		ExpectedSummary string
+	}{
+		{
+			Name:            "SingleFollowerTest",
+			Suggestion:      Suggestion{Score: 1, FollowedBy: []Follow{bob}},
+			ExpectedSummary: "Followed by bob",
+		},
+		{
+			Name:            "TwoFollowersTest",
+			Suggestion:      Suggestion{Score: 2, FollowedBy: []Follow{bob, alice}},
+			ExpectedSummary: "Followed by bob and alice",
+		},
+		{
+			Name:            "ManyFollowersTest",
+			Suggestion:      Suggestion{Score: 4, FollowedBy: []Follow{bob, alice}},
+			ExpectedSummary: "Followed by bob and 3 others",
+		},
+		{
+			Name:            "NoFollowersTest",
+			Suggestion:      Suggestion{},
+			ExpectedSummary: "Suggested for you",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		if summary := testCase.Suggestion.Summary(); summary != testCase.ExpectedSummary {
+			t.Errorf("Summaries are different expected %s, given %s", testCase.ExpectedSummary, summary)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Trend represents hashtag usage in time window compared to its baseline
+type Trend struct {
+	Hashtag  string  `json:"hashtag"`
+	Count    int     `json:"count"`
+	Baseline float64 `json:"baseline"`
+	Velocity float64 `json:"velocity"`
+}
+
+// Trends represents ranked hashtags computed for time window
+type Trends struct {
+	Window     string    `json:"window"`
+	ComputedAt time.Time `json:"computed_at"`
+	Trends     []Trend   `json:"trends"`
+}
@@ -2,10 +2,10 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"time"
 
-	valid "github.com/asaskevich/govalidator"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -29,6 +29,8 @@ type User struct {
 	FollowersCount uint64        `json:"followers_count" bson:"followers_count"`
 	FollowingCount uint64        `json:"following_count" bson:"following_count"`
 	CreatedAt      time.Time     `json:"created_at" bson:"created_at"`
+	// Suspended is set by operators, suspended user cannot authenticate
+	Suspended bool `json:"-" bson:"suspended,omitempty"`
 }
 
 // Equal return true if UserId, Name and Email are equal otherwhise false
@@ -43,20 +45,38 @@ func (u User) ToPublic() PublicUser {
 	return PublicUser{User: u}
 }
 
-// IsValid returns false if name, email, password is empty or email is in wrong format
+// IsValid returns false if user violates any validation rule with default limits
 func (u User) IsValid() bool {
-	if u.Name == "" || u.Email == "" || u.Password == "" || !valid.IsEmail(u.Email) {
-		return false
-	}
+	return len(u.Validate(DefaultLimits)) == 0
+}
 
-	return true
+// Validate returns all violations of name, email and password rules
+func (u User) Validate(limits Limits) []FieldError {
+	limits = limits.WithDefaults()
+	return Validate(
+		Field{Name: "name", Value: u.Name, Rules: []Rule{
+			Required("Name is required"),
+			MinLength(limits.NameMinLength, fmt.Sprintf("Name has to have at least %d characters", limits.NameMinLength)),
+			MaxLength(limits.NameMaxLength, fmt.Sprintf("Name can have at most %d characters", limits.NameMaxLength)),
+			// names have to be mentionable in caws
+			Charset(isMentionRune, "Name can contain only letters, digits and underscores"),
+		}},
+		Field{Name: "email", Value: u.Email, Rules: []Rule{
+			Required("Email is required"),
+			Email("Email has wrong format"),
+		}},
+		Field{Name: "password", Value: u.Password, Rules: []Rule{
+			Required("Password is required"),
+			MinLength(limits.PasswordMinLength, fmt.Sprintf("Password has to have at least %d characters", limits.PasswordMinLength)),
+			StrongPassword("Password has to contain a letter and a digit"),
+		}},
+	)
 }
 
 // UserFromJson parse JSON payload to User model
 func UserFromJSON(jUser io.Reader) (*User, error) {
 	var user User
-	decoder := json.NewDecoder(jUser)
-	if err := decoder.Decode(&user); err != nil {
+	if err := DecodeJSON(jUser, &user); err != nil {
 		return nil, err
 	}
 
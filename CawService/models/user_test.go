@@ -14,7 +14,7 @@ func TestUserModelValidation(t *testing.T) {
 	}{
 		{
 			"ValidUserTest",
-			User{Name: "user", Email: "user@email.com", Password: "foobar"},
+			User{Name: "user", Email: "user@email.com", Password: "foobar12"},
 			true,
 		},
 		{
@@ -82,3 +82,71 @@ func TestUserModelEquality(t *testing.T) {
 		}
 	}
 }
+
+func TestUserModelFieldErrors(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		UserModel      User
+		ExpectedFields []string
+		ExpectedCodes  []string
+	}{
+		{"ValidUserTest", User{Name: "user_1", Email: "user@email.com", Password: "foobar12"}, nil, nil},
+		{
+			"EmptyUserTest",
+			User{},
+			[]string{"name", "email", "password"},
+			[]string{FieldRequired, FieldRequired, FieldRequired},
+		},
+		{
+			"InvalidEmailTest",
+			User{Name: "user", Email: "useremail.com", Password: "foobar12"},
+			[]string{"email"},
+			[]string{FieldInvalid},
+		},
+		{
+			"ShortNameAndPasswordTest",
+			User{Name: "us", Email: "user@email.com", Password: "foo1"},
+			[]string{"name", "password"},
+			[]string{FieldTooShort, FieldTooShort},
+		},
+		{
+			"LongNameTest",
+			User{Name: "user_with_name_longer_than_limit", Email: "user@email.com", Password: "foobar12"},
+			[]string{"name"},
+			[]string{FieldTooLong},
+		},
+		{
+			"NameCharsetTest",
+			User{Name: "user name", Email: "user@email.com", Password: "foobar12"},
+			[]string{"name"},
+			[]string{FieldInvalidCharacters},
+		},
+		{
+			"WeakPasswordTest",
+			User{Name: "user", Email: "user@email.com", Password: "foobarbaz"},
+			[]string{"password"},
+			[]string{FieldWeak},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		errors := testCase.UserModel.Validate(DefaultLimits)
+		if len(errors) != len(testCase.ExpectedFields) {
+			t.Fatalf("Field errors count is %d expected %d: %v", len(errors), len(testCase.ExpectedFields), errors)
+		}
+		for i, err := range errors {
+			if err.Field != testCase.ExpectedFields[i] || err.Code != testCase.ExpectedCodes[i] {
+				t.Errorf("Field error is %v expected %s %s", err, testCase.ExpectedFields[i], testCase.ExpectedCodes[i])
+			}
+		}
+	}
+}
+
+func TestUserModelConfiguredLimits(t *testing.T) {
+	user := User{Name: "user", Email: "user@email.com", Password: "foobar12"}
+	errors := user.Validate(Limits{NameMinLength: 5, PasswordMinLength: 10})
+	if len(errors) != 2 || errors[0].Field != "name" || errors[1].Field != "password" {
+		t.Fatalf("Configured limits should be applied, given %v", errors)
+	}
+}
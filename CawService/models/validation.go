@@ -0,0 +1,135 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	valid "github.com/asaskevich/govalidator"
+)
+
+// Limits configures validation rules of payloads
+type Limits struct {
+	NameMinLength     int
+	NameMaxLength     int
+	PasswordMinLength int
+	CawMaxLength      int
+}
+
+// DefaultLimits are used for limits which are not configured
+var DefaultLimits = Limits{
+	NameMinLength:     3,
+	NameMaxLength:     30,
+	PasswordMinLength: 8,
+	CawMaxLength:      280,
+}
+
+// WithDefaults returns current Limits with not configured values taken from DefaultLimits
+func (l Limits) WithDefaults() Limits {
+	if l.NameMinLength <= 0 {
+		l.NameMinLength = DefaultLimits.NameMinLength
+	}
+	if l.NameMaxLength <= 0 {
+		l.NameMaxLength = DefaultLimits.NameMaxLength
+	}
+	if l.PasswordMinLength <= 0 {
+		l.PasswordMinLength = DefaultLimits.PasswordMinLength
+	}
+	if l.CawMaxLength <= 0 {
+		l.CawMaxLength = DefaultLimits.CawMaxLength
+	}
+	return l
+}
+
+// Rule checks value of a field. Rule returns false with code and message describing violation.
+type Rule func(value string) (ok bool, code string, message string)
+
+// Field binds rules to value of a named field
+type Field struct {
+	Name  string
+	Value string
+	Rules []Rule
+}
+
+// Validate checks all fields and returns every violation. Rules of a field are checked
+// until the first violation, so empty value is reported only as required.
+func Validate(fields ...Field) []FieldError {
+	var errors []FieldError
+	for _, field := range fields {
+		for _, rule := range field.Rules {
+			if ok, code, message := rule(field.Value); !ok {
+				errors = append(errors, FieldError{Field: field.Name, Code: code, Message: message})
+				break
+			}
+		}
+	}
+	return errors
+}
+
+// Required rejects empty and blank values
+func Required(message string) Rule {
+	return func(value string) (bool, string, string) {
+		return strings.TrimSpace(value) != "", FieldRequired, message
+	}
+}
+
+// MinLength rejects values shorter than min characters
+func MinLength(min int, message string) Rule {
+	return func(value string) (bool, string, string) {
+		return utf8.RuneCountInString(value) >= min, FieldTooShort, message
+	}
+}
+
+// MaxLength rejects values longer than max characters
+func MaxLength(max int, message string) Rule {
+	return func(value string) (bool, string, string) {
+		return utf8.RuneCountInString(value) <= max, FieldTooLong, message
+	}
+}
+
+// OneOf rejects values which are not one of allowed
+func OneOf(allowed []string, message string) Rule {
+	return func(value string) (bool, string, string) {
+		for _, a := range allowed {
+			if value == a {
+				return true, "", ""
+			}
+		}
+		return false, FieldInvalid, message
+	}
+}
+
+// Email rejects values which are not email addresses
+func Email(message string) Rule {
+	return func(value string) (bool, string, string) {
+		return valid.IsEmail(value), FieldInvalid, message
+	}
+}
+
+// Charset rejects values containing runes not accepted by isAllowed
+func Charset(isAllowed func(r rune) bool, message string) Rule {
+	return func(value string) (bool, string, string) {
+		for _, r := range value {
+			if !isAllowed(r) {
+				return false, FieldInvalidCharacters, message
+			}
+		}
+		return true, "", ""
+	}
+}
+
+// NoControlCharacters rejects values with control characters other than new line
+func NoControlCharacters(message string) Rule {
+	return Charset(func(r rune) bool {
+		return r == '\n' || !unicode.IsControl(r)
+	}, message)
+}
+
+// StrongPassword rejects passwords without at least one letter and one digit
+func StrongPassword(message string) Rule {
+	return func(value string) (bool, string, string) {
+		hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+		hasDigit := strings.IndexFunc(value, unicode.IsDigit) >= 0
+		return hasLetter && hasDigit, FieldWeak, message
+	}
+}
@@ -0,0 +1,185 @@
+package reconciler
+
+import (
+	"Caw/UserService/infrastructure"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultInterval  = time.Hour
+	defaultBatchSize = 100
+)
+
+var (
+	ErrStopped    = errors.New("Reconciler stopped")
+	ErrInProgress = errors.New("Reconciliation in progress")
+)
+
+var (
+	// metrics exposes progress of reconciliation of every database at /debug/vars
+	metrics   = expvar.NewMap("counter_reconciler")
+	metricsMu sync.Mutex
+)
+
+// databaseMetrics returns metrics of database, reconcilers of the same database share them
+func databaseMetrics(database string) *expvar.Map {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if databaseMetrics, ok := metrics.Get(database).(*expvar.Map); ok {
+		return databaseMetrics
+	}
+	databaseMetrics := new(expvar.Map).Init()
+	metrics.Set(database, databaseMetrics)
+	return databaseMetrics
+}
+
+// Report summarizes one reconciliation pass
+type Report struct {
+	UsersScanned int
+	UsersFixed   int
+	CawsScanned  int
+	CawsFixed    int
+	Drift        int
+	Duration     time.Duration
+}
+
+// Reconciler periodically recomputes denormalized counters in batches
+// and fixes documents whose counters drifted from source collections
+type Reconciler struct {
+	factory   infrastructure.DataStoreFactory
+	interval  time.Duration
+	batchSize int
+	running   int32
+	stop      chan struct{}
+	stopOnce  sync.Once
+	metrics   *expvar.Map
+	logger    *logrus.Logger
+}
+
+// New creates Reconciler of database running reconciliation every interval.
+// Progress is published in metrics under database name.
+func New(database string, factory infrastructure.DataStoreFactory, interval time.Duration, logger *logrus.Logger) *Reconciler {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Reconciler{
+		factory:   factory,
+		interval:  interval,
+		batchSize: defaultBatchSize,
+		stop:      make(chan struct{}),
+		metrics:   databaseMetrics(database),
+		logger:    logger,
+	}
+}
+
+// Run reconciles counters every interval until Stop is called
+func (r *Reconciler) Run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			report, err := r.Reconcile()
+			if err == ErrStopped {
+				return
+			}
+			if err != nil {
+				r.logger.Errorf("Cannot reconcile counters. err: %s", err)
+				continue
+			}
+			r.logger.Infof("Counters reconciled. %+v", *report)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop stops Run and interrupts reconciliation in progress between batches
+func (r *Reconciler) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// Reconcile runs one reconciliation pass over all users and caws
+func (r *Reconciler) Reconcile() (*Report, error) {
+	if !atomic.CompareAndSwapInt32(&r.running, 0, 1) {
+		return nil, ErrInProgress
+	}
+	defer atomic.StoreInt32(&r.running, 0)
+
+	start := time.Now()
+	r.metrics.Add("runs", 1)
+	r.setInt("in_progress", 1)
+	defer r.setInt("in_progress", 0)
+
+	var report Report
+	err := r.reconcileAll("users", func(ds infrastructure.CounterDataStore, afterID string) (*infrastructure.ReconcileResult, error) {
+		return ds.ReconcileUsers(afterID, r.batchSize)
+	}, &report.UsersScanned, &report.UsersFixed, &report.Drift)
+	if err != nil {
+		r.metrics.Add("errors", 1)
+		return nil, err
+	}
+	err = r.reconcileAll("caws", func(ds infrastructure.CounterDataStore, afterID string) (*infrastructure.ReconcileResult, error) {
+		return ds.ReconcileCaws(afterID, r.batchSize)
+	}, &report.CawsScanned, &report.CawsFixed, &report.Drift)
+	if err != nil {
+		r.metrics.Add("errors", 1)
+		return nil, err
+	}
+
+	report.Duration = time.Since(start)
+	r.setInt("last_run_unix", time.Now().Unix())
+	r.setInt("last_run_duration_ms", int64(report.Duration/time.Millisecond))
+	r.setInt("last_run_drift", int64(report.Drift))
+	return &report, nil
+}
+
+type reconcileFunc func(ds infrastructure.CounterDataStore, afterID string) (*infrastructure.ReconcileResult, error)
+
+// reconcileAll reconciles collection batch by batch, progress is published in metrics
+func (r *Reconciler) reconcileAll(collection string, reconcile reconcileFunc, scanned, fixed, drift *int) error {
+	position := new(expvar.String)
+	r.metrics.Set(collection+"_position", position)
+
+	afterID := ""
+	for {
+		select {
+		case <-r.stop:
+			return ErrStopped
+		default:
+		}
+
+		ds := r.factory.CreateCounterDataStore()
+		result, err := reconcile(ds, afterID)
+		ds.Close()
+		if err != nil {
+			return err
+		}
+
+		afterID = result.LastID
+		*scanned += result.Scanned
+		*fixed += result.Fixed
+		*drift += result.Drift
+		position.Set(afterID)
+		r.metrics.Add(collection+"_scanned", int64(result.Scanned))
+		r.metrics.Add(collection+"_fixed", int64(result.Fixed))
+		r.metrics.Add("drift", int64(result.Drift))
+		if result.Done {
+			return nil
+		}
+	}
+}
+
+func (r *Reconciler) setInt(key string, value int64) {
+	v := new(expvar.Int)
+	v.Set(value)
+	r.metrics.Set(key, v)
+}
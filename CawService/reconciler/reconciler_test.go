@@ -0,0 +1,121 @@
+package reconciler
+
+import (
+	"Caw/UserService/infrastructure"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// counterDataStoreMock pretends collections of given size where every third document drifted by 2
+type counterDataStoreMock struct {
+	users   int
+	caws    int
+	err     error
+	onBatch func()
+}
+
+func (m *counterDataStoreMock) reconcile(size int, afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.onBatch != nil {
+		m.onBatch()
+	}
+	start := 0
+	if afterID != "" {
+		start, _ = strconv.Atoi(afterID)
+	}
+	result := &infrastructure.ReconcileResult{LastID: afterID}
+	for i := start + 1; i <= size && result.Scanned < batchSize; i++ {
+		result.LastID = strconv.Itoa(i)
+		result.Scanned++
+		if i%3 == 0 {
+			result.Fixed++
+			result.Drift += 2
+		}
+	}
+	result.Done = result.Scanned < batchSize
+	return result, nil
+}
+
+func (m *counterDataStoreMock) ReconcileUsers(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return m.reconcile(m.users, afterID, batchSize)
+}
+
+func (m *counterDataStoreMock) ReconcileCaws(afterID string, batchSize int) (*infrastructure.ReconcileResult, error) {
+	return m.reconcile(m.caws, afterID, batchSize)
+}
+
+func (m *counterDataStoreMock) Close() {
+}
+
+type dataStoreFactoryMock struct {
+	infrastructure.DataStoreFactory
+	counterDataStore *counterDataStoreMock
+}
+
+func (m dataStoreFactoryMock) CreateCounterDataStore() infrastructure.CounterDataStore {
+	return m.counterDataStore
+}
+
+func TestReconcile(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Users          int
+		Caws           int
+		ExpectedReport Report
+	}{
+		{"EmptyTest", 0, 0, Report{}},
+		{"SingleBatchTest", 5, 3, Report{UsersScanned: 5, UsersFixed: 1, CawsScanned: 3, CawsFixed: 1, Drift: 4}},
+		{"ManyBatchesTest", 250, 100, Report{UsersScanned: 250, UsersFixed: 83, CawsScanned: 100, CawsFixed: 33, Drift: 232}},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		factory := dataStoreFactoryMock{counterDataStore: &counterDataStoreMock{users: testCase.Users, caws: testCase.Caws}}
+		report, err := New("test", factory, 0, logrus.New()).Reconcile()
+		assert.Nil(t, err)
+		report.Duration = 0
+		assert.Equal(t, testCase.ExpectedReport, *report)
+	}
+}
+
+func TestReconcileError(t *testing.T) {
+	factory := dataStoreFactoryMock{counterDataStore: &counterDataStoreMock{users: 10, err: errors.New("db error")}}
+	_, err := New("test", factory, 0, logrus.New()).Reconcile()
+	assert.NotNil(t, err)
+}
+
+func TestReconcileStopsBetweenBatches(t *testing.T) {
+	mock := &counterDataStoreMock{users: 1000, caws: 1000}
+	reconciler := New("test", dataStoreFactoryMock{counterDataStore: mock}, 0, logrus.New())
+	batches := 0
+	mock.onBatch = func() {
+		batches++
+		if batches == 2 {
+			reconciler.Stop()
+		}
+	}
+
+	_, err := reconciler.Reconcile()
+	assert.Equal(t, ErrStopped, err)
+	assert.Equal(t, 2, batches)
+}
+
+func TestReconcileMetricsArePerDatabase(t *testing.T) {
+	cats := New("metrics_cats", dataStoreFactoryMock{counterDataStore: &counterDataStoreMock{users: 3}}, 0, logrus.New())
+	dogs := New("metrics_dogs", dataStoreFactoryMock{counterDataStore: &counterDataStoreMock{users: 6}}, 0, logrus.New())
+	for _, reconciler := range []*Reconciler{cats, dogs, dogs} {
+		_, err := reconciler.Reconcile()
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, "1", databaseMetrics("metrics_cats").Get("runs").String())
+	assert.Equal(t, "3", databaseMetrics("metrics_cats").Get("users_scanned").String())
+	assert.Equal(t, "2", databaseMetrics("metrics_dogs").Get("runs").String())
+	assert.Equal(t, "12", databaseMetrics("metrics_dogs").Get("users_scanned").String())
+}
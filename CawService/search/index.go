@@ -0,0 +1,329 @@
+package search
+
+import (
+	"Caw/UserService/models"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PageSize is number of results returned in one page
+	PageSize = 10
+	// recencyDecay is age after which recency boost of caw drops to 1/e of its maximum
+	recencyDecay = 24 * time.Hour
+)
+
+var (
+	ErrEmptyQuery = errors.New("Query cannot be empty")
+)
+
+// CawFilter narrows caw search results to author and creation date range.
+// Zero values do not filter.
+type CawFilter struct {
+	AuthorID string
+	From     time.Time
+	To       time.Time
+}
+
+func (f CawFilter) match(caw models.Caw) bool {
+	if f.AuthorID != "" && caw.UserID.Hex() != f.AuthorID {
+		return false
+	}
+	if !f.From.IsZero() && caw.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && caw.CreatedAt.After(f.To) {
+		return false
+	}
+	return true
+}
+
+type cawDocument struct {
+	caw       models.Caw
+	positions map[string][]int
+}
+
+type userDocument struct {
+	user   models.User
+	name   string
+	tokens []string
+}
+
+// Index is embedded full-text index of public caws and users.
+// Caws are ranked by TF-IDF relevance boosted by recency,
+// users are matched by prefixes of their names.
+type Index struct {
+	mu       sync.RWMutex
+	caws     map[string]*cawDocument
+	postings map[string]map[string]bool
+	users    map[string]*userDocument
+	now      func() time.Time
+}
+
+// NewIndex creates empty Index
+func NewIndex() *Index {
+	return &Index{
+		caws:     map[string]*cawDocument{},
+		postings: map[string]map[string]bool{},
+		users:    map[string]*userDocument{},
+		now:      time.Now,
+	}
+}
+
+// IndexCaw adds or replaces caw. Caws which are not public are removed from Index.
+func (i *Index) IndexCaw(caw models.Caw) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	cawID := caw.ID.Hex()
+	i.removeCaw(cawID)
+	if caw.Visibility != "" && caw.Visibility != models.VisibilityPublic {
+		return
+	}
+
+	document := &cawDocument{caw: caw, positions: map[string][]int{}}
+	for position, token := range tokenize(caw.Message) {
+		document.positions[token] = append(document.positions[token], position)
+	}
+	for token := range document.positions {
+		if i.postings[token] == nil {
+			i.postings[token] = map[string]bool{}
+		}
+		i.postings[token][cawID] = true
+	}
+	i.caws[cawID] = document
+}
+
+// RemoveCaw removes caw with cawID
+func (i *Index) RemoveCaw(cawID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeCaw(cawID)
+}
+
+// removeCaw has to be called with locked mutex
+func (i *Index) removeCaw(cawID string) {
+	document, ok := i.caws[cawID]
+	if !ok {
+		return
+	}
+	for token := range document.positions {
+		delete(i.postings[token], cawID)
+		if len(i.postings[token]) == 0 {
+			delete(i.postings, token)
+		}
+	}
+	delete(i.caws, cawID)
+}
+
+// IndexUser adds or replaces user. Password is not kept in Index.
+func (i *Index) IndexUser(user models.User) {
+	user.Password = ""
+	name := strings.ToLower(user.Name)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.users[user.ID.Hex()] = &userDocument{user: user, name: name, tokens: tokenize(name)}
+}
+
+// RemoveUser removes user with userID together with caws of the user
+func (i *Index) RemoveUser(userID string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.users, userID)
+	for cawID, document := range i.caws {
+		if document.caw.UserID.Hex() == userID {
+			i.removeCaw(cawID)
+		}
+	}
+}
+
+type cawResult struct {
+	caw   models.Caw
+	score float64
+}
+
+// SearchCaws returns page of public caws matching query and filter, best matches first
+func (i *Index) SearchCaws(q string, filter CawFilter, page int) ([]models.Caw, error) {
+	query := ParseQuery(q)
+	if query.IsEmpty() {
+		return nil, ErrEmptyQuery
+	}
+	words := query.words()
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	now := i.now()
+	var results []cawResult
+	for cawID := range i.candidates(words) {
+		document := i.caws[cawID]
+		if !filter.match(document.caw) || !document.hasPhrases(query.Phrases) {
+			continue
+		}
+
+		var relevance float64
+		for _, word := range words {
+			frequency := float64(len(document.positions[word]))
+			idf := math.Log(1 + float64(len(i.caws))/float64(len(i.postings[word])))
+			relevance += (1 + math.Log(frequency)) * idf
+		}
+		age := now.Sub(document.caw.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+		recency := math.Exp(-float64(age) / float64(recencyDecay))
+		results = append(results, cawResult{caw: document.caw, score: relevance * (1 + recency)})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].score != results[b].score {
+			return results[a].score > results[b].score
+		}
+		return results[a].caw.CreatedAt.After(results[b].caw.CreatedAt)
+	})
+
+	caws := []models.Caw{}
+	start, end := pageBounds(len(results), page)
+	for _, result := range results[start:end] {
+		caws = append(caws, result.caw)
+	}
+	return caws, nil
+}
+
+// candidates returns IDs of caws containing all words
+func (i *Index) candidates(words []string) map[string]bool {
+	candidates := map[string]bool{}
+	for cawID := range i.postings[words[0]] {
+		candidates[cawID] = true
+	}
+	for _, word := range words[1:] {
+		for cawID := range candidates {
+			if !i.postings[word][cawID] {
+				delete(candidates, cawID)
+			}
+		}
+	}
+	return candidates
+}
+
+// hasPhrases returns true if all phrases occur in caw
+func (d *cawDocument) hasPhrases(phrases [][]string) bool {
+	for _, phrase := range phrases {
+		if !d.hasPhrase(phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *cawDocument) hasPhrase(phrase []string) bool {
+	for _, start := range d.positions[phrase[0]] {
+		found := true
+		for offset, word := range phrase[1:] {
+			if !containsPosition(d.positions[word], start+offset+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPosition(positions []int, position int) bool {
+	for _, p := range positions {
+		if p == position {
+			return true
+		}
+	}
+	return false
+}
+
+type userResult struct {
+	user  models.User
+	name  string
+	score int
+}
+
+// SearchUsers returns page of users whose names match query. Every word of query has to be
+// prefix of word of user name. Exact and whole name prefix matches are ranked first.
+func (i *Index) SearchUsers(q string, page int) ([]models.User, error) {
+	query := ParseQuery(q)
+	if query.IsEmpty() {
+		return nil, ErrEmptyQuery
+	}
+	words := query.words()
+	whole := strings.Join(words, "")
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var results []userResult
+	for _, document := range i.users {
+		if !document.hasPrefixes(words) {
+			continue
+		}
+		score := 0
+		compact := strings.Join(document.tokens, "")
+		switch {
+		case compact == whole:
+			score = 2
+		case strings.HasPrefix(compact, whole):
+			score = 1
+		}
+		results = append(results, userResult{user: document.user, name: document.name, score: score})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].score != results[b].score {
+			return results[a].score > results[b].score
+		}
+		if len(results[a].name) != len(results[b].name) {
+			return len(results[a].name) < len(results[b].name)
+		}
+		return results[a].name < results[b].name
+	})
+
+	users := []models.User{}
+	start, end := pageBounds(len(results), page)
+	for _, result := range results[start:end] {
+		users = append(users, result.user)
+	}
+	return users, nil
+}
+
+// pageBounds returns bounds of page in results of length total
+func pageBounds(total, page int) (int, int) {
+	start := page * PageSize
+	if page < 0 || start > total {
+		return total, total
+	}
+	end := start + PageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func (d *userDocument) hasPrefixes(words []string) bool {
+	for _, word := range words {
+		found := false
+		for _, token := range d.tokens {
+			if strings.HasPrefix(token, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
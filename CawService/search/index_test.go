@@ -0,0 +1,211 @@
+package search
+
+import (
+	"Caw/UserService/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestParseQuery(t *testing.T) {
+	var testCases = []struct {
+		Name          string
+		Query         string
+		ExpectedQuery Query
+	}{
+		{
+			Name:          "TermsTest",
+			Query:         "Hello, World!",
+			ExpectedQuery: Query{Terms: []string{"hello", "world"}},
+		},
+		{
+			Name:          "PhraseTest",
+			Query:         `go "big data" fun`,
+			ExpectedQuery: Query{Terms: []string{"go", "fun"}, Phrases: [][]string{{"big", "data"}}},
+		},
+		{
+			Name:          "SingleWordPhraseIsTermTest",
+			Query:         `"go"`,
+			ExpectedQuery: Query{Terms: []string{"go"}},
+		},
+		{
+			Name:          "EmptyQueryTest",
+			Query:         ` "" `,
+			ExpectedQuery: Query{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		assert.Equal(t, testCase.ExpectedQuery, ParseQuery(testCase.Query))
+	}
+}
+
+func newTestIndex(now time.Time, caws ...models.Caw) *Index {
+	index := NewIndex()
+	index.now = func() time.Time { return now }
+	for _, caw := range caws {
+		index.IndexCaw(caw)
+	}
+	return index
+}
+
+func cawMessages(caws []models.Caw) []string {
+	messages := []string{}
+	for _, caw := range caws {
+		messages = append(messages, caw.Message)
+	}
+	return messages
+}
+
+func TestSearchCaws(t *testing.T) {
+	now := time.Date(2017, 8, 1, 12, 0, 0, 0, time.UTC)
+	author := bson.NewObjectId()
+	caws := []models.Caw{
+		{ID: bson.NewObjectId(), UserID: author, Message: "big data is big", CreatedAt: now.Add(-time.Hour)},
+		{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "data is big", CreatedAt: now},
+		{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "old big data", CreatedAt: now.Add(-30 * 24 * time.Hour)},
+		{ID: bson.NewObjectId(), UserID: author, Message: "secret big data", Visibility: models.VisibilityFollowers, CreatedAt: now},
+	}
+	index := newTestIndex(now, caws...)
+
+	var testCases = []struct {
+		Name             string
+		Query            string
+		Filter           CawFilter
+		ExpectedMessages []string
+	}{
+		{
+			Name:             "RelevanceAndRecencyRankingTest",
+			Query:            "big data",
+			ExpectedMessages: []string{"big data is big", "data is big", "old big data"},
+		},
+		{
+			Name:             "PhraseTest",
+			Query:            `"big data"`,
+			ExpectedMessages: []string{"big data is big", "old big data"},
+		},
+		{
+			Name:             "AuthorFilterTest",
+			Query:            "data",
+			Filter:           CawFilter{AuthorID: author.Hex()},
+			ExpectedMessages: []string{"big data is big"},
+		},
+		{
+			Name:             "DateRangeFilterTest",
+			Query:            "data",
+			Filter:           CawFilter{From: now.Add(-48 * time.Hour), To: now.Add(-time.Minute)},
+			ExpectedMessages: []string{"big data is big"},
+		},
+		{
+			Name:             "NoMatchTest",
+			Query:            "rust",
+			ExpectedMessages: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		result, err := index.SearchCaws(testCase.Query, testCase.Filter, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.ExpectedMessages, cawMessages(result))
+	}
+
+	_, err := index.SearchCaws(" ", CawFilter{}, 0)
+	assert.Equal(t, ErrEmptyQuery, err)
+}
+
+func TestSearchCawsKeepsIndexInSync(t *testing.T) {
+	now := time.Now()
+	caw := models.Caw{ID: bson.NewObjectId(), Message: "hello world", CreatedAt: now}
+	index := newTestIndex(now, caw)
+
+	caw.Visibility = models.VisibilityMentioned
+	index.IndexCaw(caw)
+	result, _ := index.SearchCaws("hello", CawFilter{}, 0)
+	assert.Empty(t, result, "caw which is not public anymore has to be removed")
+
+	caw.Visibility = models.VisibilityPublic
+	index.IndexCaw(caw)
+	index.RemoveCaw(caw.ID.Hex())
+	result, _ = index.SearchCaws("hello", CawFilter{}, 0)
+	assert.Empty(t, result, "removed caw cannot be found")
+	assert.Empty(t, index.postings)
+}
+
+func TestRemoveUserRemovesCaws(t *testing.T) {
+	now := time.Now()
+	user := models.User{ID: bson.NewObjectId(), Name: "bob"}
+	caw := models.Caw{ID: bson.NewObjectId(), UserID: user.ID, Message: "hello world", CreatedAt: now}
+	other := models.Caw{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Message: "hello there", CreatedAt: now}
+	index := newTestIndex(now, caw, other)
+	index.IndexUser(user)
+
+	index.RemoveUser(user.ID.Hex())
+	result, _ := index.SearchCaws("hello", CawFilter{}, 0)
+	assert.Equal(t, []models.Caw{other}, result, "caws of removed user cannot be found")
+	users, _ := index.SearchUsers("bob", 0)
+	assert.Empty(t, users)
+}
+
+func TestSearchCawsPagination(t *testing.T) {
+	now := time.Now()
+	index := newTestIndex(now)
+	for i := 0; i < PageSize+2; i++ {
+		index.IndexCaw(models.Caw{ID: bson.NewObjectId(), Message: "caw", CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+	}
+
+	result, _ := index.SearchCaws("caw", CawFilter{}, 1)
+	assert.Equal(t, 2, len(result))
+	result, _ = index.SearchCaws("caw", CawFilter{}, 2)
+	assert.Equal(t, 0, len(result))
+}
+
+func TestSearchUsers(t *testing.T) {
+	index := NewIndex()
+	names := []string{"anycmon", "any_body", "Anna", "bob"}
+	for _, name := range names {
+		index.IndexUser(models.User{ID: bson.NewObjectId(), Name: name, Password: "secret"})
+	}
+
+	var testCases = []struct {
+		Name          string
+		Query         string
+		ExpectedNames []string
+	}{
+		{
+			Name:          "PrefixTest",
+			Query:         "an",
+			ExpectedNames: []string{"Anna", "anycmon", "any_body"},
+		},
+		{
+			Name:          "ExactMatchFirstTest",
+			Query:         "anna",
+			ExpectedNames: []string{"Anna"},
+		},
+		{
+			Name:          "WordOfNamePrefixTest",
+			Query:         "bo",
+			ExpectedNames: []string{"bob", "any_body"},
+		},
+		{
+			Name:          "NoMatchTest",
+			Query:         "carol",
+			ExpectedNames: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		users, err := index.SearchUsers(testCase.Query, 0)
+		assert.Nil(t, err)
+		userNames := []string{}
+		for _, user := range users {
+			userNames = append(userNames, user.Name)
+			assert.Empty(t, user.Password)
+		}
+		assert.Equal(t, testCase.ExpectedNames, userNames)
+	}
+}
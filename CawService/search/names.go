@@ -0,0 +1,129 @@
+package search
+
+import (
+	"Caw/UserService/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCompletionCandidates limits number of users ranked for one prefix
+const maxCompletionCandidates = 1000
+
+type nameEntry struct {
+	name string
+	user models.User
+}
+
+// NameIndex keeps users sorted by lower case name for fast prefix lookups
+type NameIndex struct {
+	mu      sync.RWMutex
+	entries []nameEntry
+	names   map[string]string
+}
+
+// NewNameIndex creates empty NameIndex
+func NewNameIndex() *NameIndex {
+	return &NameIndex{names: map[string]string{}}
+}
+
+// Put adds user or replaces user with the same ID, e.g. after rename.
+// Password is not kept in NameIndex.
+func (n *NameIndex) Put(user models.User) {
+	user.Password = ""
+	entry := nameEntry{name: strings.ToLower(user.Name), user: user}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.remove(user.ID.Hex())
+	i := n.search(entry.name)
+	n.entries = append(n.entries, nameEntry{})
+	copy(n.entries[i+1:], n.entries[i:])
+	n.entries[i] = entry
+	n.names[user.ID.Hex()] = entry.name
+}
+
+// Remove removes user with userID
+func (n *NameIndex) Remove(userID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.remove(userID)
+}
+
+// AddFollowers changes followers count of user with userID by delta
+func (n *NameIndex) AddFollowers(userID string, delta int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	i, ok := n.find(userID)
+	if !ok {
+		return
+	}
+	count := int64(n.entries[i].user.FollowersCount) + int64(delta)
+	if count < 0 {
+		count = 0
+	}
+	n.entries[i].user.FollowersCount = uint64(count)
+}
+
+// Complete returns up to limit users whose names start with prefix. Users followed
+// by requestor are ranked first, then users with more followers.
+func (n *NameIndex) Complete(prefix string, following map[string]bool, limit int) []models.User {
+	prefix = strings.ToLower(prefix)
+
+	n.mu.RLock()
+	var candidates []models.User
+	for i := n.search(prefix); i < len(n.entries) && len(candidates) < maxCompletionCandidates; i++ {
+		if !strings.HasPrefix(n.entries[i].name, prefix) {
+			break
+		}
+		candidates = append(candidates, n.entries[i].user)
+	}
+	n.mu.RUnlock()
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		followedA, followedB := following[candidates[a].ID.Hex()], following[candidates[b].ID.Hex()]
+		if followedA != followedB {
+			return followedA
+		}
+		if candidates[a].FollowersCount != candidates[b].FollowersCount {
+			return candidates[a].FollowersCount > candidates[b].FollowersCount
+		}
+		return len(candidates[a].Name) < len(candidates[b].Name)
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// search returns position of first entry not less than name, has to be called with locked mutex
+func (n *NameIndex) search(name string) int {
+	return sort.Search(len(n.entries), func(i int) bool {
+		return n.entries[i].name >= name
+	})
+}
+
+// find returns position of user with userID, has to be called with locked mutex
+func (n *NameIndex) find(userID string) (int, bool) {
+	name, ok := n.names[userID]
+	if !ok {
+		return 0, false
+	}
+	for i := n.search(name); i < len(n.entries) && n.entries[i].name == name; i++ {
+		if n.entries[i].user.ID.Hex() == userID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// remove has to be called with locked mutex
+func (n *NameIndex) remove(userID string) {
+	i, ok := n.find(userID)
+	if !ok {
+		return
+	}
+	n.entries = append(n.entries[:i], n.entries[i+1:]...)
+	delete(n.names, userID)
+}
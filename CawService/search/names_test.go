@@ -0,0 +1,92 @@
+package search
+
+import (
+	"Caw/UserService/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func userNames(users []models.User) []string {
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names
+}
+
+func TestNameIndexComplete(t *testing.T) {
+	index := NewNameIndex()
+	followed := bson.NewObjectId()
+	users := []models.User{
+		{ID: bson.NewObjectId(), Name: "anna", FollowersCount: 1},
+		{ID: bson.NewObjectId(), Name: "Andrew", FollowersCount: 100},
+		{ID: followed, Name: "anycmon"},
+		{ID: bson.NewObjectId(), Name: "bob", FollowersCount: 1000},
+	}
+	for _, user := range users {
+		index.Put(user)
+	}
+
+	var testCases = []struct {
+		Name          string
+		Prefix        string
+		Following     map[string]bool
+		Limit         int
+		ExpectedNames []string
+	}{
+		{
+			Name:          "FollowersCountRankingTest",
+			Prefix:        "an",
+			Limit:         10,
+			ExpectedNames: []string{"Andrew", "anna", "anycmon"},
+		},
+		{
+			Name:          "FollowedUsersFirstTest",
+			Prefix:        "AN",
+			Following:     map[string]bool{followed.Hex(): true},
+			Limit:         10,
+			ExpectedNames: []string{"anycmon", "Andrew", "anna"},
+		},
+		{
+			Name:          "LimitTest",
+			Prefix:        "a",
+			Limit:         1,
+			ExpectedNames: []string{"Andrew"},
+		},
+		{
+			Name:          "NoMatchTest",
+			Prefix:        "c",
+			Limit:         10,
+			ExpectedNames: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Log(testCase.Name)
+		assert.Equal(t, testCase.ExpectedNames, userNames(index.Complete(testCase.Prefix, testCase.Following, testCase.Limit)))
+	}
+}
+
+func TestNameIndexUpdates(t *testing.T) {
+	index := NewNameIndex()
+	user := models.User{ID: bson.NewObjectId(), Name: "anycmon", Password: "secret"}
+	other := models.User{ID: bson.NewObjectId(), Name: "anna"}
+	index.Put(user)
+	index.Put(other)
+	assert.Empty(t, index.Complete("any", nil, 10)[0].Password)
+
+	user.Name = "bob"
+	index.Put(user)
+	assert.Equal(t, []string{"anna"}, userNames(index.Complete("an", nil, 10)))
+	assert.Equal(t, []string{"bob"}, userNames(index.Complete("b", nil, 10)))
+
+	index.AddFollowers(other.ID.Hex(), 2)
+	index.AddFollowers(other.ID.Hex(), -1)
+	assert.Equal(t, uint64(1), index.Complete("an", nil, 10)[0].FollowersCount)
+
+	index.Remove(user.ID.Hex())
+	assert.Equal(t, []string{}, userNames(index.Complete("b", nil, 10)))
+	assert.Equal(t, 1, len(index.entries))
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query represents parsed search query. Every term and every phrase has to match.
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+// ParseQuery splits q into terms and "quoted phrases"
+func ParseQuery(q string) Query {
+	var query Query
+	for i, part := range strings.Split(q, `"`) {
+		tokens := tokenize(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		// odd parts are enclosed in quotes
+		if i%2 == 1 && len(tokens) > 1 {
+			query.Phrases = append(query.Phrases, tokens)
+			continue
+		}
+		query.Terms = append(query.Terms, tokens...)
+	}
+	return query
+}
+
+// IsEmpty returns true if query has neither terms nor phrases
+func (q Query) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
+
+// words returns distinct terms and words of phrases
+func (q Query) words() []string {
+	seen := map[string]bool{}
+	var words []string
+	add := func(word string) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	for _, term := range q.Terms {
+		add(term)
+	}
+	for _, phrase := range q.Phrases {
+		for _, word := range phrase {
+			add(word)
+		}
+	}
+	return words
+}
+
+// tokenize splits text into lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
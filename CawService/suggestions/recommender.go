@@ -0,0 +1,131 @@
+package suggestions
+
+import (
+	"Caw/UserService/models"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultInterval = 10 * time.Minute
+	// idleTimeout is time after which suggestions of user who does not ask for them are not refreshed anymore
+	idleTimeout = 24 * time.Hour
+)
+
+// ComputeFunc computes suggestions of user with userID
+type ComputeFunc func(userID string) ([]models.Suggestion, error)
+
+type entry struct {
+	suggestions []models.Suggestion
+	requestedAt time.Time
+}
+
+// Recommender caches suggestions of users and periodically recomputes them
+// for users who asked for suggestions recently
+type Recommender struct {
+	compute  ComputeFunc
+	interval time.Duration
+	mu       sync.Mutex
+	cache    map[string]*entry
+	stop     chan struct{}
+	stopOnce sync.Once
+	now      func() time.Time
+	logger   *logrus.Logger
+}
+
+// NewRecommender creates Recommender refreshing cached suggestions every interval
+func NewRecommender(compute ComputeFunc, interval time.Duration, logger *logrus.Logger) *Recommender {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Recommender{
+		compute:  compute,
+		interval: interval,
+		cache:    map[string]*entry{},
+		stop:     make(chan struct{}),
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// Get returns cached suggestions of user or computes them if user is not cached
+func (r *Recommender) Get(userID string) ([]models.Suggestion, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[userID]
+	if ok {
+		cached.requestedAt = r.now()
+		suggestions := cached.suggestions
+		r.mu.Unlock()
+		return suggestions, nil
+	}
+	r.mu.Unlock()
+
+	suggestions, err := r.compute(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cache[userID] = &entry{suggestions: suggestions, requestedAt: r.now()}
+	r.mu.Unlock()
+	return suggestions, nil
+}
+
+// Invalidate drops cached suggestions of user, e.g. after user followed someone
+func (r *Recommender) Invalidate(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cache, userID)
+}
+
+// Run refreshes cached suggestions until Stop is called
+func (r *Recommender) Run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.refresh()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop stops Run
+func (r *Recommender) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+}
+
+// refresh evicts idle users and recomputes suggestions of the remaining ones
+func (r *Recommender) refresh() {
+	now := r.now()
+	var userIDs []string
+	r.mu.Lock()
+	for userID, cached := range r.cache {
+		if now.Sub(cached.requestedAt) > idleTimeout {
+			delete(r.cache, userID)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+	r.mu.Unlock()
+
+	for _, userID := range userIDs {
+		suggestions, err := r.compute(userID)
+		if err != nil {
+			r.logger.Errorf("Cannot compute suggestions. userID: %s, err: %s", userID, err)
+			continue
+		}
+		r.mu.Lock()
+		// entry could be invalidated during computation, it is recomputed on next request then
+		if cached, ok := r.cache[userID]; ok {
+			cached.suggestions = suggestions
+		}
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,98 @@
+package suggestions
+
+import (
+	"Caw/UserService/models"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type computeMock struct {
+	calls map[string]int
+	err   error
+}
+
+func (m *computeMock) compute(userID string) ([]models.Suggestion, error) {
+	m.calls[userID]++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []models.Suggestion{{Score: m.calls[userID]}}, nil
+}
+
+func TestRecommenderCachesSuggestions(t *testing.T) {
+	mock := &computeMock{calls: map[string]int{}}
+	recommender := NewRecommender(mock.compute, time.Minute, logrus.New())
+
+	suggestions, err := recommender.Get("user")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, suggestions[0].Score)
+
+	suggestions, err = recommender.Get("user")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, suggestions[0].Score, "cached suggestions have to be returned")
+	assert.Equal(t, 1, mock.calls["user"])
+
+	recommender.Invalidate("user")
+	suggestions, err = recommender.Get("user")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, suggestions[0].Score, "invalidated suggestions have to be recomputed")
+}
+
+func TestRecommenderRefresh(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	mock := &computeMock{calls: map[string]int{}}
+	recommender := NewRecommender(mock.compute, time.Minute, logrus.New())
+	recommender.now = func() time.Time { return now }
+
+	recommender.Get("active")
+	recommender.Get("idle")
+	now = now.Add(idleTimeout / 2)
+	recommender.Get("active")
+	now = now.Add(idleTimeout)
+	recommender.refresh()
+
+	assert.Equal(t, 2, mock.calls["active"])
+	assert.Equal(t, 1, mock.calls["idle"])
+	assert.Equal(t, 1, len(recommender.cache), "idle user has to be evicted")
+
+	suggestions, _ := recommender.Get("active")
+	assert.Equal(t, 2, suggestions[0].Score)
+
+	// failed computation keeps previous suggestions
+	mock.err = errors.New("StorageInternalError")
+	recommender.refresh()
+	suggestions, _ = recommender.Get("active")
+	assert.Equal(t, 2, suggestions[0].Score)
+}
+
+func TestRecommenderGetError(t *testing.T) {
+	mock := &computeMock{calls: map[string]int{}, err: errors.New("StorageInternalError")}
+	recommender := NewRecommender(mock.compute, 0, logrus.New())
+
+	_, err := recommender.Get("user")
+	assert.Equal(t, mock.err, err)
+	assert.Equal(t, 0, len(recommender.cache))
+	assert.Equal(t, defaultInterval, recommender.interval)
+}
+
+func TestRecommenderRun(t *testing.T) {
+	recommender := NewRecommender(func(string) ([]models.Suggestion, error) { return nil, nil }, time.Minute, logrus.New())
+	done := make(chan struct{})
+	go func() {
+		recommender.Run()
+		close(done)
+	}()
+
+	recommender.Stop()
+	recommender.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Recommender was not stopped")
+	}
+}
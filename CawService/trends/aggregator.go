@@ -0,0 +1,207 @@
+package trends
+
+import (
+	"Caw/UserService/models"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+const (
+	bucketSize = time.Minute
+	// baselineFactor is length of baseline period preceding window, counted in windows
+	baselineFactor = 6
+	eventsBuffer   = 1024
+	maxTrends      = 100
+)
+
+var (
+	ErrUnknownWindow = errors.New("Unknown trend window")
+)
+
+type event struct {
+	hashtags []string
+	at       time.Time
+}
+
+// Aggregator counts hashtag usage in per-minute buckets and periodically ranks
+// hashtags in every configured window by velocity relative to the baseline,
+// which is the average usage in the periods directly preceding the window.
+type Aggregator struct {
+	windows   []time.Duration
+	events    chan event
+	stop      chan struct{}
+	stopOnce  sync.Once
+	buckets   map[int64]map[string]int
+	mu        sync.RWMutex
+	snapshots map[time.Duration]models.Trends
+	now       func() time.Time
+	logger    *logrus.Logger
+}
+
+// NewAggregator creates Aggregator ranking hashtags in provided windows
+func NewAggregator(windows []time.Duration, logger *logrus.Logger) *Aggregator {
+	return &Aggregator{
+		windows:   windows,
+		events:    make(chan event, eventsBuffer),
+		stop:      make(chan struct{}),
+		buckets:   map[int64]map[string]int{},
+		snapshots: map[time.Duration]models.Trends{},
+		now:       time.Now,
+		logger:    logger,
+	}
+}
+
+// Windows returns configured windows
+func (a *Aggregator) Windows() []time.Duration {
+	return a.windows
+}
+
+// Observe records hashtags used at provided time. It never blocks,
+// events are dropped when aggregator cannot keep up.
+func (a *Aggregator) Observe(hashtags []string, at time.Time) {
+	if len(hashtags) == 0 {
+		return
+	}
+
+	select {
+	case a.events <- event{hashtags: hashtags, at: at}:
+	default:
+		a.logger.Warnf("Trends aggregator is overloaded, dropping hashtags %v", hashtags)
+	}
+}
+
+// Run consumes observed hashtags and recomputes trends every bucket until Stop is called
+func (a *Aggregator) Run() {
+	ticker := time.NewTicker(bucketSize)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-a.events:
+			a.add(e)
+		case <-ticker.C:
+			a.recompute()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Stop stops Run loop
+func (a *Aggregator) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+}
+
+// Top returns at most limit trends computed for window
+func (a *Aggregator) Top(window time.Duration, limit int) (models.Trends, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	snapshot, ok := a.snapshots[window]
+	if !ok {
+		for _, w := range a.windows {
+			if w == window {
+				return models.Trends{Window: window.String(), Trends: []models.Trend{}}, nil
+			}
+		}
+		return models.Trends{}, ErrUnknownWindow
+	}
+
+	if limit >= 0 && limit < len(snapshot.Trends) {
+		snapshot.Trends = snapshot.Trends[:limit]
+	}
+	return snapshot, nil
+}
+
+func bucketOf(t time.Time) int64 {
+	return t.UnixNano() / int64(bucketSize)
+}
+
+func (a *Aggregator) add(e event) {
+	bucket := bucketOf(e.at)
+	counts, ok := a.buckets[bucket]
+	if !ok {
+		counts = map[string]int{}
+		a.buckets[bucket] = counts
+	}
+	for _, hashtag := range e.hashtags {
+		counts[hashtag]++
+	}
+}
+
+// recompute ranks hashtags for all windows and drops buckets older than longest baseline
+func (a *Aggregator) recompute() {
+	now := a.now()
+	current := bucketOf(now)
+	snapshots := map[time.Duration]models.Trends{}
+	retention := int64(0)
+
+	for _, window := range a.windows {
+		windowBuckets := int64(window / bucketSize)
+		if windowBuckets < 1 {
+			windowBuckets = 1
+		}
+		if r := windowBuckets * (baselineFactor + 1); r > retention {
+			retention = r
+		}
+
+		counts := a.sum(current-windowBuckets, current)
+		baselines := a.sum(current-windowBuckets*(baselineFactor+1), current-windowBuckets)
+
+		trends := []models.Trend{}
+		for hashtag, count := range counts {
+			baseline := float64(baselines[hashtag]) / baselineFactor
+			trends = append(trends, models.Trend{
+				Hashtag:  hashtag,
+				Count:    count,
+				Baseline: baseline,
+				Velocity: float64(count) / (baseline + 1),
+			})
+		}
+
+		sort.Slice(trends, func(i, j int) bool {
+			if trends[i].Velocity != trends[j].Velocity {
+				return trends[i].Velocity > trends[j].Velocity
+			}
+			if trends[i].Count != trends[j].Count {
+				return trends[i].Count > trends[j].Count
+			}
+			return trends[i].Hashtag < trends[j].Hashtag
+		})
+		if len(trends) > maxTrends {
+			trends = trends[:maxTrends]
+		}
+
+		snapshots[window] = models.Trends{Window: window.String(), ComputedAt: now, Trends: trends}
+	}
+
+	for bucket := range a.buckets {
+		if bucket <= current-retention {
+			delete(a.buckets, bucket)
+		}
+	}
+
+	a.mu.Lock()
+	a.snapshots = snapshots
+	a.mu.Unlock()
+}
+
+// sum returns hashtag counts from buckets in range (from, to]
+func (a *Aggregator) sum(from, to int64) map[string]int {
+	result := map[string]int{}
+	for bucket, counts := range a.buckets {
+		if bucket <= from || bucket > to {
+			continue
+		}
+		for hashtag, count := range counts {
+			result[hashtag] += count
+		}
+	}
+	return result
+}
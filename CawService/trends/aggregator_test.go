@@ -0,0 +1,85 @@
+package trends
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatorRanksByVelocity(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 30, 0, time.UTC)
+	aggregator := NewAggregator([]time.Duration{time.Hour}, logrus.New())
+	aggregator.now = func() time.Time { return now }
+
+	// "go" is popular all the time, "caw" bursts in last hour
+	for i := 0; i < 6; i++ {
+		aggregator.add(event{hashtags: []string{"go"}, at: now.Add(-time.Duration(i+1) * time.Hour)})
+		aggregator.add(event{hashtags: []string{"go"}, at: now.Add(-time.Duration(i+1) * time.Hour)})
+	}
+	aggregator.add(event{hashtags: []string{"go", "caw"}, at: now.Add(-10 * time.Minute)})
+	aggregator.add(event{hashtags: []string{"go", "caw"}, at: now.Add(-5 * time.Minute)})
+	aggregator.add(event{hashtags: []string{"go"}, at: now})
+	aggregator.recompute()
+
+	result, err := aggregator.Top(time.Hour, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "1h0m0s", result.Window)
+	assert.Equal(t, 2, len(result.Trends))
+	assert.Equal(t, "caw", result.Trends[0].Hashtag)
+	assert.Equal(t, 2, result.Trends[0].Count)
+	assert.Equal(t, "go", result.Trends[1].Hashtag)
+	assert.Equal(t, 3, result.Trends[1].Count)
+	assert.Equal(t, 2.0, result.Trends[1].Baseline)
+
+	result, err = aggregator.Top(time.Hour, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Trends))
+}
+
+func TestAggregatorDropsExpiredBuckets(t *testing.T) {
+	now := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	aggregator := NewAggregator([]time.Duration{time.Hour}, logrus.New())
+	aggregator.now = func() time.Time { return now }
+
+	aggregator.add(event{hashtags: []string{"old"}, at: now.Add(-8 * time.Hour)})
+	aggregator.add(event{hashtags: []string{"new"}, at: now})
+	aggregator.recompute()
+
+	assert.Equal(t, 1, len(aggregator.buckets))
+	result, err := aggregator.Top(time.Hour, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(result.Trends))
+	assert.Equal(t, "new", result.Trends[0].Hashtag)
+}
+
+func TestAggregatorUnknownWindow(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Hour}, logrus.New())
+
+	_, err := aggregator.Top(24*time.Hour, 10)
+	assert.Equal(t, ErrUnknownWindow, err)
+
+	result, err := aggregator.Top(time.Hour, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.Trends))
+}
+
+func TestAggregatorRun(t *testing.T) {
+	aggregator := NewAggregator([]time.Duration{time.Hour}, logrus.New())
+	done := make(chan struct{})
+	go func() {
+		aggregator.Run()
+		close(done)
+	}()
+
+	aggregator.Observe([]string{"go"}, time.Now())
+	aggregator.Stop()
+	aggregator.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run does not stop")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/fsnotify/fsnotify"
@@ -9,15 +10,76 @@ import (
 )
 
 type AppConfig struct {
-	Address               string
-	Mongo                 string
-	TokenExpiresInMinutes int
+	Address                 string
+	Store                   string
+	Mongo                   string
+	SQLiteDir               string
+	Database                string
+	Tenants                 []string
+	TenantSource            string
+	TokenExpiresInMinutes   int
+	TrendWindows            []time.Duration
+	StreamBackpressure      string
+	SocketMessagesPerSecond int
+	SuggestionsRefresh      time.Duration
+	ReconcileInterval       time.Duration
+	CawEditWindow           time.Duration
+	CawMaxEdits             int
+	UserNameMinLength       int
+	UserNameMaxLength       int
+	PasswordMinLength       int
+	CawMaxLength            int
+	MigrateOnStartup        bool
+	// Operators are IDs of users allowed to read metrics of the service
+	Operators []string
+
+	// Tenant served by App, it is not read from config file but set for every configured tenant
+	Tenant string
+}
+
+// Stores in which App data is kept
+const (
+	StoreMongo = "mongo"
+	// StoreSQLite keeps every database in a file <sqlite_dir>/<database>.db
+	StoreSQLite = "sqlite"
+)
+
+// Sources of tenant of request in multi-tenant mode
+const (
+	// TenantSourceHost takes tenant from the first label of Host header
+	TenantSourceHost = "host"
+	// TenantSourceClaim takes tenant from token audience or X-Caw-Tenant header of requests without token
+	TenantSourceClaim = "claim"
+	// TenantHeader carries tenant of requests without token when tenant source is claim
+	TenantHeader = "X-Caw-Tenant"
+)
+
+// ForTenant returns copy of config for App serving tenant
+func (appConfig AppConfig) ForTenant(tenant string) *AppConfig {
+	appConfig.Tenant = tenant
+	return &appConfig
 }
 
 func New() *AppConfig {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
+	viper.SetDefault("store", StoreMongo)
+	viper.SetDefault("sqlite_dir", "data")
+	viper.SetDefault("database", "test")
+	viper.SetDefault("tenant_source", TenantSourceHost)
+	viper.SetDefault("trend_windows", []string{"1h", "24h"})
+	viper.SetDefault("stream_backpressure", "disconnect")
+	viper.SetDefault("socket_messages_per_second", 5)
+	viper.SetDefault("suggestions_refresh", "10m")
+	viper.SetDefault("reconcile_interval", "1h")
+	viper.SetDefault("caw_edit_window", "15m")
+	viper.SetDefault("caw_max_edits", 5)
+	viper.SetDefault("user_name_min_length", 3)
+	viper.SetDefault("user_name_max_length", 30)
+	viper.SetDefault("password_min_length", 8)
+	viper.SetDefault("caw_max_length", 280)
+	viper.SetDefault("migrate_on_startup", true)
 
 	return readConfig()
 }
@@ -41,8 +103,38 @@ func readConfig() *AppConfig {
 	}
 
 	return &AppConfig{
-		Address: viper.GetString("address"),
-		Mongo:   viper.GetString("mongo"),
-		TokenExpiresInMinutes: viper.GetInt("token_expires_in_minutes"),
+		Address:                 viper.GetString("address"),
+		Store:                   viper.GetString("store"),
+		Mongo:                   viper.GetString("mongo"),
+		SQLiteDir:               viper.GetString("sqlite_dir"),
+		Database:                viper.GetString("database"),
+		Tenants:                 viper.GetStringSlice("tenants"),
+		TenantSource:            viper.GetString("tenant_source"),
+		TokenExpiresInMinutes:   viper.GetInt("token_expires_in_minutes"),
+		TrendWindows:            readDurations("trend_windows"),
+		StreamBackpressure:      viper.GetString("stream_backpressure"),
+		SocketMessagesPerSecond: viper.GetInt("socket_messages_per_second"),
+		SuggestionsRefresh:      viper.GetDuration("suggestions_refresh"),
+		ReconcileInterval:       viper.GetDuration("reconcile_interval"),
+		CawEditWindow:           viper.GetDuration("caw_edit_window"),
+		CawMaxEdits:             viper.GetInt("caw_max_edits"),
+		UserNameMinLength:       viper.GetInt("user_name_min_length"),
+		UserNameMaxLength:       viper.GetInt("user_name_max_length"),
+		PasswordMinLength:       viper.GetInt("password_min_length"),
+		CawMaxLength:            viper.GetInt("caw_max_length"),
+		MigrateOnStartup:        viper.GetBool("migrate_on_startup"),
+		Operators:               viper.GetStringSlice("operators"),
+	}
+}
+
+func readDurations(key string) []time.Duration {
+	var durations []time.Duration
+	for _, value := range viper.GetStringSlice(key) {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			panic(fmt.Errorf("Fatal error config file: %s is not a duration: %s \n", key, err))
+		}
+		durations = append(durations, duration)
 	}
+	return durations
 }
@@ -33,10 +33,21 @@ type UserClaims struct {
 	UserId string `json:"user_id"`
 }
 
+// Tenant returns tenant which issued the token, empty in single tenant mode
+func (c *UserClaims) Tenant() string {
+	return c.Audience
+}
+
 // NewUserToken creates new user token with provided data
 func NewUserToken(userId string, expiresAt int64) (string, error) {
+	return NewTenantUserToken(userId, "", expiresAt)
+}
+
+// NewTenantUserToken creates new user token valid only for tenant, tenant is stored as audience
+func NewTenantUserToken(userId string, tenant string, expiresAt int64) (string, error) {
 	claims := UserClaims{
 		jwt.StandardClaims{
+			Audience:  tenant,
 			ExpiresAt: expiresAt,
 		},
 		userId,
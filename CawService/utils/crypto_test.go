@@ -32,6 +32,23 @@ func TestUserToken(t *testing.T) {
 	}
 }
 
+func TestTenantUserToken(t *testing.T) {
+	t.Parallel()
+	tokenString, err := NewTenantUserToken("foobar", "cats", time.Now().Add(10*time.Minute).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := DecodeUserToken(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Tenant() != "cats" {
+		t.Errorf("Invalid tenant expected %v given %v", "cats", claims.Tenant())
+	}
+}
+
 func TestCheckHashPassword(t *testing.T) {
 	t.Parallel()
 	password := "password"
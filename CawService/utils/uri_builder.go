@@ -4,14 +4,33 @@ import "bytes"
 
 type UriBuilder interface {
 	User() UriBuilder
+	Caw() UriBuilder
 	Auth() UriBuilder
 	Following() UriBuilder
 	Followers() UriBuilder
 	Caws() UriBuilder
+	Mentions() UriBuilder
+	Notifications() UriBuilder
+	Read() UriBuilder
+	Hashtags() UriBuilder
+	Trends() UriBuilder
+	Stream() UriBuilder
+	Socket() UriBuilder
+	Search() UriBuilder
+	Autocomplete() UriBuilder
+	Suggestions() UriBuilder
+	Mutuals() UriBuilder
+	Relationship() UriBuilder
+	Metrics() UriBuilder
+	History() UriBuilder
+	OpenAPI() UriBuilder
 	WithUser(userID string) UriBuilder
 	WithFollowing(followingID string) UriBuilder
 	WithFollowers(followersID string) UriBuilder
 	WithCaw(cawID string) UriBuilder
+	WithHashtag(hashtag string) UriBuilder
+	WithNotification(notificationID string) UriBuilder
+	WithRelationship(otherID string) UriBuilder
 	Done() string
 }
 
@@ -24,6 +43,11 @@ func (ub uriBuilder) User() UriBuilder {
 	return ub
 }
 
+func (ub uriBuilder) Caw() UriBuilder {
+	ub.buffer.WriteString("/v1/caws")
+	return ub
+}
+
 func (ub uriBuilder) WithUser(userID string) UriBuilder {
 	ub.buffer.WriteString("/" + userID)
 	return ub
@@ -59,11 +83,101 @@ func (ub uriBuilder) Caws() UriBuilder {
 	return ub
 }
 
+func (ub uriBuilder) Mentions() UriBuilder {
+	ub.buffer.WriteString("/mentions")
+	return ub
+}
+
+func (ub uriBuilder) Notifications() UriBuilder {
+	ub.buffer.WriteString("/notifications")
+	return ub
+}
+
+func (ub uriBuilder) WithNotification(notificationID string) UriBuilder {
+	ub.buffer.WriteString("/" + notificationID)
+	return ub
+}
+
+func (ub uriBuilder) Read() UriBuilder {
+	ub.buffer.WriteString("/read")
+	return ub
+}
+
 func (ub uriBuilder) WithCaw(cawID string) UriBuilder {
 	ub.buffer.WriteString("/" + cawID)
 	return ub
 }
 
+func (ub uriBuilder) Hashtags() UriBuilder {
+	ub.buffer.WriteString("/v1/hashtags")
+	return ub
+}
+
+func (ub uriBuilder) WithHashtag(hashtag string) UriBuilder {
+	ub.buffer.WriteString("/" + hashtag)
+	return ub
+}
+
+func (ub uriBuilder) Trends() UriBuilder {
+	ub.buffer.WriteString("/v1/trends")
+	return ub
+}
+
+func (ub uriBuilder) Stream() UriBuilder {
+	ub.buffer.WriteString("/v1/stream")
+	return ub
+}
+
+func (ub uriBuilder) Socket() UriBuilder {
+	ub.buffer.WriteString("/v1/ws")
+	return ub
+}
+
+func (ub uriBuilder) Search() UriBuilder {
+	ub.buffer.WriteString("/v1/search")
+	return ub
+}
+
+func (ub uriBuilder) Autocomplete() UriBuilder {
+	ub.buffer.WriteString("/autocomplete")
+	return ub
+}
+
+func (ub uriBuilder) Suggestions() UriBuilder {
+	ub.buffer.WriteString("/suggestions")
+	return ub
+}
+
+func (ub uriBuilder) Mutuals() UriBuilder {
+	ub.buffer.WriteString("/mutuals")
+	return ub
+}
+
+func (ub uriBuilder) Relationship() UriBuilder {
+	ub.buffer.WriteString("/relationship")
+	return ub
+}
+
+func (ub uriBuilder) WithRelationship(otherID string) UriBuilder {
+	ub.buffer.WriteString("/" + otherID)
+	return ub
+}
+
+func (ub uriBuilder) Metrics() UriBuilder {
+	ub.buffer.WriteString("/debug/vars")
+	return ub
+}
+
+func (ub uriBuilder) History() UriBuilder {
+	ub.buffer.WriteString("/history")
+	return ub
+}
+
+func (ub uriBuilder) OpenAPI() UriBuilder {
+	ub.buffer.WriteString("/v1/openapi.json")
+	return ub
+}
+
 func (ub uriBuilder) Done() string {
 	result := ub.buffer.String()
 	ub.buffer.Reset()
@@ -8,6 +8,8 @@ func TestUriBuilder(t *testing.T) {
 	cawID := "456"
 	followingID := "789"
 	followersID := "987"
+	hashtag := "go"
+	notificationID := "654"
 	var testCases = []struct {
 		Name        string
 		URI         string
@@ -48,6 +50,86 @@ func TestUriBuilder(t *testing.T) {
 			URI:         uriBuilder.User().WithUser(userID).Followers().Done(),
 			ExpectedURI: "/v1/users/" + userID + "/followers",
 		},
+		{
+			Name:        "UserURIWithUserIDMentionsTest",
+			URI:         uriBuilder.User().WithUser(userID).Mentions().Done(),
+			ExpectedURI: "/v1/users/" + userID + "/mentions",
+		},
+		{
+			Name:        "UserURIWithUserIDNotificationsReadTest",
+			URI:         uriBuilder.User().WithUser(userID).Notifications().Read().Done(),
+			ExpectedURI: "/v1/users/" + userID + "/notifications/read",
+		},
+		{
+			Name:        "UserURIWithUserIDAndNotificationIDReadTest",
+			URI:         uriBuilder.User().WithUser(userID).Notifications().WithNotification(notificationID).Read().Done(),
+			ExpectedURI: "/v1/users/" + userID + "/notifications/" + notificationID + "/read",
+		},
+		{
+			Name:        "HashtagCawsURITest",
+			URI:         uriBuilder.Hashtags().WithHashtag(hashtag).Caws().Done(),
+			ExpectedURI: "/v1/hashtags/" + hashtag + "/caws",
+		},
+		{
+			Name:        "TrendsURITest",
+			URI:         uriBuilder.Trends().Done(),
+			ExpectedURI: "/v1/trends",
+		},
+		{
+			Name:        "StreamURITest",
+			URI:         uriBuilder.Stream().Done(),
+			ExpectedURI: "/v1/stream",
+		},
+		{
+			Name:        "SocketURITest",
+			URI:         uriBuilder.Socket().Done(),
+			ExpectedURI: "/v1/ws",
+		},
+		{
+			Name:        "SearchURITest",
+			URI:         uriBuilder.Search().Done(),
+			ExpectedURI: "/v1/search",
+		},
+		{
+			Name:        "UsersAutocompleteURITest",
+			URI:         uriBuilder.User().Autocomplete().Done(),
+			ExpectedURI: "/v1/users/autocomplete",
+		},
+		{
+			Name:        "UserSuggestionsURITest",
+			URI:         uriBuilder.User().WithUser("1").Suggestions().Done(),
+			ExpectedURI: "/v1/users/1/suggestions",
+		},
+		{
+			Name:        "UserMutualsURITest",
+			URI:         uriBuilder.User().WithUser("1").Mutuals().Done(),
+			ExpectedURI: "/v1/users/1/mutuals",
+		},
+		{
+			Name:        "UserRelationshipURITest",
+			URI:         uriBuilder.User().WithUser("1").Relationship().WithRelationship("2").Done(),
+			ExpectedURI: "/v1/users/1/relationship/2",
+		},
+		{
+			Name:        "CawURITest",
+			URI:         uriBuilder.Caw().WithCaw("1").Done(),
+			ExpectedURI: "/v1/caws/1",
+		},
+		{
+			Name:        "CawHistoryURITest",
+			URI:         uriBuilder.User().WithUser("1").Caws().WithCaw("2").History().Done(),
+			ExpectedURI: "/v1/users/1/caws/2/history",
+		},
+		{
+			Name:        "MetricsURITest",
+			URI:         uriBuilder.Metrics().Done(),
+			ExpectedURI: "/debug/vars",
+		},
+		{
+			Name:        "OpenAPIURITest",
+			URI:         uriBuilder.OpenAPI().Done(),
+			ExpectedURI: "/v1/openapi.json",
+		},
 		{
 			Name:        "AuthUriTest",
 			URI:         uriBuilder.Auth().Done(),